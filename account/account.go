@@ -2,34 +2,86 @@ package account
 
 import (
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
-	"github.com/marvindeckmyn/drankspelletjes-server/auth"
 	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
 	"github.com/marvindeckmyn/drankspelletjes-server/log"
 	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
 	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
 )
 
+// maxNameLength is the longest display name an account can have.
+const maxNameLength = 50
+
+type AccountBody struct {
+	Name string `json:"name"`
+}
+
+// validateAccountBody checks if the body is valid.
+func validateAccountBody(r *server.Request) (*AccountBody, error) {
+	v := validator.V{
+		"name": validator.IsString,
+	}
+
+	body := AccountBody{}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" || utf8.RuneCountInString(body.Name) > maxNameLength {
+		return nil, &validator.ErrInvalidContent{Cause: "name"}
+	}
+
+	return &body, nil
+}
+
 // Get to retrieve the account of the current user.
 func Get(rw server.ResponseWriter, r *server.Request) {
-	accID, err := auth.GetID(r)
+	account := r.Account()
+
+	rw.JSON(http.StatusOK, map[string]interface{}{
+		"id":           account.ID,
+		"name":         *account.Name,
+		"email":        account.Email,
+		"role":         account.Role,
+		"totp_enabled": account.TotpEnabled,
+		"delete_after": account.DeleteAfter,
+	})
+}
+
+// Update changes the display name of the current user.
+func Update(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	body, err := validateAccountBody(r)
 	if err != nil {
 		log.Error(err.Error())
-		rw.JSON(http.StatusUnauthorized, nil)
+		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
-	account := accountModel.Account{
-		ID: &accID,
+	update := accountModel.Account{
+		Name: &body.Name,
 	}
 
-	err = accountDao.GetAccount(&account)
+	selectors := map[string]interface{}{
+		"ID": acc.ID,
+	}
+
+	err = accountDao.UpdateAccount(nil, &update, selectors)
 	if err != nil {
-		rw.JSON(http.StatusBadRequest, nil)
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 
-	rw.JSON(http.StatusOK, map[string]interface{}{
-		"name": *account.Name,
-	})
+	acc.Name = &body.Name
+
+	rw.JSON(http.StatusOK, nil)
 }
@@ -0,0 +1,32 @@
+package account
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+)
+
+func TestValidateAccountBody(t *testing.T) {
+	cases := map[string]bool{
+		`{"name": "Joske"}`:     true,
+		`{"name": "  Joske  "}`: true,
+		`{"name": ""}`:          false,
+		`{"name": "   "}`:       false,
+		`{"name": "` + strings.Repeat("a", 51) + `"}`: false,
+	}
+
+	for body, valid := range cases {
+		r := &server.Request{R: httptest.NewRequest("PUT", "/api/account", strings.NewReader(body))}
+
+		parsed, err := validateAccountBody(r)
+		if (err == nil) != valid {
+			t.Fatalf("expected %s to be valid: %t", body, valid)
+		}
+
+		if valid && parsed.Name != "Joske" {
+			t.Fatalf("expected the name to be trimmed, got %q", parsed.Name)
+		}
+	}
+}
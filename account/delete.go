@@ -0,0 +1,151 @@
+package account
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/auth"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	"github.com/marvindeckmyn/drankspelletjes-server/mail"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// deletionGracePeriod is how long a deleted account can still be restored by logging in.
+const deletionGracePeriod = 30 * 24 * time.Hour
+
+// Delete schedules the deletion of the current user after confirming their identity. The account is
+// logged out everywhere and purged once the grace period has passed.
+func Delete(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	body := struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}{}
+
+	v := validator.V{
+		"password":      validator.IsOptString,
+		"code":          validator.IsOptString,
+		"recovery_code": validator.IsOptString,
+	}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	status := auth.ConfirmIdentity(r, acc, auth.Confirmation{
+		Password:     body.Password,
+		Code:         body.Code,
+		RecoveryCode: body.RecoveryCode,
+	})
+	if status != http.StatusOK {
+		log.Warning("%s couldn't confirm their identity to delete their account", acc.ID)
+		rw.JSON(status, nil)
+		return
+	}
+
+	now := time.Now().UTC()
+	acc.DeletedAt = &now
+	acc.DeleteAfter = types.Ptr(now.Add(deletionGracePeriod))
+
+	err = accountDao.UpdateAccountDeletion(acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	// Log out everywhere
+	err = auth.RevokeSessions(nil, acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = accountDao.DeleteApiToken(&accountModel.ApiToken{Account: acc.ID})
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = mail.Send(mail.Message{
+		To:      *acc.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Your account will be deleted on %s. Log in before then to keep it.",
+			acc.DeleteAfter.Format("2 January 2006")),
+	})
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	log.Info("%s scheduled the deletion of their account", acc.ID)
+
+	rw.JSON(http.StatusOK, map[string]interface{}{
+		"delete_after": acc.DeleteAfter,
+	})
+}
+
+// purgeAccount anonymizes the content the account authored and deletes the account, all in one
+// transaction so a failure leaves the account as it was.
+func purgeAccount(acc *accountModel.Account) error {
+	tx := cdb.NewTx()
+
+	err := gameDao.AnonymizeGames(tx, acc)
+	if err != nil {
+		return err
+	}
+
+	err = gameDao.AnonymizeReviews(tx, acc)
+	if err != nil {
+		return err
+	}
+
+	err = accountDao.DeleteAccount(tx, acc)
+	if err != nil {
+		return err
+	}
+
+	return tx.Exec()
+}
+
+// PurgeDeletedAccounts purges all the accounts whose grace period has passed.
+func PurgeDeletedAccounts() {
+	accounts, err := accountDao.GetAccountsToPurge()
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	for _, acc := range accounts {
+		err = purgeAccount(acc)
+		if err != nil {
+			log.Error(err.Error())
+			continue
+		}
+
+		log.Info("Purged account %s", acc.ID)
+	}
+}
+
+// StartPurge purges the deleted accounts in the background at the given interval.
+func StartPurge(interval time.Duration) {
+	go func() {
+		for {
+			PurgeDeletedAccounts()
+			time.Sleep(interval)
+		}
+	}()
+}
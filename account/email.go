@@ -0,0 +1,221 @@
+package account
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/auth"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	"github.com/marvindeckmyn/drankspelletjes-server/mail"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// emailChangeDuration is how long the verification link of a new email can be used.
+const emailChangeDuration = 24 * time.Hour
+
+// EmailChangeURL is the link which is mailed to the new address, the token is filled in at %s.
+var EmailChangeURL = "https://drankspelletjes.local/account/email/confirm?token=%s"
+
+// emailTaken checks whether another account already uses the email.
+func emailTaken(email string) (bool, error) {
+	acc := accountModel.Account{
+		Email: &email,
+	}
+
+	err := accountDao.GetAccount(&acc)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ChangeEmail starts an email change for the current user. The new email is only used once it is
+// verified through the link which is mailed to it.
+func ChangeEmail(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	body := struct {
+		Email        string `json:"email"`
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}{}
+
+	v := validator.V{
+		"email":         validator.IsEmail,
+		"password":      validator.IsOptString,
+		"code":          validator.IsOptString,
+		"recovery_code": validator.IsOptString,
+	}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	status := auth.ConfirmIdentity(r, acc, auth.Confirmation{
+		Password:     body.Password,
+		Code:         body.Code,
+		RecoveryCode: body.RecoveryCode,
+	})
+	if status != http.StatusOK {
+		log.Warning("%s couldn't confirm their identity to change their email", acc.ID)
+		rw.JSON(status, nil)
+		return
+	}
+
+	taken, err := emailTaken(body.Email)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if taken {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Replace pending changes
+	err = accountDao.DeleteEmailChanges(acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	now := time.Now().UTC()
+
+	change := accountModel.EmailChange{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Account:   acc.ID,
+		Email:     &body.Email,
+		TokenHash: types.Ptr(auth.HashToken(token)),
+		CreatedAt: &now,
+		ExpiresAt: types.Ptr(now.Add(emailChangeDuration)),
+	}
+
+	err = accountDao.InsertEmailChange(&change)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = mail.Send(mail.Message{
+		To:      body.Email,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Use the link below to confirm your new email. It expires in 24 hours.\r\n\r\n%s",
+			fmt.Sprintf(EmailChangeURL, token)),
+	})
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	log.Info("%s requested an email change", acc.ID)
+
+	rw.JSON(http.StatusAccepted, nil)
+}
+
+// ConfirmEmail finishes an email change with the token from the verification mail. The old address
+// is notified of the change.
+func ConfirmEmail(rw server.ResponseWriter, r *server.Request) {
+	body := struct {
+		Token string `json:"token"`
+	}{}
+
+	v := validator.V{
+		"token": validator.IsString,
+	}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Consume token
+	change, err := accountDao.ConsumeEmailChange(auth.HashToken(body.Token))
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	acc := accountModel.Account{
+		ID: change.Account,
+	}
+
+	err = accountDao.GetAccount(&acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	taken, err := emailTaken(*change.Email)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if taken {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Update email
+	update := accountModel.Account{
+		Email: change.Email,
+	}
+
+	selectors := map[string]interface{}{
+		"ID": acc.ID,
+	}
+
+	err = accountDao.UpdateAccount(nil, &update, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = mail.Send(mail.Message{
+		To:      *acc.Email,
+		Subject: "Your email was changed",
+		Body: fmt.Sprintf("The email of your account was changed to %s. Contact us if this wasn't you.",
+			*change.Email),
+	})
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	log.Info("%s confirmed their new email", acc.ID)
+
+	rw.JSON(http.StatusOK, nil)
+}
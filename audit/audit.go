@@ -0,0 +1,86 @@
+// The audit package keeps an append-only trail of administrative changes. Entries are written in
+// the same transaction as the change they record, so a change can't happen without its entry.
+package audit
+
+import (
+	"encoding/json"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	auditDao "github.com/marvindeckmyn/drankspelletjes-server/dao/audit"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// Entity types which are audited
+const (
+	EntityAccount      = "account"
+	EntityCategory     = "category"
+	EntityGame         = "game"
+	EntityNecessity    = "necessity"
+	EntityTag          = "tag"
+	EntityGameTag      = "game_tag"
+	EntityGameCategory = "game_category"
+	EntityRule         = "rule"
+	EntityVariation    = "variation"
+	EntityReview       = "review"
+)
+
+// snapshot converts an entity to the JSON object which is stored in the entry. Nil is returned for
+// a nil entity, e.g. the state before a create.
+func snapshot(entity interface{}) (*map[string]interface{}, error) {
+	if entity == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+
+	err = json.Unmarshal(encoded, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// Record adds an audit entry for a change to the transaction. The actor, IP and request ID are
+// taken from the request. Before and after are the states of the entity around the change.
+func Record(tx *cdb.Transaction, r *server.Request, action string, entityType string,
+	entityID *uuid.UUID, before interface{}, after interface{}) error {
+
+	beforeSnapshot, err := snapshot(before)
+	if err != nil {
+		return err
+	}
+
+	afterSnapshot, err := snapshot(after)
+	if err != nil {
+		return err
+	}
+
+	entry := auditModel.AuditEntry{
+		ID:         types.Ptr(uuid.UUIDv4()),
+		Action:     &action,
+		EntityType: &entityType,
+		EntityID:   entityID,
+		Before:     beforeSnapshot,
+		After:      afterSnapshot,
+		IP:         types.Ptr(r.ClientIP()),
+	}
+
+	if acc := r.Account(); acc != nil {
+		entry.Actor = acc.ID
+	}
+
+	if id := r.RequestID(); id != "" {
+		entry.RequestID = &id
+	}
+
+	return auditDao.InsertAuditEntry(tx, &entry)
+}
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+func TestCursor(t *testing.T) {
+	entry := auditModel.AuditEntry{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		CreatedAt: types.Ptr(time.Date(2024, 3, 1, 20, 15, 0, 123456789, time.UTC)),
+	}
+
+	createdAt, id, err := decodeCursor(encodeCursor(&entry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !createdAt.Equal(*entry.CreatedAt) || id != *entry.ID {
+		t.Fatalf("unexpected cursor %s %s", createdAt, id)
+	}
+
+	_, _, err = decodeCursor("not-a-cursor")
+	if err == nil {
+		t.Fatal("expected an invalid cursor to be rejected")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	before, err := snapshot(nil)
+	if err != nil || before != nil {
+		t.Fatalf("expected no snapshot for a nil entity, got %v", before)
+	}
+
+	category := gameModel.GameCategory{
+		ID:    types.Ptr(uuid.UUIDv4()),
+		Name:  &map[string]string{"nl": "Kaartspellen"},
+		Order: types.Ptr(int32(2)),
+	}
+
+	after, err := snapshot(category)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, ok := (*after)["name"].(map[string]interface{})
+	if !ok || name["nl"] != "Kaartspellen" || (*after)["order"] != float64(2) {
+		t.Fatalf("unexpected snapshot %v", *after)
+	}
+}
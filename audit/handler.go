@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	auditDao "github.com/marvindeckmyn/drankspelletjes-server/dao/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// defaultLimit is the page size when none was given.
+const defaultLimit = 50
+
+// maxLimit is the largest page size.
+const maxLimit = 200
+
+// NextCursorHeader is the header which holds the cursor of the next page.
+const NextCursorHeader = "X-Next-Cursor"
+
+type AuditQuery struct {
+	Actor      *uuid.UUID `json:"actor"`
+	Action     *string    `json:"action"`
+	EntityType *string    `json:"entity_type"`
+	EntityID   *uuid.UUID `json:"entity_id"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+	Cursor     *string    `json:"cursor"`
+	Limit      int32      `json:"limit,string"`
+}
+
+// encodeCursor creates the cursor which starts the page after the given entry.
+func encodeCursor(entry *auditModel.AuditEntry) string {
+	raw := entry.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + entry.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor from encodeCursor.
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, &validator.ErrInvalidContent{Cause: "cursor"}
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, uuid.UUID{}, &validator.ErrInvalidContent{Cause: "cursor"}
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, &validator.ErrInvalidContent{Cause: "cursor"}
+	}
+
+	id, err := uuid.FromString(parts[1])
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, &validator.ErrInvalidContent{Cause: "cursor"}
+	}
+
+	return createdAt, id, nil
+}
+
+// validateAuditQuery checks if the query is valid and converts it to a filter.
+func validateAuditQuery(r *server.Request) (*auditDao.Filter, error) {
+	v := validator.V{
+		"actor":       validator.IsOptUUIDV4,
+		"action":      validator.IsOptString,
+		"entity_type": validator.IsOptString,
+		"entity_id":   validator.IsOptUUIDV4,
+		"from":        validator.IsOptTimestamp,
+		"to":          validator.IsOptTimestamp,
+		"cursor":      validator.IsOptString,
+		"limit":       validator.IsOptInt,
+	}
+
+	query := AuditQuery{}
+
+	err := v.ValidateAndMarshalQuery(r, &query)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultLimit
+	}
+
+	if query.Limit < 0 || query.Limit > maxLimit {
+		return nil, &validator.ErrInvalidContent{Cause: "limit"}
+	}
+
+	filter := auditDao.Filter{
+		Actor:      query.Actor,
+		Action:     query.Action,
+		EntityType: query.EntityType,
+		EntityID:   query.EntityID,
+		From:       query.From,
+		To:         query.To,
+		Limit:      query.Limit,
+	}
+
+	if query.Cursor != nil {
+		createdAt, id, err := decodeCursor(*query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		filter.AfterTime = &createdAt
+		filter.AfterID = &id
+	}
+
+	return &filter, nil
+}
+
+// GetEntries lists the audit entries which match the query, newest first. When there may be more
+// entries, the cursor of the next page is returned in the X-Next-Cursor header.
+func GetEntries(rw server.ResponseWriter, r *server.Request) {
+	filter, err := validateAuditQuery(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	entries, err := auditDao.GetAuditEntries(*filter)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if int32(len(entries)) == filter.Limit {
+		rw.W.Header().Set(NextCursorHeader, encodeCursor(entries[len(entries)-1]))
+	}
+
+	rw.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// Scopes which can be granted to an API token. Requests authenticated with the cookie are allowed
+// everything. The admin scope can only be granted to tokens of admins.
+const (
+	ScopeCatalogWrite = "catalog:write"
+	ScopeAccountRead  = "account:read"
+	ScopeAdmin        = "admin"
+)
+
+// scopes contains all the scopes which can be granted.
+var scopes = map[string]bool{
+	ScopeCatalogWrite: true,
+	ScopeAccountRead:  true,
+	ScopeAdmin:        true,
+}
+
+// apiTokenPrefix makes API tokens recognizable, e.g. for secret scanners.
+const apiTokenPrefix = "drnk_"
+
+// defaultApiTokenDays is the lifetime of an API token when none was given.
+const defaultApiTokenDays = 90
+
+// maxApiTokenDays is the longest lifetime of an API token.
+const maxApiTokenDays = 365
+
+// apiTokenParam is the middleware parameter under which the API token of the request is cached.
+const apiTokenParam = "api_token"
+
+type ApiTokenBody struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int32    `json:"expires_in_days"`
+}
+
+type ApiTokenURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// loadApiToken fetches the API token with the given hash and marks it as used.
+func loadApiToken(tokenHash string) (*accountModel.ApiToken, error) {
+	token := accountModel.ApiToken{
+		TokenHash: &tokenHash,
+	}
+
+	err := accountDao.GetApiToken(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = accountDao.TouchApiToken(&token)
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	return &token, nil
+}
+
+// bearerToken returns the token from the Authorization header, or an empty string when the request
+// has no bearer token.
+func bearerToken(r *server.Request) string {
+	header := r.R.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+
+	return strings.TrimSpace(header[7:])
+}
+
+// authenticateApiToken checks the bearer token of the request and returns the ID of its account.
+func authenticateApiToken(r *server.Request, bearer string,
+	load func(tokenHash string) (*accountModel.ApiToken, error)) (uuid.UUID, error) {
+
+	token, err := load(HashToken(bearer))
+	if err != nil {
+		return uuid.UUID{}, &ErrInvalidToken{}
+	}
+
+	if time.Now().After(*token.ExpiresAt) {
+		return uuid.UUID{}, &ErrInvalidToken{}
+	}
+
+	r.MiddlewareParams[apiTokenParam] = token
+	return *token.Account, nil
+}
+
+// requestApiToken returns the API token the request was authenticated with. Nil is returned when
+// the request was authenticated with the cookie.
+func requestApiToken(r *server.Request) *accountModel.ApiToken {
+	token, ok := r.MiddlewareParams[apiTokenParam].(*accountModel.ApiToken)
+	if !ok {
+		return nil
+	}
+
+	return token
+}
+
+// hasScope checks whether the request is allowed to use the given scope.
+func hasScope(r *server.Request, scope string) bool {
+	token := requestApiToken(r)
+	if token == nil {
+		return true
+	}
+
+	for _, granted := range *token.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequireScope returns middleware which only lets requests through from a logged-in account whose
+// API token was granted the given scope.
+func RequireScope(scope string) server.Middleware {
+	return requireScope(scope, dbLoaders)
+}
+
+// requireScope is RequireScope with the given loaders.
+func requireScope(scope string, l loaders) server.Middleware {
+	return func(rw server.ResponseWriter, r *server.Request) bool {
+		_, ok := login(rw, r, l)
+		if !ok {
+			return false
+		}
+
+		if !hasScope(r, scope) {
+			rw.JSON(http.StatusForbidden, nil)
+			return false
+		}
+
+		return true
+	}
+}
+
+// validateApiTokenBody checks if the body is valid.
+func validateApiTokenBody(requestBody io.Reader) (*ApiTokenBody, error) {
+	v := validator.V{
+		"name":   validator.IsString,
+		"scopes": validator.IsStringSlice,
+	}
+
+	body := ApiTokenBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	for _, scope := range body.Scopes {
+		if !scopes[scope] {
+			return nil, &validator.ErrInvalidContent{Cause: "scopes"}
+		}
+	}
+
+	if body.ExpiresInDays == 0 {
+		body.ExpiresInDays = defaultApiTokenDays
+	}
+
+	if body.ExpiresInDays < 0 || body.ExpiresInDays > maxApiTokenDays {
+		return nil, &validator.ErrInvalidContent{Cause: "expires_in_days"}
+	}
+
+	return &body, nil
+}
+
+// validateApiTokenURL checks if the API token URL is valid.
+func validateApiTokenURL(r *server.Request) (*ApiTokenURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := ApiTokenURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// GetApiTokens lists the API tokens of the current user.
+func GetApiTokens(rw server.ResponseWriter, r *server.Request) {
+	tokens, err := accountDao.GetApiTokensByAccount(r.Account())
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, tokens)
+}
+
+// PostApiToken creates an API token for the current user. The token itself is only returned once.
+func PostApiToken(rw server.ResponseWriter, r *server.Request) {
+	body, err := validateApiTokenBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	acc := r.Account()
+
+	for _, scope := range body.Scopes {
+		if scope == ScopeAdmin && (acc.Role == nil || *acc.Role != accountModel.RoleAdmin) {
+			rw.JSON(http.StatusForbidden, nil)
+			return
+		}
+	}
+
+	secret, err := GenerateToken()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	secret = apiTokenPrefix + secret
+	now := time.Now().UTC()
+
+	token := accountModel.ApiToken{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Account:   r.Account().ID,
+		Name:      &body.Name,
+		TokenHash: types.Ptr(HashToken(secret)),
+		Scopes:    &body.Scopes,
+		CreatedAt: &now,
+		ExpiresAt: types.Ptr(now.AddDate(0, 0, int(body.ExpiresInDays))),
+	}
+
+	err = accountDao.InsertApiToken(&token)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	log.Info("%s created API token %s", r.Account().ID, token.ID)
+
+	rw.JSON(http.StatusCreated, map[string]interface{}{
+		"token":     secret,
+		"api_token": token,
+	})
+}
+
+// DeleteApiToken revokes an API token of the current user.
+func DeleteApiToken(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateApiTokenURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	token := accountModel.ApiToken{
+		ID:      &url.ID,
+		Account: r.Account().ID,
+	}
+
+	err = accountDao.GetApiToken(&token)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	err = accountDao.DeleteApiToken(&accountModel.ApiToken{ID: token.ID})
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	log.Info("%s revoked API token %s", r.Account().ID, token.ID)
+
+	rw.JSON(http.StatusOK, nil)
+}
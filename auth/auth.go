@@ -4,7 +4,7 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
 	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
 	"github.com/marvindeckmyn/drankspelletjes-server/log"
 	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
@@ -12,17 +12,8 @@ import (
 	"github.com/marvindeckmyn/drankspelletjes-server/types"
 	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
 	"github.com/marvindeckmyn/drankspelletjes-server/validator"
-	"golang.org/x/crypto/bcrypt"
 )
 
-const SALT_ROUNDS = 14
-
-// hashPassword hashes the given password with bcrypt.
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), SALT_ROUNDS)
-	return string(bytes), err
-}
-
 // Register to create an account.
 func Register(rw server.ResponseWriter, r *server.Request) {
 	// Check body
@@ -62,6 +53,14 @@ func Register(rw server.ResponseWriter, r *server.Request) {
 
 	log.Info("%s is registering", body.Email)
 
+	err = checkPasswordPolicy(body.Password)
+	if err != nil {
+		rw.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	// Insert account
 	hash, err := hashPassword(body.Password)
 	if err != nil {
@@ -75,6 +74,7 @@ func Register(rw server.ResponseWriter, r *server.Request) {
 		Name:     &body.Name,
 		Email:    &body.Email,
 		Password: &hash,
+		Role:     types.Ptr(accountModel.RoleUser),
 	}
 
 	err = accountDao.InsertAccount(&acc)
@@ -87,12 +87,6 @@ func Register(rw server.ResponseWriter, r *server.Request) {
 	rw.JSON(http.StatusCreated, nil)
 }
 
-// checkPasswordHash will check if the password is equal to the hash if hashed.
-func checkPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
-}
-
 // Login to log in an account.
 func Login(rw server.ResponseWriter, r *server.Request) {
 	// Check body
@@ -113,7 +107,12 @@ func Login(rw server.ResponseWriter, r *server.Request) {
 		return
 	}
 
-	log.Info("%s is logging in", body.Email)
+	// Check IP lockout
+	ipKey := ipThrottleKey(r.ClientIP())
+	if until := lockedUntil(ipKey); until != nil {
+		tooManyAttempts(rw, *until)
+		return
+	}
 
 	// Get account
 	acc := accountModel.Account{
@@ -121,82 +120,102 @@ func Login(rw server.ResponseWriter, r *server.Request) {
 	}
 
 	err = accountDao.GetAccount(&acc)
-	if err != nil {
-		log.Error("Account not found with email %s", body.Email)
-		if _, ok := err.(*cdb.ErrMissingResult); !ok {
-			rw.JSON(http.StatusUnauthorized, nil)
-			return
-		}
-
+	if err != nil && !dao.IsMissingResult(err) {
+		log.Error(err.Error())
 		rw.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 
-	match := checkPasswordHash(body.Password, *acc.Password)
+	// Unknown emails are throttled like accounts, so a lockout doesn't tell which emails exist
+	found := err == nil
+
+	key := emailThrottleKey(body.Email)
+	if found {
+		key = accountThrottleKey(*acc.ID)
+	}
+
+	// The password is always hashed before answering, so the response time is the same as well
+	match, rehash := false, false
+	if found && acc.Password != nil {
+		match, rehash = verifyPassword(body.Password, *acc.Password)
+	} else {
+		compareDummyHash(body.Password)
+	}
+
+	// Check lockout
+	if until := lockedUntil(key); until != nil {
+		log.Warning("%s tried to log in while locked", key)
+		tooManyAttempts(rw, *until)
+		return
+	}
+
 	if !match {
+		recordLoginFailure(ipKey)
+		recordLoginFailure(key)
 		rw.JSON(http.StatusUnauthorized, nil)
 		return
 	}
 
-	// Add JWT
-	jwt, err := createToken(&acc)
-	if err != nil {
-		log.Error(err.Error())
-		rw.JSON(http.StatusInternalServerError, nil)
+	log.Info("%s is logging in", acc.ID)
+
+	if rehash {
+		rehashPassword(&acc, body.Password)
+	}
+
+	// Accounts with two-factor authentication get a challenge instead of a session
+	if twoFactorEnabled(&acc) {
+		challenge, err := createChallengeToken(&acc)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+
+		rw.JSON(http.StatusOK, map[string]interface{}{
+			"two_factor_required": true,
+			"challenge":           challenge,
+		})
 		return
 	}
 
-	cookie := &http.Cookie{
-		Name:   "drnkngg-token",
-		Value:  jwt,
-		Path:   "/",
-		Domain: ".drankspelletjes.local",
-		MaxAge: 3600 * 24 * 7,
+	err = accountDao.DeleteLoginThrottle(nil, key)
+	if err != nil {
+		log.Error(err.Error())
 	}
 
-	http.SetCookie(rw.W, cookie)
+	// Start session
+	err = startSession(rw, r, &acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
 
 	rw.JSON(http.StatusOK, nil)
 }
 
 // Logout to log out of an account.
 func Logout(rw server.ResponseWriter, r *server.Request) {
-	accID, err := GetID(r)
+	acc := r.Account()
+	log.Info("%s is logging out", acc.ID)
+
+	// End session
+	_, sessionID, err := getClaims(r)
 	if err != nil {
 		log.Error(err.Error())
 		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
-	log.Info("%s is logging out", accID)
-
-	// Get account
-	acc := accountModel.Account{
-		ID: &accID,
-	}
-
-	err = accountDao.GetAccount(&acc)
+	err = accountDao.DeleteSession(nil, &accountModel.Session{ID: &sessionID})
 	if err != nil {
 		log.Error(err.Error())
-		if _, ok := err.(*cdb.ErrMissingResult); !ok {
-			rw.JSON(http.StatusNotFound, nil)
-			return
-		}
-
 		rw.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 
 	// Remove cookie
-	cookie := &http.Cookie{
-		Name:   "drnkngg-token",
-		Value:  "",
-		Path:   "/",
-		Domain: ".drankspelletjes.local",
-		MaxAge: -1,
-	}
-
-	http.SetCookie(rw.W, cookie)
+	setTokenCookie(rw, "", -1)
 
 	rw.JSON(http.StatusOK, nil)
 }
@@ -0,0 +1,47 @@
+package auth
+
+// ErrInvalidToken is thrown when a token is malformed, expired or already used.
+type ErrInvalidToken struct{}
+
+func (e *ErrInvalidToken) Error() string {
+	return "invalid token"
+}
+
+// ErrSessionExpired is thrown when the session of a token no longer exists or has expired.
+type ErrSessionExpired struct{}
+
+func (e *ErrSessionExpired) Error() string {
+	return "session has expired"
+}
+
+// ErrInvalidHash is thrown when a password hash is in an unknown format.
+type ErrInvalidHash struct{}
+
+func (e *ErrInvalidHash) Error() string {
+	return "invalid password hash"
+}
+
+// ErrWeakPassword is thrown when a new password doesn't meet the password policy.
+type ErrWeakPassword struct {
+	Reason string
+}
+
+func (e *ErrWeakPassword) Error() string {
+	return "weak password: " + e.Reason
+}
+
+// ErrOIDCNotConfigured is thrown when logging in through OpenID Connect while no provider is set.
+type ErrOIDCNotConfigured struct{}
+
+func (e *ErrOIDCNotConfigured) Error() string {
+	return "OpenID Connect is not configured"
+}
+
+// ErrOIDC is thrown when the OpenID Connect provider or one of its tokens can't be trusted.
+type ErrOIDC struct {
+	Reason string
+}
+
+func (e *ErrOIDC) Error() string {
+	return "OpenID Connect: " + e.Reason
+}
@@ -13,14 +13,15 @@ import (
 
 var secretKey = []byte("drankspelletjes_key")
 
-// createToken creates a JWT token for the given account.
-func createToken(acc *accountModel.Account) (string, error) {
-	if acc == nil || acc.ID == nil {
+// createToken creates a JWT token for the given account and session.
+func createToken(acc *accountModel.Account, session *accountModel.Session) (string, error) {
+	if acc == nil || acc.ID == nil || session == nil || session.ID == nil {
 		return "", nil
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"ID": fmt.Sprint(acc.ID),
+		"ID":  fmt.Sprint(acc.ID),
+		"SID": fmt.Sprint(session.ID),
 	})
 
 	jwt, err := token.SignedString(secretKey)
@@ -32,6 +33,12 @@ func createToken(acc *accountModel.Account) (string, error) {
 	return jwt, err
 }
 
+// SignToken signs the given claims with the secret key, so they can be checked with ParseToken.
+func SignToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secretKey)
+}
+
 // ParseToken to parse a JWT token and make it readable
 func ParseToken(tokenString string) (jwt.MapClaims, error) {
 	token, _ := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -49,30 +56,53 @@ func ParseToken(tokenString string) (jwt.MapClaims, error) {
 	return nil, nil
 }
 
-// GetID to get the ID of a JWT
-func GetID(r *server.Request) (uuid.UUID, error) {
-	jwt, err := r.Cookie("drnkngg-token")
+// claimUUID parses the claim with the given name as a UUID.
+func claimUUID(claims jwt.MapClaims, name string) (uuid.UUID, error) {
+	marshalClaim, err := json.Marshal(claims[name])
 	if err != nil {
 		return uuid.UUID{}, err
 	}
 
-	parsedToken, err := ParseToken(*jwt)
+	id := uuid.UUID{}
+	err = json.Unmarshal(marshalClaim, &id)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
 
-	id := parsedToken["ID"]
+	return id, nil
+}
 
-	marshalToken, err := json.Marshal(id)
+// getClaims returns the account ID and the session ID from the JWT of the request.
+func getClaims(r *server.Request) (uuid.UUID, uuid.UUID, error) {
+	jwt, err := r.Cookie(tokenCookie)
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, uuid.UUID{}, err
 	}
 
-	token := uuid.UUID{}
-	err = json.Unmarshal(marshalToken, &token)
+	parsedToken, err := ParseToken(*jwt)
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, uuid.UUID{}, err
+	}
+
+	if parsedToken == nil {
+		return uuid.UUID{}, uuid.UUID{}, &ErrInvalidToken{}
 	}
 
-	return token, nil
+	accID, err := claimUUID(parsedToken, "ID")
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, err
+	}
+
+	sessionID, err := claimUUID(parsedToken, "SID")
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, err
+	}
+
+	return accID, sessionID, nil
+}
+
+// GetID to get the ID of a JWT
+func GetID(r *server.Request) (uuid.UUID, error) {
+	accID, _, err := getClaims(r)
+	return accID, err
 }
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"net/http"
+
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// loadAccount fetches the account with the given ID.
+func loadAccount(id uuid.UUID) (*accountModel.Account, error) {
+	acc := accountModel.Account{
+		ID: &id,
+	}
+
+	err := accountDao.GetAccount(&acc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &acc, nil
+}
+
+// loaders fetch what the middleware needs to authenticate a request, so it can be tested without a
+// database.
+type loaders struct {
+	account  func(id uuid.UUID) (*accountModel.Account, error)
+	session  func(id uuid.UUID) (*accountModel.Session, error)
+	apiToken func(tokenHash string) (*accountModel.ApiToken, error)
+}
+
+// dbLoaders fetch from the database.
+var dbLoaders = loaders{
+	account:  loadAccount,
+	session:  loadSession,
+	apiToken: loadApiToken,
+}
+
+// authenticate loads the account of the caller and caches it on the request. When the account was
+// already loaded earlier in the chain the cached account is returned.
+func authenticate(r *server.Request, l loaders) (*accountModel.Account, error) {
+	if acc := r.Account(); acc != nil {
+		return acc, nil
+	}
+
+	accID, err := authenticateRequest(r, l)
+	if err != nil {
+		return nil, err
+	}
+
+	acc, err := l.account(accID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SetAccount(acc)
+	return acc, nil
+}
+
+// authenticateRequest checks the credentials of the request and returns the ID of the account they
+// belong to. A bearer token in the Authorization header takes precedence over the cookie.
+func authenticateRequest(r *server.Request, l loaders) (uuid.UUID, error) {
+	if bearer := bearerToken(r); bearer != "" {
+		return authenticateApiToken(r, bearer, l.apiToken)
+	}
+
+	accID, sessionID, err := getClaims(r)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	err = checkSession(accID, sessionID, l.session)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return accID, nil
+}
+
+// login authenticates the caller and answers with a 401 when that fails.
+func login(rw server.ResponseWriter, r *server.Request, l loaders) (*accountModel.Account, bool) {
+	acc, err := authenticate(r, l)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusUnauthorized, nil)
+		return nil, false
+	}
+
+	return acc, true
+}
+
+// RequireAccount is middleware which only lets requests through that were made by a logged-in
+// account with the cookie. The account is available to the handler through Request.Account. API
+// tokens are refused, they are only let through by routes which declare a scope with RequireScope.
+func RequireAccount(rw server.ResponseWriter, r *server.Request) bool {
+	return requireAccount(rw, r, dbLoaders)
+}
+
+// requireAccount is RequireAccount with the given loaders.
+func requireAccount(rw server.ResponseWriter, r *server.Request, l loaders) bool {
+	_, ok := login(rw, r, l)
+	if !ok {
+		return false
+	}
+
+	if requestApiToken(r) != nil {
+		rw.JSON(http.StatusForbidden, nil)
+		return false
+	}
+
+	return true
+}
+
+// OptionalAccount is middleware which loads the account of the caller when logged in with the
+// cookie. Anonymous requests and requests with an API token are let through as anonymous, in which
+// case Request.Account returns nil.
+func OptionalAccount(rw server.ResponseWriter, r *server.Request) bool {
+	return optionalAccount(rw, r, dbLoaders)
+}
+
+// optionalAccount is OptionalAccount with the given loaders.
+func optionalAccount(rw server.ResponseWriter, r *server.Request, l loaders) bool {
+	if bearerToken(r) != "" {
+		return true
+	}
+
+	authenticate(r, l)
+	return true
+}
+
+// RequireAdmin is middleware which only lets requests through that were made by an admin account.
+// An API token of an admin has to be granted the admin scope.
+func RequireAdmin(rw server.ResponseWriter, r *server.Request) bool {
+	return requireAdminScope(ScopeAdmin, dbLoaders)(rw, r)
+}
+
+// RequireAdminScope returns middleware which only lets requests through that were made by an admin
+// account whose API token was granted the given scope. Accounts which are created when logging in
+// are never admins, so they can't get past it.
+func RequireAdminScope(scope string) server.Middleware {
+	return requireAdminScope(scope, dbLoaders)
+}
+
+// requireAdminScope is RequireAdminScope with the given loaders.
+func requireAdminScope(scope string, l loaders) server.Middleware {
+	return func(rw server.ResponseWriter, r *server.Request) bool {
+		acc, ok := login(rw, r, l)
+		if !ok {
+			return false
+		}
+
+		if !hasScope(r, scope) {
+			rw.JSON(http.StatusForbidden, nil)
+			return false
+		}
+
+		if acc.Role == nil || *acc.Role != accountModel.RoleAdmin {
+			rw.JSON(http.StatusForbidden, nil)
+			return false
+		}
+
+		if requireAdminTwoFactor && !twoFactorEnabled(acc) {
+			rw.JSON(http.StatusForbidden, map[string]interface{}{
+				"error": "two_factor_required",
+			})
+			return false
+		}
+
+		return true
+	}
+}
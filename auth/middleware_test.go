@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// sessions holds the sessions which are served by the stubbed session loader.
+var sessions = map[uuid.UUID]*accountModel.Session{}
+
+// newTestRequest creates a request, optionally carrying the auth cookie of the given account.
+func newTestRequest(acc *accountModel.Account) (server.ResponseWriter, *server.Request, *httptest.ResponseRecorder) {
+	httpReq := httptest.NewRequest(http.MethodGet, "/api/auth/account", nil)
+
+	if acc != nil {
+		session := &accountModel.Session{
+			ID:        types.Ptr(uuid.UUIDv4()),
+			Account:   acc.ID,
+			ExpiresAt: types.Ptr(time.Now().Add(time.Hour)),
+		}
+		sessions[*session.ID] = session
+
+		token, _ := createToken(acc, session)
+		httpReq.AddCookie(&http.Cookie{Name: tokenCookie, Value: token})
+	}
+
+	rec := httptest.NewRecorder()
+	r := &server.Request{
+		R:                httpReq,
+		QueryParams:      map[string][]string{},
+		MiddlewareParams: map[string]interface{}{},
+		UserData:         map[string]interface{}{},
+	}
+
+	return server.ResponseWriter{W: rec}, r, rec
+}
+
+// stubLoaders returns loaders which serve the given account and the test sessions, together with
+// the amount of times the account was loaded.
+func stubLoaders(acc *accountModel.Account) (*loaders, *int) {
+	calls := 0
+
+	l := &loaders{
+		account: func(id uuid.UUID) (*accountModel.Account, error) {
+			calls++
+			if acc == nil || *acc.ID != id {
+				return nil, errors.New("no results found")
+			}
+
+			return acc, nil
+		},
+		session: func(id uuid.UUID) (*accountModel.Session, error) {
+			session, ok := sessions[id]
+			if !ok {
+				return nil, errors.New("no results found")
+			}
+
+			return session, nil
+		},
+		apiToken: func(tokenHash string) (*accountModel.ApiToken, error) {
+			return nil, errors.New("no results found")
+		},
+	}
+
+	return l, &calls
+}
+
+func TestRequireAccount(t *testing.T) {
+	acc := &accountModel.Account{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: types.Ptr("joske"),
+	}
+	l, calls := stubLoaders(acc)
+
+	rw, r, _ := newTestRequest(acc)
+	if !requireAccount(rw, r, *l) {
+		t.Fatal("expected the request to be let through")
+	}
+
+	if r.Account() != acc {
+		t.Fatal("expected the account to be cached on the request")
+	}
+
+	// A second middleware in the chain shouldn't load the account again.
+	if !requireAccount(rw, r, *l) || *calls != 1 {
+		t.Fatalf("expected the account to be loaded once, got %d", *calls)
+	}
+}
+
+func TestRequireAccountAnonymous(t *testing.T) {
+	l, _ := stubLoaders(nil)
+
+	rw, r, rec := newTestRequest(nil)
+	if requireAccount(rw, r, *l) {
+		t.Fatal("expected the request to be stopped")
+	}
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestOptionalAccount(t *testing.T) {
+	l, _ := stubLoaders(nil)
+
+	rw, r, _ := newTestRequest(nil)
+	if !optionalAccount(rw, r, *l) {
+		t.Fatal("expected anonymous requests to be let through")
+	}
+
+	if r.Account() != nil {
+		t.Fatal("expected no account on an anonymous request")
+	}
+}
+
+func TestRequireAccountRevokedSession(t *testing.T) {
+	acc := &accountModel.Account{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: types.Ptr("joske"),
+	}
+	l, _ := stubLoaders(acc)
+
+	rw, r, rec := newTestRequest(acc)
+	for id, session := range sessions {
+		if *session.Account == *acc.ID {
+			delete(sessions, id)
+		}
+	}
+
+	if requireAccount(rw, r, *l) {
+		t.Fatal("expected a request with a revoked session to be stopped")
+	}
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+// newBearerRequest creates a request authenticated with an API token which has the given scopes.
+// The loaders are set up to serve the token.
+func newBearerRequest(l *loaders, acc *accountModel.Account, expiresAt time.Time, scopes ...string) (server.ResponseWriter, *server.Request, *httptest.ResponseRecorder) {
+	secret := apiTokenPrefix + "test-secret"
+	token := &accountModel.ApiToken{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Account:   acc.ID,
+		Scopes:    &scopes,
+		ExpiresAt: &expiresAt,
+	}
+
+	l.apiToken = func(tokenHash string) (*accountModel.ApiToken, error) {
+		if tokenHash != HashToken(secret) {
+			return nil, errors.New("no results found")
+		}
+
+		return token, nil
+	}
+
+	rw, r, rec := newTestRequest(nil)
+	r.R.Header.Set("Authorization", "Bearer "+secret)
+	return rw, r, rec
+}
+
+func TestRequireScope(t *testing.T) {
+	acc := &accountModel.Account{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: types.Ptr("joske"),
+	}
+	l, _ := stubLoaders(acc)
+
+	rw, r, _ := newBearerRequest(l, acc, time.Now().Add(time.Hour), ScopeCatalogWrite)
+	if !requireScope(ScopeCatalogWrite, *l)(rw, r) {
+		t.Fatal("expected a token with the scope to be let through")
+	}
+
+	if r.Account() != acc {
+		t.Fatal("expected the account of the token to be cached on the request")
+	}
+
+	rw, r, rec := newBearerRequest(l, acc, time.Now().Add(time.Hour), ScopeAccountRead)
+	if requireScope(ScopeCatalogWrite, *l)(rw, r) || rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a token without the scope to be forbidden, got %d", rec.Code)
+	}
+
+	rw, r, rec = newBearerRequest(l, acc, time.Now().Add(-time.Hour), ScopeCatalogWrite)
+	if requireScope(ScopeCatalogWrite, *l)(rw, r) || rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an expired token to be unauthorized, got %d", rec.Code)
+	}
+
+	// Cookie sessions are allowed every scope.
+	rw, r, _ = newTestRequest(acc)
+	if !requireScope(ScopeCatalogWrite, *l)(rw, r) {
+		t.Fatal("expected a cookie session to be let through")
+	}
+}
+
+func TestTokenNeedsScopedRoute(t *testing.T) {
+	acc := &accountModel.Account{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: types.Ptr("joske"),
+		Role: types.Ptr(accountModel.RoleAdmin),
+	}
+	l, _ := stubLoaders(acc)
+
+	// Routes without a scope only take the cookie
+	rw, r, rec := newBearerRequest(l, acc, time.Now().Add(time.Hour), ScopeAccountRead)
+	if requireAccount(rw, r, *l) || rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a token to be refused, got %d", rec.Code)
+	}
+
+	rw, r, rec = newBearerRequest(l, acc, time.Now().Add(time.Hour), ScopeCatalogWrite)
+	if requireAdminScope(ScopeAdmin, *l)(rw, r) || rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a token without the admin scope to be refused, got %d", rec.Code)
+	}
+
+	rw, r, _ = newBearerRequest(l, acc, time.Now().Add(time.Hour), ScopeAdmin)
+	if !requireAdminScope(ScopeAdmin, *l)(rw, r) {
+		t.Fatal("expected a token with the admin scope to be let through")
+	}
+
+	// Tokens don't identify the caller on public routes
+	rw, r, _ = newBearerRequest(l, acc, time.Now().Add(time.Hour), ScopeAccountRead)
+	if !optionalAccount(rw, r, *l) || r.Account() != nil {
+		t.Fatal("expected a token to be treated as anonymous")
+	}
+}
+
+func TestRequireAdminScope(t *testing.T) {
+	acc := &accountModel.Account{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: types.Ptr("joske"),
+		Role: types.Ptr(accountModel.RoleUser),
+	}
+	l, _ := stubLoaders(acc)
+
+	// Accounts which aren't admins can't write the catalog, not even with the cookie
+	rw, r, rec := newTestRequest(acc)
+	if requireAdminScope(ScopeCatalogWrite, *l)(rw, r) || rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a user to be forbidden, got %d", rec.Code)
+	}
+
+	acc.Role = types.Ptr(accountModel.RoleAdmin)
+
+	rw, r, _ = newBearerRequest(l, acc, time.Now().Add(time.Hour), ScopeCatalogWrite)
+	if !requireAdminScope(ScopeCatalogWrite, *l)(rw, r) {
+		t.Fatal("expected an admin token with the scope to be let through")
+	}
+}
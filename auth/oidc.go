@@ -0,0 +1,707 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// oidcCookie is the name of the cookie which holds the state of an ongoing OpenID Connect login.
+const oidcCookie = "drnkngg-oidc"
+
+// oidcFlowDuration is how long a user has to complete the login at the provider.
+const oidcFlowDuration = 10 * time.Minute
+
+// oidcKeyRefresh is the shortest time between two fetches of the key set. The key ID comes from a
+// token which isn't verified yet, so unknown keys must not make every request hit the provider.
+const oidcKeyRefresh = time.Minute
+
+// OIDCConfig configures the external OpenID Connect provider.
+type OIDCConfig struct {
+	// Issuer is the URL of the provider, the discovery document is fetched from it.
+	Issuer string
+
+	// ClientID and ClientSecret are the credentials of this server at the provider.
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is the URL of the callback endpoint which is registered at the provider.
+	RedirectURL string
+
+	// PostLoginURL is where the browser is sent after a successful login.
+	PostLoginURL string
+}
+
+// oidcProvider contains the fields of the discovery document which are used.
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcClaims contains the verified claims of an ID token.
+type oidcClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	AuthTime      time.Time
+}
+
+// oidcFlow is the state of an ongoing login which is kept in a cookie.
+type oidcFlow struct {
+	Nonce    string
+	Verifier string
+	Reauth   bool
+}
+
+var (
+	oidcMu            sync.Mutex
+	oidcConfig        *OIDCConfig
+	oidcMetadata      *oidcProvider
+	oidcKeys          map[string]*rsa.PublicKey
+	oidcKeysFetchedAt time.Time
+)
+
+// oidcClient is the HTTP client which is used to talk to the provider.
+var oidcClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// SetOIDCConfig enables logging in through the given OpenID Connect provider.
+func SetOIDCConfig(cfg OIDCConfig) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	oidcConfig = &cfg
+	oidcMetadata = nil
+	oidcKeys = nil
+	oidcKeysFetchedAt = time.Time{}
+}
+
+// getOIDCConfig returns the provider configuration, or an error when OpenID Connect isn't enabled.
+func getOIDCConfig() (*OIDCConfig, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	if oidcConfig == nil {
+		return nil, &ErrOIDCNotConfigured{}
+	}
+
+	return oidcConfig, nil
+}
+
+// fetchJSON fetches the JSON document at the given URL.
+func fetchJSON(url string, dest interface{}) error {
+	resp, err := oidcClient.Get(url)
+	if err != nil {
+		return &ErrOIDC{Reason: err.Error()}
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &ErrOIDC{Reason: fmt.Sprintf("%s answered with status %d", url, resp.StatusCode)}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
+
+// discoverOIDC returns the discovery document of the provider. The document is cached after the
+// first fetch.
+func discoverOIDC() (*oidcProvider, error) {
+	cfg, err := getOIDCConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	oidcMu.Lock()
+	metadata := oidcMetadata
+	oidcMu.Unlock()
+
+	if metadata != nil {
+		return metadata, nil
+	}
+
+	metadata = &oidcProvider{}
+	err = fetchJSON(cfg.Issuer+"/.well-known/openid-configuration", metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(metadata.Issuer, "/") != cfg.Issuer {
+		return nil, &ErrOIDC{Reason: "issuer of the discovery document doesn't match"}
+	}
+
+	oidcMu.Lock()
+	oidcMetadata = metadata
+	oidcMu.Unlock()
+
+	return metadata, nil
+}
+
+// parseJWKS parses the RSA keys from a JSON web key set.
+func parseJWKS(set struct {
+	Keys []map[string]string `json:"keys"`
+}) map[string]*rsa.PublicKey {
+	keys := map[string]*rsa.PublicKey{}
+
+	for _, key := range set.Keys {
+		if key["kty"] != "RSA" || (key["use"] != "" && key["use"] != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key["n"])
+		if err != nil {
+			continue
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(key["e"])
+		if err != nil {
+			continue
+		}
+
+		keys[key["kid"]] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys
+}
+
+// oidcKey returns the signing key of the provider with the given key ID. The key set is fetched
+// again when the key is unknown, so key rotations at the provider are picked up. Unknown keys are
+// refused without a fetch when the key set was fetched less than oidcKeyRefresh ago.
+func oidcKey(kid string) (*rsa.PublicKey, error) {
+	oidcMu.Lock()
+	key, ok := oidcKeys[kid]
+	recent := time.Since(oidcKeysFetchedAt) < oidcKeyRefresh
+
+	// Claim the fetch, so concurrent requests don't fetch the key set as well
+	if !ok && !recent {
+		oidcKeysFetchedAt = time.Now()
+	}
+	oidcMu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if recent {
+		return nil, &ErrOIDC{Reason: "unknown signing key " + kid}
+	}
+
+	metadata, err := discoverOIDC()
+	if err != nil {
+		return nil, err
+	}
+
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+
+	err = fetchJSON(metadata.JWKSURI, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := parseJWKS(set)
+
+	oidcMu.Lock()
+	oidcKeys = keys
+	oidcMu.Unlock()
+
+	key, ok = keys[kid]
+	if !ok {
+		return nil, &ErrOIDC{Reason: "unknown signing key " + kid}
+	}
+
+	return key, nil
+}
+
+// pkceChallenge derives the S256 code challenge from a PKCE code verifier.
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// oidcAuthURL builds the URL of the authorization endpoint of the provider. A re-authentication
+// asks the provider to log the user in again, even when they still have a session there.
+func oidcAuthURL(state string, nonce string, verifier string, reauth bool) (string, error) {
+	cfg, err := getOIDCConfig()
+	if err != nil {
+		return "", err
+	}
+
+	metadata, err := discoverOIDC()
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {cfg.RedirectURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	if reauth {
+		params.Set("prompt", "login")
+		params.Set("max_age", "0")
+	}
+
+	return metadata.AuthorizationEndpoint + "?" + params.Encode(), nil
+}
+
+// exchangeCode exchanges the authorization code at the token endpoint and returns the ID token.
+func exchangeCode(code string, verifier string) (string, error) {
+	cfg, err := getOIDCConfig()
+	if err != nil {
+		return "", err
+	}
+
+	metadata, err := discoverOIDC()
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := oidcClient.PostForm(metadata.TokenEndpoint, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.RedirectURL},
+		"client_id":     {cfg.ClientID},
+		"client_secret": {cfg.ClientSecret},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return "", &ErrOIDC{Reason: err.Error()}
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &ErrOIDC{Reason: fmt.Sprintf("token endpoint answered with status %d", resp.StatusCode)}
+	}
+
+	body := struct {
+		IDToken string `json:"id_token"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil || body.IDToken == "" {
+		return "", &ErrOIDC{Reason: "token response contains no id_token"}
+	}
+
+	return body.IDToken, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token and returns
+// its claims.
+func verifyIDToken(raw string, nonce string) (*oidcClaims, error) {
+	cfg, err := getOIDCConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return oidcKey(kid)
+	})
+	if err != nil || !token.Valid {
+		return nil, &ErrOIDC{Reason: "invalid ID token"}
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, &ErrOIDC{Reason: "invalid ID token claims"}
+	}
+
+	if !claims.VerifyIssuer(cfg.Issuer, true) {
+		return nil, &ErrOIDC{Reason: "ID token issuer doesn't match"}
+	}
+
+	if !claims.VerifyAudience(cfg.ClientID, true) {
+		return nil, &ErrOIDC{Reason: "ID token audience doesn't match"}
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, &ErrOIDC{Reason: "ID token has no expiry"}
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, &ErrOIDC{Reason: "ID token nonce doesn't match"}
+	}
+
+	result := oidcClaims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.EmailVerified, _ = claims["email_verified"].(bool)
+	result.Name, _ = claims["name"].(string)
+
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		result.AuthTime = time.Unix(int64(authTime), 0)
+	}
+
+	if result.Subject == "" {
+		return nil, &ErrOIDC{Reason: "ID token has no subject"}
+	}
+
+	return &result, nil
+}
+
+// createFlowToken signs the state of an ongoing login so it can be kept in a cookie.
+func createFlowToken(state string, flow oidcFlow) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose":  "oidc",
+		"state":    state,
+		"nonce":    flow.Nonce,
+		"verifier": flow.Verifier,
+		"reauth":   flow.Reauth,
+		"exp":      time.Now().Add(oidcFlowDuration).Unix(),
+	})
+
+	return token.SignedString(secretKey)
+}
+
+// parseFlowToken verifies the cookie of an ongoing login and returns its state when the state
+// matches.
+func parseFlowToken(flowToken string, state string) (*oidcFlow, error) {
+	claims, err := ParseToken(flowToken)
+	if err != nil || claims == nil || claims["purpose"] != "oidc" {
+		return nil, &ErrInvalidToken{}
+	}
+
+	expected, _ := claims["state"].(string)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(state)) != 1 {
+		return nil, &ErrOIDC{Reason: "state doesn't match"}
+	}
+
+	flow := oidcFlow{}
+	flow.Nonce, _ = claims["nonce"].(string)
+	flow.Verifier, _ = claims["verifier"].(string)
+	flow.Reauth, _ = claims["reauth"].(bool)
+
+	return &flow, nil
+}
+
+// checkReauth verifies that a re-authentication at the provider was made by the logged-in account
+// and is recent enough to confirm a sensitive change.
+func checkReauth(r *server.Request, claims *oidcClaims) (*accountModel.Account, error) {
+	cfg, err := getOIDCConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	acc, err := authenticate(r, dbLoaders)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := accountModel.Identity{
+		Issuer:  &cfg.Issuer,
+		Subject: &claims.Subject,
+	}
+
+	err = accountDao.GetIdentity(&identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if *identity.Account != *acc.ID {
+		return nil, &ErrOIDC{Reason: "provider account belongs to another account"}
+	}
+
+	if time.Since(claims.AuthTime) > reauthDuration {
+		return nil, &ErrOIDC{Reason: "login at the provider isn't recent"}
+	}
+
+	return acc, nil
+}
+
+// linkOIDCAccount returns the account which is linked to the subject of the provider. Unknown
+// subjects are linked to the account with the same verified email, or a new account is created.
+func linkOIDCAccount(claims *oidcClaims) (*accountModel.Account, error) {
+	cfg, err := getOIDCConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	identity := accountModel.Identity{
+		Issuer:  &cfg.Issuer,
+		Subject: &claims.Subject,
+	}
+
+	err = accountDao.GetIdentity(&identity)
+	if err == nil {
+		return loadAccount(*identity.Account)
+	}
+
+	if !dao.IsMissingResult(err) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, &ErrOIDC{Reason: "email of the provider account isn't verified"}
+	}
+
+	acc := accountModel.Account{
+		Email: &claims.Email,
+	}
+
+	err = accountDao.GetAccount(&acc)
+	if err != nil {
+		if !dao.IsMissingResult(err) {
+			return nil, err
+		}
+
+		name := claims.Name
+		if name == "" {
+			name = strings.Split(claims.Email, "@")[0]
+		}
+
+		acc = accountModel.Account{
+			ID:    types.Ptr(uuid.UUIDv4()),
+			Name:  &name,
+			Email: &claims.Email,
+			Role:  types.Ptr(accountModel.RoleUser),
+		}
+
+		err = accountDao.InsertAccount(&acc)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("%s was created through OpenID Connect", acc.ID)
+	}
+
+	identity = accountModel.Identity{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Account:   acc.ID,
+		Issuer:    &cfg.Issuer,
+		Subject:   &claims.Subject,
+		Email:     &claims.Email,
+		CreatedAt: types.Ptr(time.Now().UTC()),
+	}
+
+	err = accountDao.InsertIdentity(&identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return &acc, nil
+}
+
+// setOIDCCookie writes the cookie which holds the state of an ongoing login.
+func setOIDCCookie(rw server.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(rw.W, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   server.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OIDCLogin starts a login at the OpenID Connect provider. With reauth=true a logged-in user logs
+// in at the provider again to confirm a sensitive change, see ConfirmIdentity.
+func OIDCLogin(rw server.ResponseWriter, r *server.Request) {
+	reauth := r.R.URL.Query().Get("reauth") == "true"
+
+	state, err := GenerateToken()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	nonce, err := GenerateToken()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	verifier, err := GenerateToken()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	authURL, err := oidcAuthURL(state, nonce, verifier, reauth)
+	if err != nil {
+		log.Error(err.Error())
+		if _, ok := err.(*ErrOIDCNotConfigured); ok {
+			rw.JSON(http.StatusNotFound, nil)
+			return
+		}
+
+		rw.JSON(http.StatusBadGateway, nil)
+		return
+	}
+
+	flowToken, err := createFlowToken(state, oidcFlow{
+		Nonce:    nonce,
+		Verifier: verifier,
+		Reauth:   reauth,
+	})
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	setOIDCCookie(rw, flowToken, int(oidcFlowDuration.Seconds()))
+	http.Redirect(rw.W, r.R, authURL, http.StatusFound)
+}
+
+// OIDCCallback finishes a login at the OpenID Connect provider and starts a session.
+func OIDCCallback(rw server.ResponseWriter, r *server.Request) {
+	cfg, err := getOIDCConfig()
+	if err != nil {
+		rw.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	query := r.R.URL.Query()
+	if query.Get("error") != "" {
+		log.Warning("OpenID Connect login failed: %s", query.Get("error"))
+		rw.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+
+	flowToken, err := r.Cookie(oidcCookie)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	setOIDCCookie(rw, "", -1)
+
+	flow, err := parseFlowToken(*flowToken, query.Get("state"))
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	idToken, err := exchangeCode(query.Get("code"), flow.Verifier)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadGateway, nil)
+		return
+	}
+
+	claims, err := verifyIDToken(idToken, flow.Nonce)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+
+	redirect := cfg.PostLoginURL
+	if redirect == "" {
+		redirect = "/"
+	}
+
+	// A re-authentication doesn't start a session, it only proves the login was recent
+	if flow.Reauth {
+		acc, err := checkReauth(r, claims)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusForbidden, nil)
+			return
+		}
+
+		reauthToken, err := createReauthToken(acc, claims.AuthTime)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+
+		log.Info("%s confirmed their identity through OpenID Connect", acc.ID)
+
+		rw.SetCookie(reauthCookie, reauthToken, int(reauthDuration.Seconds()), false)
+		http.Redirect(rw.W, r.R, redirect, http.StatusFound)
+		return
+	}
+
+	acc, err := linkOIDCAccount(claims)
+	if err != nil {
+		log.Error(err.Error())
+		if _, ok := err.(*ErrOIDC); ok {
+			rw.JSON(http.StatusForbidden, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	log.Info("%s is logging in through OpenID Connect", acc.ID)
+
+	// Accounts with two-factor authentication finish the login with the challenge
+	if twoFactorEnabled(acc) {
+		challenge, err := createChallengeToken(acc)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+
+		separator := "?"
+		if strings.Contains(redirect, "?") {
+			separator = "&"
+		}
+
+		// The challenge is kept out of the URL, so it doesn't end up in the history or in logs
+		setChallengeCookie(rw, challenge, int(challengeDuration.Seconds()))
+
+		redirect += separator + url.Values{"two_factor": {"required"}}.Encode()
+		http.Redirect(rw.W, r.R, redirect, http.StatusFound)
+		return
+	}
+
+	err = startSession(rw, r, acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	http.Redirect(rw.W, r.R, redirect, http.StatusFound)
+}
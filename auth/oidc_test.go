@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// mockIssuer is a local OpenID Connect provider which issues ID tokens for a single code.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+
+	// challenge is the PKCE challenge which was sent to the authorization endpoint.
+	challenge string
+
+	// keyFetches is the amount of times the key set was fetched.
+	keyFetches int
+}
+
+// newMockIssuer starts a mock provider and configures the auth package to use it.
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &mockIssuer{key: key}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.keyFetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"use": "sig",
+				"kid": "test-key",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "test-code" ||
+			pkceChallenge(r.Form.Get("code_verifier")) != issuer.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token": issuer.sign(t, "test-key", issuer.claims),
+		})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	SetOIDCConfig(OIDCConfig{
+		Issuer:       issuer.server.URL,
+		ClientID:     "drankspelletjes",
+		ClientSecret: "secret",
+		RedirectURL:  "https://drankspelletjes.local/api/auth/oidc/callback",
+	})
+
+	t.Cleanup(func() {
+		oidcMu.Lock()
+		oidcConfig = nil
+		oidcMetadata = nil
+		oidcKeys = nil
+		oidcKeysFetchedAt = time.Time{}
+		oidcMu.Unlock()
+	})
+
+	return issuer
+}
+
+// sign signs the claims with the key of the issuer.
+func (m *mockIssuer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+// validClaims returns the claims of a valid ID token for the given nonce.
+func (m *mockIssuer) validClaims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            "drankspelletjes",
+		"sub":            "user-1",
+		"email":          "joske@drankspelletjes.local",
+		"email_verified": true,
+		"nonce":          nonce,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestOIDCFlow(t *testing.T) {
+	issuer := newMockIssuer(t)
+
+	authURL, err := oidcAuthURL("test-state", "test-nonce", "test-verifier", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := parsed.Query()
+	if query.Get("state") != "test-state" || query.Get("nonce") != "test-nonce" ||
+		query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	issuer.challenge = query.Get("code_challenge")
+	issuer.claims = issuer.validClaims("test-nonce")
+	issuer.claims["auth_time"] = time.Now().Unix()
+
+	idToken, err := exchangeCode("test-code", "test-verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := verifyIDToken(idToken, "test-nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "user-1" || !claims.EmailVerified || time.Since(claims.AuthTime) > time.Minute {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	// A wrong PKCE verifier is refused by the provider.
+	_, err = exchangeCode("test-code", "other-verifier")
+	if err == nil {
+		t.Fatal("expected the code exchange to fail with a wrong verifier")
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	issuer := newMockIssuer(t)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongAudience := issuer.validClaims("test-nonce")
+	wrongAudience["aud"] = "someone-else"
+
+	wrongIssuer := issuer.validClaims("test-nonce")
+	wrongIssuer["iss"] = "https://evil.example"
+
+	expired := issuer.validClaims("test-nonce")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	forged := &mockIssuer{server: issuer.server, key: other}
+
+	cases := map[string]string{
+		"nonce":     issuer.sign(t, "test-key", issuer.validClaims("other-nonce")),
+		"audience":  issuer.sign(t, "test-key", wrongAudience),
+		"issuer":    issuer.sign(t, "test-key", wrongIssuer),
+		"expired":   issuer.sign(t, "test-key", expired),
+		"signature": forged.sign(t, "test-key", issuer.validClaims("test-nonce")),
+		"key":       issuer.sign(t, "unknown-key", issuer.validClaims("test-nonce")),
+	}
+
+	for name, token := range cases {
+		_, err := verifyIDToken(token, "test-nonce")
+		if err == nil {
+			t.Fatalf("expected the token with a wrong %s to be rejected", name)
+		}
+	}
+}
+
+func TestUnknownKeyIsNotRefetched(t *testing.T) {
+	issuer := newMockIssuer(t)
+	token := issuer.sign(t, "unknown-key", issuer.validClaims("test-nonce"))
+
+	for i := 0; i < 3; i++ {
+		_, err := verifyIDToken(token, "test-nonce")
+		if err == nil {
+			t.Fatal("expected a token with an unknown key to be rejected")
+		}
+	}
+
+	if issuer.keyFetches != 1 {
+		t.Fatalf("expected the key set to be fetched once, got %d", issuer.keyFetches)
+	}
+
+	// The key set is fetched again once the refresh interval has passed
+	oidcMu.Lock()
+	oidcKeysFetchedAt = time.Now().Add(-oidcKeyRefresh)
+	oidcMu.Unlock()
+
+	verifyIDToken(token, "test-nonce")
+	if issuer.keyFetches != 2 {
+		t.Fatalf("expected the key set to be fetched again, got %d", issuer.keyFetches)
+	}
+}
+
+func TestReauthURL(t *testing.T) {
+	newMockIssuer(t)
+
+	authURL, err := oidcAuthURL("test-state", "test-nonce", "test-verifier", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := parsed.Query()
+	if query.Get("prompt") != "login" || query.Get("max_age") != "0" {
+		t.Fatalf("expected a re-authentication to force a login, got %s", authURL)
+	}
+}
+
+func TestFlowToken(t *testing.T) {
+	flowToken, err := createFlowToken("test-state", oidcFlow{
+		Nonce:    "test-nonce",
+		Verifier: "test-verifier",
+		Reauth:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flow, err := parseFlowToken(flowToken, "test-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if flow.Nonce != "test-nonce" || flow.Verifier != "test-verifier" || !flow.Reauth {
+		t.Fatalf("unexpected flow %+v", flow)
+	}
+
+	_, err = parseFlowToken(flowToken, "other-state")
+	if err == nil {
+		t.Fatal("expected a wrong state to be rejected")
+	}
+}
@@ -0,0 +1,373 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashParams are the argon2id parameters which are used to hash new passwords.
+type HashParams struct {
+	// Memory is the amount of memory in KiB.
+	Memory uint32
+
+	// Iterations is the amount of passes over the memory.
+	Iterations uint32
+
+	// Parallelism is the amount of threads.
+	Parallelism uint8
+
+	// SaltLength is the length of the random salt in bytes.
+	SaltLength uint32
+
+	// KeyLength is the length of the generated key in bytes.
+	KeyLength uint32
+}
+
+// DefaultHashParams are the argon2id parameters recommended by RFC 9106.
+var DefaultHashParams = HashParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Bounds of the parameters of stored hashes. A hash outside of them is rejected instead of
+// verified, so a tampered hash can't make a login panic or exhaust the memory.
+const (
+	maxHashMemory      = 1024 * 1024
+	maxHashIterations  = 32
+	maxHashParallelism = 16
+	minHashSaltLength  = 8
+	minHashKeyLength   = 16
+	maxHashKeyLength   = 128
+)
+
+// hashParams are the parameters which are currently used to hash passwords.
+var hashParams = DefaultHashParams
+
+// SetHashParams selects the argon2id parameters for new password hashes. Existing hashes with other
+// parameters are rehashed on the next successful login.
+func SetHashParams(params HashParams) {
+	hashParams = params
+}
+
+// PasswordPolicy describes the requirements a new password has to meet.
+type PasswordPolicy struct {
+	// MinLength is the minimum amount of characters.
+	MinLength int
+}
+
+// passwordPolicy is the policy which is checked for new passwords.
+var passwordPolicy = PasswordPolicy{
+	MinLength: 10,
+}
+
+// SetPasswordPolicy selects the policy which new passwords have to meet.
+func SetPasswordPolicy(policy PasswordPolicy) {
+	passwordPolicy = policy
+}
+
+var (
+	breachedPasswords   = map[string]struct{}{}
+	breachedPasswordsMu sync.RWMutex
+)
+
+// LoadBreachedPasswords reads a list of breached passwords, one per line, which are refused as new
+// passwords.
+func LoadBreachedPasswords(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	passwords := map[string]struct{}{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		password := strings.TrimSpace(scanner.Text())
+		if password != "" {
+			passwords[password] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	breachedPasswordsMu.Lock()
+	breachedPasswords = passwords
+	breachedPasswordsMu.Unlock()
+
+	return nil
+}
+
+// checkPasswordPolicy checks whether the password is allowed as a new password.
+func checkPasswordPolicy(password string) error {
+	if utf8.RuneCountInString(password) < passwordPolicy.MinLength {
+		return &ErrWeakPassword{
+			Reason: fmt.Sprintf("password must contain at least %d characters", passwordPolicy.MinLength),
+		}
+	}
+
+	breachedPasswordsMu.RLock()
+	_, breached := breachedPasswords[password]
+	breachedPasswordsMu.RUnlock()
+
+	if breached {
+		return &ErrWeakPassword{Reason: "password appears in a list of breached passwords"}
+	}
+
+	return nil
+}
+
+// hashPassword hashes the given password with argon2id. The hash is encoded in the PHC string
+// format so the parameters can be read back when verifying.
+func hashPassword(password string) (string, error) {
+	params := hashParams
+
+	salt := make([]byte, params.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory,
+		params.Parallelism, params.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.Memory,
+		params.Iterations, params.Parallelism, base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// decodeArgon2Hash parses an argon2id hash in the PHC string format.
+func decodeArgon2Hash(hash string) (*HashParams, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, &ErrInvalidHash{}
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return nil, nil, nil, &ErrInvalidHash{}
+	}
+
+	params := HashParams{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations,
+		&params.Parallelism)
+	if err != nil {
+		return nil, nil, nil, &ErrInvalidHash{}
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, &ErrInvalidHash{}
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, &ErrInvalidHash{}
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	if !validHashParams(&params) {
+		return nil, nil, nil, &ErrInvalidHash{}
+	}
+
+	return &params, salt, key, nil
+}
+
+// validHashParams checks whether the parameters of a stored hash are within bounds. Argon2 needs at
+// least 8 KiB of memory per thread.
+func validHashParams(params *HashParams) bool {
+	return params.Parallelism >= 1 && params.Parallelism <= maxHashParallelism &&
+		params.Iterations >= 1 && params.Iterations <= maxHashIterations &&
+		params.Memory >= 8*uint32(params.Parallelism) && params.Memory <= maxHashMemory &&
+		params.SaltLength >= minHashSaltLength &&
+		params.KeyLength >= minHashKeyLength && params.KeyLength <= maxHashKeyLength
+}
+
+// verifyPassword checks if the password matches the hash. Both argon2id and legacy bcrypt hashes
+// are supported. The second return value is true when the hash should be replaced by a hash with
+// the current parameters.
+func verifyPassword(password, hash string) (bool, bool) {
+	if strings.HasPrefix(hash, "$2") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		return err == nil, true
+	}
+
+	params, salt, key, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return false, false
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory,
+		params.Parallelism, params.KeyLength)
+
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false
+	}
+
+	return true, *params != hashParams
+}
+
+// rehashPassword replaces the stored hash of the account by a hash with the current parameters.
+func rehashPassword(acc *accountModel.Account, password string) {
+	hash, err := hashPassword(password)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	update := accountModel.Account{
+		Password: &hash,
+	}
+
+	selectors := map[string]interface{}{
+		"ID": acc.ID,
+	}
+
+	err = accountDao.UpdateAccount(nil, &update, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	acc.Password = &hash
+	log.Info("%s password hash was upgraded", acc.ID)
+}
+
+// confirmPassword checks the current password of the account before a sensitive change. Wrong
+// passwords count as failed logins, so this can't be used to brute-force a stolen session.
+func confirmPassword(acc *accountModel.Account, password string) bool {
+	if acc.Password == nil {
+		return false
+	}
+
+	key := accountThrottleKey(*acc.ID)
+	if lockedUntil(key) != nil {
+		return false
+	}
+
+	match, rehash := verifyPassword(password, *acc.Password)
+	if !match {
+		recordLoginFailure(key)
+		return false
+	}
+
+	if rehash {
+		rehashPassword(acc, password)
+	}
+
+	return true
+}
+
+// ChangePassword sets a new password for the current user after confirming the current one, or the
+// identity of accounts without a password. All the other sessions of the account are revoked
+// afterwards.
+func ChangePassword(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	body := struct {
+		CurrentPassword string `json:"current_password"`
+		Password        string `json:"password"`
+		Code            string `json:"code"`
+		RecoveryCode    string `json:"recovery_code"`
+	}{}
+
+	v := validator.V{
+		"current_password": validator.IsOptString,
+		"password":         validator.IsString,
+		"code":             validator.IsOptString,
+		"recovery_code":    validator.IsOptString,
+	}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	status := ConfirmIdentity(r, acc, Confirmation{
+		Password:     body.CurrentPassword,
+		Code:         body.Code,
+		RecoveryCode: body.RecoveryCode,
+	})
+	if status != http.StatusOK {
+		log.Warning("%s couldn't confirm their identity to change their password", acc.ID)
+		rw.JSON(status, nil)
+		return
+	}
+
+	err = checkPasswordPolicy(body.Password)
+	if err != nil {
+		rw.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Update password
+	hash, err := hashPassword(body.Password)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	update := accountModel.Account{
+		Password: &hash,
+	}
+
+	selectors := map[string]interface{}{
+		"ID": acc.ID,
+	}
+
+	err = accountDao.UpdateAccount(nil, &update, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	// Revoke the other sessions
+	_, sessionID, err := getClaims(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = accountDao.DeleteOtherSessions(acc, sessionID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	log.Info("%s changed their password", acc.ID)
+
+	rw.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	"github.com/marvindeckmyn/drankspelletjes-server/mail"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// passwordResetDuration is how long a password reset token can be used.
+const passwordResetDuration = time.Hour
+
+// PasswordResetURL is the link which is mailed to the user, the token is filled in at %s.
+var PasswordResetURL = "https://drankspelletjes.local/password/reset?token=%s"
+
+// GenerateToken creates a random URL safe token.
+func GenerateToken() (string, error) {
+	data := make([]byte, 32)
+
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// HashToken hashes a token so it can be stored without being usable when the database leaks.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendPasswordReset creates a password reset token for the account with the given email and mails
+// it to the account. Unknown emails are silently ignored.
+func sendPasswordReset(email string) {
+	acc := accountModel.Account{
+		Email: &email,
+	}
+
+	err := accountDao.GetAccount(&acc)
+	if err != nil {
+		return
+	}
+
+	token, err := GenerateToken()
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+
+	reset := accountModel.PasswordReset{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Account:   acc.ID,
+		TokenHash: types.Ptr(HashToken(token)),
+		CreatedAt: &now,
+		ExpiresAt: types.Ptr(now.Add(passwordResetDuration)),
+	}
+
+	err = accountDao.InsertPasswordReset(&reset)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	err = mail.Send(mail.Message{
+		To:      *acc.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use the link below to choose a new password. It expires in one hour.\r\n\r\n%s",
+			fmt.Sprintf(PasswordResetURL, token)),
+	})
+	if err != nil {
+		log.Error(err.Error())
+	}
+}
+
+// ForgotPassword mails a password reset link to the account with the given email. The response is
+// the same whether the email exists or not.
+func ForgotPassword(rw server.ResponseWriter, r *server.Request) {
+	body := struct {
+		Email string `json:"email"`
+	}{}
+
+	v := validator.V{
+		"email": validator.IsEmail,
+	}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Handled in the background so the response time doesn't reveal whether the email exists.
+	go sendPasswordReset(body.Email)
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// ResetPassword sets a new password with a password reset token. All the sessions of the account
+// are revoked afterwards.
+func ResetPassword(rw server.ResponseWriter, r *server.Request) {
+	body := struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}{}
+
+	v := validator.V{
+		"token":    validator.IsString,
+		"password": validator.IsString,
+	}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = checkPasswordPolicy(body.Password)
+	if err != nil {
+		rw.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	accID, err := accountDao.GetPasswordReset(HashToken(body.Token))
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	hash, err := hashPassword(body.Password)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	acc := accountModel.Account{
+		ID:       &accID,
+		Password: &hash,
+	}
+
+	selectors := map[string]interface{}{
+		"ID": acc.ID,
+	}
+
+	// Consume the token, update the password and revoke the sessions and other outstanding tokens
+	// together, so a failure can't burn the token while the old password keeps working
+	tx := cdb.NewTx()
+
+	err = accountDao.ConsumePasswordReset(tx, HashToken(body.Token), time.Now().UTC())
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = accountDao.UpdateAccount(tx, &acc, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = RevokeSessions(tx, &acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = accountDao.DeletePasswordResets(tx, &acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+
+		// The token was used by another request in the meantime
+		if dao.IsGuardFailed(err) {
+			rw.JSON(http.StatusBadRequest, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	log.Info("%s reset their password", accID)
+
+	rw.JSON(http.StatusOK, nil)
+}
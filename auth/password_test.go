@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastHashParams keeps the tests quick.
+var fastHashParams = HashParams{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestHashPassword(t *testing.T) {
+	SetHashParams(fastHashParams)
+	t.Cleanup(func() { SetHashParams(DefaultHashParams) })
+
+	hash, err := hashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match, rehash := verifyPassword("correct horse battery staple", hash)
+	if !match || rehash {
+		t.Fatalf("expected a match without rehash, got match %t rehash %t", match, rehash)
+	}
+
+	match, _ = verifyPassword("wrong password", hash)
+	if match {
+		t.Fatal("expected a wrong password not to match")
+	}
+
+	// Changing the parameters should flag the old hash for a rehash.
+	params := fastHashParams
+	params.Iterations = 2
+	SetHashParams(params)
+
+	match, rehash = verifyPassword("correct horse battery staple", hash)
+	if !match || !rehash {
+		t.Fatalf("expected a match with rehash, got match %t rehash %t", match, rehash)
+	}
+}
+
+func TestVerifyBcryptPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("joske123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match, rehash := verifyPassword("joske123", string(hash))
+	if !match || !rehash {
+		t.Fatalf("expected a bcrypt match with rehash, got match %t rehash %t", match, rehash)
+	}
+
+	match, _ = verifyPassword("joske124", string(hash))
+	if match {
+		t.Fatal("expected a wrong password not to match")
+	}
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	match, _ := verifyPassword("joske123", "$argon2id$v=19$m=abc$salt$key")
+	if match {
+		t.Fatal("expected an invalid hash not to match")
+	}
+}
+
+func TestDecodeOutOfRangeHash(t *testing.T) {
+	salt, key := "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+
+	hashes := []string{
+		"$argon2id$v=19$m=1024,t=1,p=0$" + salt + "$" + key,
+		"$argon2id$v=19$m=4294967295,t=1,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=1024,t=0,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$",
+	}
+
+	for _, hash := range hashes {
+		_, _, _, err := decodeArgon2Hash(hash)
+		if err == nil {
+			t.Fatalf("expected %s to be rejected", hash)
+		}
+
+		// Verifying must not panic
+		match, _ := verifyPassword("joske123", hash)
+		if match {
+			t.Fatalf("expected %s not to match", hash)
+		}
+	}
+
+	_, _, _, err := decodeArgon2Hash("$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$" + key)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	err := os.WriteFile(path, []byte("password1234\nqwertyuiop\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = LoadBreachedPasswords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { breachedPasswords = map[string]struct{}{} })
+
+	if checkPasswordPolicy("short") == nil {
+		t.Fatal("expected a short password to be refused")
+	}
+
+	if checkPasswordPolicy("qwertyuiop") == nil {
+		t.Fatal("expected a breached password to be refused")
+	}
+
+	if err := checkPasswordPolicy("drank spelletjes zijn leuk"); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+}
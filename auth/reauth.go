@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+)
+
+// reauthCookie is the name of the cookie which proves a recent login at the OpenID Connect
+// provider.
+const reauthCookie = "drnkngg-reauth"
+
+// reauthDuration is how long a login at the provider counts as recent.
+const reauthDuration = 5 * time.Minute
+
+// Confirmation is what a user sends along to confirm a sensitive change of their account.
+type Confirmation struct {
+	Password     string
+	Code         string
+	RecoveryCode string
+}
+
+// createReauthToken creates a token which proves the account logged in at the provider at the
+// given time. It expires once that login no longer counts as recent.
+func createReauthToken(acc *accountModel.Account, authTime time.Time) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose": "reauth",
+		"ID":      acc.ID.String(),
+		"exp":     authTime.Add(reauthDuration).Unix(),
+	})
+
+	return token.SignedString(secretKey)
+}
+
+// reauthenticated checks if the request carries a recent login at the provider of the account.
+func reauthenticated(r *server.Request, acc *accountModel.Account) bool {
+	reauthToken, err := r.Cookie(reauthCookie)
+	if err != nil {
+		return false
+	}
+
+	claims, err := ParseToken(*reauthToken)
+	if err != nil || claims == nil || claims["purpose"] != "reauth" {
+		return false
+	}
+
+	accID, err := claimUUID(claims, "ID")
+	if err != nil {
+		return false
+	}
+
+	return accID == *acc.ID
+}
+
+// ConfirmIdentity checks who is making a sensitive change before it is made, so a stolen session
+// isn't enough to take over the account. Accounts with a password confirm it. Accounts without one
+// confirm with their second factor, or with a recent login at the provider through
+// /api/auth/oidc/login?reauth=true. It returns the status to answer with, which is 200 when the
+// identity is confirmed.
+func ConfirmIdentity(r *server.Request, acc *accountModel.Account, confirmation Confirmation) int {
+	if acc.Password != nil {
+		if !confirmPassword(acc, confirmation.Password) {
+			return http.StatusUnauthorized
+		}
+
+		return http.StatusOK
+	}
+
+	if confirmation.Code != "" || confirmation.RecoveryCode != "" {
+		key := accountThrottleKey(*acc.ID)
+		if lockedUntil(key) != nil || !twoFactorEnabled(acc) {
+			return http.StatusForbidden
+		}
+
+		if !checkSecondFactor(acc, confirmation.Code, confirmation.RecoveryCode) {
+			recordLoginFailure(key)
+			return http.StatusForbidden
+		}
+
+		return http.StatusOK
+	}
+
+	if !reauthenticated(r, acc) {
+		return http.StatusForbidden
+	}
+
+	return http.StatusOK
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+func TestConfirmIdentityWithoutPassword(t *testing.T) {
+	acc := &accountModel.Account{ID: types.Ptr(uuid.UUIDv4())}
+	other := &accountModel.Account{ID: types.Ptr(uuid.UUIDv4())}
+
+	cases := map[string]struct {
+		acc      *accountModel.Account
+		authTime time.Time
+		status   int
+	}{
+		"none":   {status: http.StatusForbidden},
+		"recent": {acc: acc, authTime: time.Now(), status: http.StatusOK},
+		"old":    {acc: acc, authTime: time.Now().Add(-time.Hour), status: http.StatusForbidden},
+		"other":  {acc: other, authTime: time.Now(), status: http.StatusForbidden},
+	}
+
+	for name, c := range cases {
+		_, r, _ := newTestRequest(nil)
+
+		if c.acc != nil {
+			reauthToken, err := createReauthToken(c.acc, c.authTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r.R.AddCookie(&http.Cookie{Name: reauthCookie, Value: reauthToken})
+		}
+
+		status := ConfirmIdentity(r, acc, Confirmation{Password: "joske123"})
+		if status != c.status {
+			t.Fatalf("expected status %d with a %s re-authentication, got %d", c.status, name, status)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// tokenCookie is the name of the cookie which holds the JWT of a session.
+const tokenCookie = "drnkngg-token"
+
+// sessionDuration is how long a session stays valid after logging in.
+const sessionDuration = 7 * 24 * time.Hour
+
+// loadSession fetches the session with the given ID.
+func loadSession(id uuid.UUID) (*accountModel.Session, error) {
+	session := accountModel.Session{
+		ID: &id,
+	}
+
+	err := accountDao.GetSession(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// setTokenCookie writes the cookie which holds the JWT to the response. The cookie is HttpOnly,
+// Secure and SameSite so it can't be read by scripts or sent along with cross-site requests.
+func setTokenCookie(rw server.ResponseWriter, value string, maxAge int) {
+	rw.SetCookie(tokenCookie, value, maxAge, false)
+}
+
+// startSession creates a new session for the account and hands the token to the client. Logging in
+// during the grace period of a deletion cancels the deletion.
+func startSession(rw server.ResponseWriter, r *server.Request, acc *accountModel.Account) error {
+	now := time.Now().UTC()
+
+	if acc.DeleteAfter != nil {
+		acc.DeletedAt = nil
+		acc.DeleteAfter = nil
+
+		err := accountDao.UpdateAccountDeletion(acc)
+		if err != nil {
+			return err
+		}
+
+		log.Info("%s cancelled the deletion of their account", acc.ID)
+	}
+
+	session := accountModel.Session{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Account:   acc.ID,
+		IP:        types.Ptr(r.ClientIP()),
+		UserAgent: types.Ptr(r.R.UserAgent()),
+		CreatedAt: &now,
+		ExpiresAt: types.Ptr(now.Add(sessionDuration)),
+	}
+
+	err := accountDao.InsertSession(&session)
+	if err != nil {
+		return err
+	}
+
+	jwt, err := createToken(acc, &session)
+	if err != nil {
+		return err
+	}
+
+	setTokenCookie(rw, jwt, int(sessionDuration.Seconds()))
+	return nil
+}
+
+// checkSession verifies that the session from the token still exists and belongs to the account.
+func checkSession(accID uuid.UUID, sessionID uuid.UUID,
+	load func(id uuid.UUID) (*accountModel.Session, error)) error {
+
+	session, err := load(sessionID)
+	if err != nil {
+		return &ErrSessionExpired{}
+	}
+
+	if *session.Account != accID || time.Now().After(*session.ExpiresAt) {
+		return &ErrSessionExpired{}
+	}
+
+	return nil
+}
+
+// RevokeSessions ends all the sessions of the given account, as part of the transaction when one is
+// given.
+func RevokeSessions(tx *cdb.Transaction, acc *accountModel.Account) error {
+	session := accountModel.Session{
+		Account: acc.ID,
+	}
+
+	return accountDao.DeleteSession(tx, &session)
+}
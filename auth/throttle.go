@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// freeLoginAttempts is the amount of failed logins which are allowed before a key gets locked.
+const freeLoginAttempts = 5
+
+// baseLockout is the lockout after the first failed attempt which exceeds the free attempts. Every
+// following failure doubles the lockout.
+const baseLockout = 30 * time.Second
+
+// maxLockout is the longest time a key can be locked.
+const maxLockout = time.Hour
+
+// failureWindow is how long failed attempts are remembered.
+const failureWindow = 24 * time.Hour
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// compareDummyHash compares the password against a dummy hash so a login for an unknown email
+// takes as long as one for an existing account.
+func compareDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = hashPassword("drankspelletjes-dummy-password")
+	})
+
+	verifyPassword(password, dummyHash)
+}
+
+// accountThrottleKey returns the login throttle key of an account.
+func accountThrottleKey(id uuid.UUID) string {
+	return "account:" + id.String()
+}
+
+// emailThrottleKey returns the login throttle key of an email which doesn't belong to an account.
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// ipThrottleKey returns the login throttle key of an IP address.
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// lockoutDuration returns how long a key gets locked after the given amount of failed attempts.
+func lockoutDuration(failures int32) time.Duration {
+	if failures <= freeLoginAttempts {
+		return 0
+	}
+
+	exp := float64(failures - freeLoginAttempts - 1)
+	lockout := time.Duration(float64(baseLockout) * math.Pow(2, exp))
+	if lockout > maxLockout || lockout <= 0 {
+		return maxLockout
+	}
+
+	return lockout
+}
+
+// lockedUntil returns until when the logins for the given key are locked. Nil is returned when the
+// key isn't locked.
+func lockedUntil(key string) *time.Time {
+	throttle := accountModel.LoginThrottle{
+		Key: &key,
+	}
+
+	err := accountDao.GetLoginThrottle(&throttle)
+	if err != nil || throttle.LockedUntil == nil || time.Now().After(*throttle.LockedUntil) {
+		return nil
+	}
+
+	return throttle.LockedUntil
+}
+
+// recordLoginFailure registers a failed attempt for the given key and locks it when it exceeded
+// its free attempts.
+func recordLoginFailure(key string) {
+	failures, err := accountDao.RecordLoginFailure(key, failureWindow)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	lockout := lockoutDuration(failures)
+	if lockout == 0 {
+		return
+	}
+
+	err = accountDao.LockLogin(key, time.Now().UTC().Add(lockout))
+	if err != nil {
+		log.Error(err.Error())
+	}
+}
+
+// tooManyAttempts answers with a 429 which tells the client when it can try again.
+func tooManyAttempts(rw server.ResponseWriter, until time.Time) {
+	retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+
+	rw.W.Header().Set("Retry-After", fmt.Sprint(retryAfter))
+	rw.JSON(http.StatusTooManyRequests, map[string]interface{}{
+		"retry_at": until.UTC(),
+	})
+}
+
+// UnlockAccount clears the failed login attempts and the lockout of an account.
+func UnlockAccount(rw server.ResponseWriter, r *server.Request) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := struct {
+		ID uuid.UUID `json:"id"`
+	}{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	tx := cdb.NewTx()
+
+	err = accountDao.DeleteLoginThrottle(tx, accountThrottleKey(url.ID))
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionUnlock, audit.EntityAccount, &url.ID, nil, nil)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	log.Info("%s unlocked account %s", r.Account().ID, url.ID)
+
+	rw.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	cases := map[int32]time.Duration{
+		0:                      0,
+		freeLoginAttempts:      0,
+		freeLoginAttempts + 1:  baseLockout,
+		freeLoginAttempts + 2:  2 * baseLockout,
+		freeLoginAttempts + 4:  8 * baseLockout,
+		freeLoginAttempts + 50: maxLockout,
+		freeLoginAttempts + 90: maxLockout,
+	}
+
+	for failures, want := range cases {
+		got := lockoutDuration(failures)
+		if got != want {
+			t.Fatalf("lockout after %d failures: expected %s, got %s", failures, want, got)
+		}
+	}
+}
+
+func TestEmailThrottleKey(t *testing.T) {
+	// Variations of the same email share their failed attempts
+	if emailThrottleKey(" Anna@Example.com") != emailThrottleKey("anna@example.com") {
+		t.Fatal("expected the email key to ignore case and spaces")
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// totpPeriod is the amount of seconds a TOTP code is valid.
+const totpPeriod = 30
+
+// totpDigits is the amount of digits of a TOTP code.
+const totpDigits = 6
+
+// totpSkew is the amount of periods before and after the current one which are accepted, to allow
+// for clock drift.
+const totpSkew = 1
+
+// totpIssuer is the name which is shown in authenticator apps.
+const totpIssuer = "Drankspelletjes"
+
+// totpEncoding is the base32 encoding which authenticator apps expect for secrets.
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateTotpSecret creates a random 160 bit TOTP secret.
+func generateTotpSecret() (string, error) {
+	secret := make([]byte, 20)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// totpURI returns the otpauth URI which authenticator apps can scan as a QR code.
+func totpURI(secret string, email string) string {
+	label := url.PathEscape(totpIssuer + ":" + email)
+
+	params := url.Values{
+		"secret":    {secret},
+		"issuer":    {totpIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(totpPeriod)},
+	}
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// totpCode calculates the HOTP code of the secret for the given time step as described in RFC 4226.
+func totpCode(secret []byte, step int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// validateTotp checks the code against the secret at the given time. The time step which matched is
+// returned so it can't be used a second time. Steps up to and including lastStep are refused.
+func validateTotp(secret string, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.ReplaceAll(code, " ", "")
+	current := now.Unix() / totpPeriod
+
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
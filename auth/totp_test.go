@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTotpCode(t *testing.T) {
+	// Test vectors from RFC 6238, truncated to 6 digits.
+	secret := []byte("12345678901234567890")
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, want := range cases {
+		got := totpCode(secret, unix/totpPeriod)
+		if got != want {
+			t.Fatalf("code at %d: expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestValidateTotp(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+
+	step, ok := validateTotp(secret, "081804", now, 0)
+	if !ok {
+		t.Fatal("expected the current code to be valid")
+	}
+
+	// The same code can't be used twice.
+	_, ok = validateTotp(secret, "081804", now, step)
+	if ok {
+		t.Fatal("expected a used code to be refused")
+	}
+
+	// Codes of the previous period are still accepted to allow for clock drift.
+	_, ok = validateTotp(secret, "081804", now.Add(totpPeriod*time.Second), 0)
+	if !ok {
+		t.Fatal("expected the code of the previous period to be valid")
+	}
+
+	_, ok = validateTotp(secret, "081804", now.Add(5*totpPeriod*time.Second), 0)
+	if ok {
+		t.Fatal("expected an old code to be refused")
+	}
+}
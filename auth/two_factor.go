@@ -0,0 +1,377 @@
+package auth
+
+import (
+	"crypto/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// challengeCookie is the name of the cookie which holds the challenge of a login through OpenID
+// Connect.
+const challengeCookie = "drnkngg-2fa"
+
+// challengeDuration is how long a user has to enter the second factor after the password.
+const challengeDuration = 5 * time.Minute
+
+// recoveryCodeCount is the amount of recovery codes which are handed out when enabling 2FA.
+const recoveryCodeCount = 10
+
+// requireAdminTwoFactor makes two-factor authentication mandatory for admin accounts.
+var requireAdminTwoFactor = false
+
+// SetRequireAdminTwoFactor selects whether admin accounts need two-factor authentication to use
+// admin endpoints.
+func SetRequireAdminTwoFactor(required bool) {
+	requireAdminTwoFactor = required
+}
+
+// twoFactorEnabled returns true when the account has confirmed its TOTP secret.
+func twoFactorEnabled(acc *accountModel.Account) bool {
+	return acc.TotpEnabled != nil && *acc.TotpEnabled && acc.TotpSecret != nil
+}
+
+// createChallengeToken creates a short-lived token which proves the password of the account was
+// correct. It can only be exchanged for a session together with the second factor.
+func createChallengeToken(acc *accountModel.Account) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose": "2fa",
+		"ID":      acc.ID.String(),
+		"exp":     time.Now().Add(challengeDuration).Unix(),
+	})
+
+	return token.SignedString(secretKey)
+}
+
+// setChallengeCookie writes the cookie which holds the challenge of a login through OpenID Connect.
+// Only the endpoint which checks the second factor gets it.
+func setChallengeCookie(rw server.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(rw.W, &http.Cookie{
+		Name:     challengeCookie,
+		Value:    value,
+		Path:     "/api/auth/login/2fa",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   server.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// parseChallengeToken verifies a challenge token and returns the ID of its account.
+func parseChallengeToken(challenge string) (uuid.UUID, error) {
+	claims, err := ParseToken(challenge)
+	if err != nil || claims == nil || claims["purpose"] != "2fa" {
+		return uuid.UUID{}, &ErrInvalidToken{}
+	}
+
+	return claimUUID(claims, "ID")
+}
+
+// normalizeRecoveryCode strips the formatting from a recovery code.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// generateRecoveryCodes replaces the recovery codes of the account and returns the new codes.
+func generateRecoveryCodes(acc *accountModel.Account) ([]string, error) {
+	err := accountDao.DeleteRecoveryCodes(acc)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := []string{}
+	now := time.Now().UTC()
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		data := make([]byte, 10)
+
+		_, err := rand.Read(data)
+		if err != nil {
+			return nil, err
+		}
+
+		raw := strings.ToLower(totpEncoding.EncodeToString(data))
+		code := raw[:8] + "-" + raw[8:16]
+
+		recoveryCode := accountModel.RecoveryCode{
+			ID:        types.Ptr(uuid.UUIDv4()),
+			Account:   acc.ID,
+			CodeHash:  types.Ptr(HashToken(normalizeRecoveryCode(code))),
+			CreatedAt: &now,
+		}
+
+		err = accountDao.InsertRecoveryCode(&recoveryCode)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// checkSecondFactor verifies a TOTP code or a recovery code of the account. Used codes are
+// remembered so they can't be replayed.
+func checkSecondFactor(acc *accountModel.Account, code string, recoveryCode string) bool {
+	if recoveryCode != "" {
+		err := accountDao.ConsumeRecoveryCode(acc, HashToken(normalizeRecoveryCode(recoveryCode)))
+		if err != nil && !dao.IsMissingResult(err) {
+			log.Error(err.Error())
+		}
+
+		return err == nil
+	}
+
+	lastStep := int64(0)
+	if acc.TotpLastStep != nil {
+		lastStep = *acc.TotpLastStep
+	}
+
+	step, ok := validateTotp(*acc.TotpSecret, code, time.Now(), lastStep)
+	if !ok {
+		return false
+	}
+
+	// Another request may have used the same code in the meantime
+	err := accountDao.AdvanceTotpStep(acc, step)
+	if err != nil {
+		if !dao.IsMissingResult(err) {
+			log.Error(err.Error())
+		}
+
+		return false
+	}
+
+	acc.TotpLastStep = &step
+
+	return true
+}
+
+// LoginTwoFactor finishes a login of an account with two-factor authentication by checking the
+// second factor for the challenge which was handed out by Login. A login through OpenID Connect
+// hands out the challenge in a cookie instead.
+func LoginTwoFactor(rw server.ResponseWriter, r *server.Request) {
+	body := struct {
+		Challenge    string `json:"challenge"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}{}
+
+	v := validator.V{
+		"challenge":     validator.IsOptString,
+		"code":          validator.IsOptString,
+		"recovery_code": validator.IsOptString,
+	}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil || (body.Code == "" && body.RecoveryCode == "") {
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if body.Challenge == "" {
+		challenge, err := r.Cookie(challengeCookie)
+		if err != nil {
+			rw.JSON(http.StatusBadRequest, nil)
+			return
+		}
+
+		body.Challenge = *challenge
+	}
+
+	accID, err := parseChallengeToken(body.Challenge)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+
+	// The second factor is throttled like the password
+	accKey := accountThrottleKey(accID)
+	if until := lockedUntil(accKey); until != nil {
+		tooManyAttempts(rw, *until)
+		return
+	}
+
+	acc, err := loadAccount(accID)
+	if err != nil || !twoFactorEnabled(acc) {
+		rw.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+
+	if !checkSecondFactor(acc, body.Code, body.RecoveryCode) {
+		log.Warning("%s entered a wrong second factor", acc.ID)
+		recordLoginFailure(accKey)
+		rw.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+
+	err = accountDao.DeleteLoginThrottle(nil, accKey)
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	setChallengeCookie(rw, "", -1)
+
+	err = startSession(rw, r, acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the current user. Two-factor authentication is
+// only enabled after the secret is confirmed with ConfirmTwoFactor.
+func EnrollTwoFactor(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	if twoFactorEnabled(acc) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	secret, err := generateTotpSecret()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	acc.TotpSecret = &secret
+	acc.TotpEnabled = types.Ptr(false)
+	acc.TotpLastStep = nil
+
+	err = accountDao.UpdateAccountTotp(acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, map[string]interface{}{
+		"secret": secret,
+		"uri":    totpURI(secret, *acc.Email),
+	})
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proved their authenticator app
+// generates valid codes. The recovery codes are only returned once.
+func ConfirmTwoFactor(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	body := struct {
+		Code string `json:"code"`
+	}{}
+
+	v := validator.V{
+		"code": validator.IsString,
+	}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if acc.TotpSecret == nil || twoFactorEnabled(acc) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	step, ok := validateTotp(*acc.TotpSecret, body.Code, time.Now(), 0)
+	if !ok {
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	acc.TotpEnabled = types.Ptr(true)
+	acc.TotpLastStep = &step
+
+	err = accountDao.UpdateAccountTotp(acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	codes, err := generateRecoveryCodes(acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	log.Info("%s enabled two-factor authentication", acc.ID)
+
+	rw.JSON(http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns off two-factor authentication after checking a current code.
+func DisableTwoFactor(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	body := struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}{}
+
+	v := validator.V{
+		"code":          validator.IsOptString,
+		"recovery_code": validator.IsOptString,
+	}
+
+	err := v.ValidateAndMarshalBody(r.R.Body, &body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if !twoFactorEnabled(acc) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	if !checkSecondFactor(acc, body.Code, body.RecoveryCode) {
+		rw.JSON(http.StatusUnauthorized, nil)
+		return
+	}
+
+	acc.TotpSecret = nil
+	acc.TotpEnabled = types.Ptr(false)
+	acc.TotpLastStep = nil
+
+	err = accountDao.UpdateAccountTotp(acc)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = accountDao.DeleteRecoveryCodes(acc)
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	log.Info("%s disabled two-factor authentication", acc.ID)
+
+	rw.JSON(http.StatusOK, nil)
+}
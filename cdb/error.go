@@ -130,3 +130,10 @@ func (e *ErrCreateStmt) Error() string {
 
 	return "Error creating statement"
 }
+
+// ErrGuard is returned when a guard statement of a transaction failed.
+type ErrGuard struct{}
+
+func (e *ErrGuard) Error() string {
+	return "the transaction was rolled back by a guard"
+}
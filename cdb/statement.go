@@ -11,6 +11,7 @@ import (
 type Statement struct {
 	Query  string
 	params map[string]interface{}
+	guard  bool
 }
 
 // AppendReturningID appends the returning id clause to the statement
@@ -175,6 +176,18 @@ func Prepare(query string) Statement {
 	}
 }
 
+// PrepareGuard creates a statement which checks a condition inside a transaction. The query has to
+// return a single boolean, the transaction is rolled back with ErrGuard when it is false. Reads
+// which a transaction depends on are checked again this way, so concurrent changes can't slip
+// through.
+func PrepareGuard(query string) Statement {
+	return Statement{
+		Query:  query,
+		params: map[string]interface{}{},
+		guard:  true,
+	}
+}
+
 // PrepareSelect builds a select statement with the given fields.
 func PrepareSelect(table string, fields []string, label string, colNames map[string]string, obj interface{}) Statement {
 	query := "SELECT "
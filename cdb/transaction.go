@@ -37,6 +37,21 @@ func (t *Transaction) Exec() error {
 				return err
 			}
 
+			if stmt.guard {
+				ok := false
+
+				err := tx.QueryRow(ctx, str, values...).Scan(&ok)
+				if err != nil {
+					return err
+				}
+
+				if !ok {
+					return &ErrGuard{}
+				}
+
+				continue
+			}
+
 			if _, err := tx.Exec(ctx, str, values...); err != nil {
 				fmt.Println(str, values)
 				return err
@@ -54,3 +69,23 @@ func BeginTx() *Transaction {
 
 	return tx
 }
+
+// NewTx creates a transaction which belongs to the caller. Unlike BeginTx it isn't shared with
+// other requests, so the statements of concurrent requests can't end up in each other's
+// transaction.
+func NewTx() *Transaction {
+	return &Transaction{
+		stmts: []Statement{},
+	}
+}
+
+// ExecTx adds the statement to the transaction when one is given. Without a transaction the
+// statement is executed right away.
+func ExecTx(t *Transaction, s *Statement) ([]CdbResult, error) {
+	if t != nil {
+		t.AddStmt(*s)
+		return nil, nil
+	}
+
+	return Exec(s)
+}
@@ -13,6 +13,14 @@ var colNamesAccount = map[string]string{
 	"Name":     "name",
 	"Email":    "email",
 	"Password": "password",
+	"Role":     "role",
+
+	"TotpSecret":   "totp_secret",
+	"TotpEnabled":  "totp_enabled",
+	"TotpLastStep": "totp_last_step",
+
+	"DeletedAt":   "deleted_at",
+	"DeleteAfter": "delete_after",
 }
 
 // unmarshalAccount parses the database row to the account object.
@@ -20,7 +28,13 @@ func unmarshalAccount(acc *accountModel.Account, r cdb.CdbResult) error {
 	r.UUID("id", &acc.ID)
 	r.Str("name", &acc.Name)
 	r.Str("email", &acc.Email)
-	r.Str("password", &acc.Password)
+	r.OptStr("password", &acc.Password)
+	r.OptStr("role", &acc.Role)
+	r.OptStr("totp_secret", &acc.TotpSecret)
+	r.OptBool("totp_enabled", &acc.TotpEnabled)
+	r.OptInt64("totp_last_step", &acc.TotpLastStep)
+	r.OptTime("deleted_at", &acc.DeletedAt)
+	r.OptTime("delete_after", &acc.DeleteAfter)
 
 	if r.HasErrorsLog("unmarshal account", "") {
 		return &cdb.ErrParseResult{}
@@ -61,3 +75,135 @@ func InsertAccount(acc *accountModel.Account) error {
 
 	return nil
 }
+
+// UpdateAccount updates the given account in the database.
+func UpdateAccount(tx *cdb.Transaction, acc *accountModel.Account,
+	selectors map[string]interface{}) error {
+
+	stmt, err := cdb.PrepareUpdate("account", colNamesAccount, acc, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateAccountTotp stores the two-factor settings of the given account. Unlike UpdateAccount nil
+// values are written as well, so a secret can be cleared.
+func UpdateAccountTotp(acc *accountModel.Account) error {
+	stmt := cdb.Prepare(`
+		update account
+		set totp_secret = :totp_secret:,
+			totp_enabled = :totp_enabled:,
+			totp_last_step = :totp_last_step:
+		where id = :id:
+	`)
+
+	stmt.Bind("id", *acc.ID)
+	stmt.Bind("totp_secret", acc.TotpSecret)
+	stmt.Bind("totp_enabled", acc.TotpEnabled != nil && *acc.TotpEnabled)
+	stmt.Bind("totp_last_step", acc.TotpLastStep)
+
+	_, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// AdvanceTotpStep remembers the time step of the TOTP code the account used. The step only moves
+// forward, an ErrMissingResult is returned when the step was already used, so a code which is
+// entered twice at the same time is only accepted once.
+func AdvanceTotpStep(acc *accountModel.Account, step int64) error {
+	stmt := cdb.Prepare(`
+		update account
+		set totp_last_step = :step:
+		where id = :id:
+			and (totp_last_step is null or totp_last_step < :step:)
+		returning id
+	`)
+
+	stmt.Bind("id", *acc.ID)
+	stmt.Bind("step", step)
+
+	_, err := dao.ExecuteStmt(stmt)
+	return err
+}
+
+// UpdateAccountDeletion stores the scheduled deletion of the given account. Unlike UpdateAccount
+// nil values are written as well, so a deletion can be cancelled.
+func UpdateAccountDeletion(acc *accountModel.Account) error {
+	stmt := cdb.Prepare(`
+		update account
+		set deleted_at = :deleted_at:,
+			delete_after = :delete_after:
+		where id = :id:
+	`)
+
+	stmt.Bind("id", *acc.ID)
+	stmt.Bind("deleted_at", acc.DeletedAt)
+	stmt.Bind("delete_after", acc.DeleteAfter)
+
+	_, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetAccountsToPurge fetches the accounts whose deletion grace period has passed.
+func GetAccountsToPurge() ([]*accountModel.Account, error) {
+	accounts := []*accountModel.Account{}
+
+	stmt := cdb.Prepare(`
+		select id, name, email, password, role,
+			totp_secret, totp_enabled, totp_last_step,
+			deleted_at, delete_after
+		from account
+		where delete_after < now()
+	`)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return accounts, err
+	}
+
+	for _, rowAccount := range rows {
+		acc := accountModel.Account{}
+
+		err = unmarshalAccount(&acc, rowAccount)
+		if err != nil {
+			log.Error(err.Error())
+			return []*accountModel.Account{}, err
+		}
+
+		accounts = append(accounts, &acc)
+	}
+
+	return accounts, nil
+}
+
+// DeleteAccount deletes the given account in the database. Everything which belongs to the account
+// is deleted along with it.
+func DeleteAccount(tx *cdb.Transaction, acc *accountModel.Account) error {
+	stmt := cdb.PrepareDelete("account", colNamesAccount, &accountModel.Account{ID: acc.ID})
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package accountDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+)
+
+var colNamesApiToken = map[string]string{
+	"ID":         "id",
+	"Account":    "account",
+	"Name":       "name",
+	"TokenHash":  "token_hash",
+	"Scopes":     "scopes",
+	"CreatedAt":  "created_at",
+	"ExpiresAt":  "expires_at",
+	"LastUsedAt": "last_used_at",
+}
+
+// unmarshalApiToken parses the database row to the API token object.
+func unmarshalApiToken(token *accountModel.ApiToken, r cdb.CdbResult) error {
+	r.UUID("id", &token.ID)
+	r.UUID("account", &token.Account)
+	r.Str("name", &token.Name)
+	r.StrSlice("scopes", &token.Scopes)
+	r.Time("created_at", &token.CreatedAt)
+	r.Time("expires_at", &token.ExpiresAt)
+	r.OptTime("last_used_at", &token.LastUsedAt)
+
+	if r.HasErrorsLog("unmarshal api token", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetApiToken fetches the API token that matches with the non nil values from the given token.
+func GetApiToken(token *accountModel.ApiToken) error {
+	fields := cdb.CreateFields(colNamesApiToken)
+	stmt := cdb.PrepareSelect("api_token", fields, "t", colNamesApiToken, token)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalApiToken(token, rows[0])
+}
+
+// GetApiTokensByAccount fetches all the API tokens of the given account.
+func GetApiTokensByAccount(acc *accountModel.Account) ([]*accountModel.ApiToken, error) {
+	tokens := []*accountModel.ApiToken{}
+
+	stmt := cdb.Prepare(`
+		select id, account, name, scopes, created_at, expires_at, last_used_at
+		from api_token
+		where account = :account:
+		order by created_at
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return tokens, err
+	}
+
+	for _, rowToken := range rows {
+		token := accountModel.ApiToken{}
+
+		err = unmarshalApiToken(&token, rowToken)
+		if err != nil {
+			log.Error(err.Error())
+			return []*accountModel.ApiToken{}, err
+		}
+
+		tokens = append(tokens, &token)
+	}
+
+	return tokens, nil
+}
+
+// InsertApiToken inserts the API token in the database.
+func InsertApiToken(token *accountModel.ApiToken) error {
+	stmt, err := cdb.PrepareInsert("api_token", colNamesApiToken, token)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// TouchApiToken sets the last used timestamp of the given API token to now.
+func TouchApiToken(token *accountModel.ApiToken) error {
+	stmt := cdb.Prepare(`
+		update api_token
+		set last_used_at = now()
+		where id = :id:
+	`)
+
+	stmt.Bind("id", *token.ID)
+
+	_, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteApiToken deletes the API tokens that match with the non nil values from the given token.
+func DeleteApiToken(token *accountModel.ApiToken) error {
+	stmt := cdb.PrepareDelete("api_token", colNamesApiToken, token)
+	_, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package accountDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+)
+
+var colNamesDataExport = map[string]string{
+	"ID":          "id",
+	"Account":     "account",
+	"Status":      "status",
+	"File":        "file",
+	"CreatedAt":   "created_at",
+	"CompletedAt": "completed_at",
+	"ExpiresAt":   "expires_at",
+}
+
+// unmarshalDataExport parses the database row to the data export object.
+func unmarshalDataExport(export *accountModel.DataExport, r cdb.CdbResult) error {
+	r.UUID("id", &export.ID)
+	r.UUID("account", &export.Account)
+	r.Str("status", &export.Status)
+	r.OptStr("file", &export.File)
+	r.Time("created_at", &export.CreatedAt)
+	r.OptTime("completed_at", &export.CompletedAt)
+	r.Time("expires_at", &export.ExpiresAt)
+
+	if r.HasErrorsLog("unmarshal data export", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetDataExport fetches the data export that matches with the non nil values from the given export.
+func GetDataExport(export *accountModel.DataExport) error {
+	fields := cdb.CreateFields(colNamesDataExport)
+	stmt := cdb.PrepareSelect("data_export", fields, "de", colNamesDataExport, export)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalDataExport(export, rows[0])
+}
+
+// GetExpiredDataExports fetches the data exports which can no longer be downloaded.
+func GetExpiredDataExports() ([]*accountModel.DataExport, error) {
+	exports := []*accountModel.DataExport{}
+
+	stmt := cdb.Prepare(`
+		select id, account, status, file, created_at, completed_at, expires_at
+		from data_export
+		where expires_at < now()
+	`)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return exports, err
+	}
+
+	for _, rowExport := range rows {
+		export := accountModel.DataExport{}
+
+		err = unmarshalDataExport(&export, rowExport)
+		if err != nil {
+			log.Error(err.Error())
+			return []*accountModel.DataExport{}, err
+		}
+
+		exports = append(exports, &export)
+	}
+
+	return exports, nil
+}
+
+// InsertDataExport inserts the data export in the database.
+func InsertDataExport(export *accountModel.DataExport) error {
+	stmt, err := cdb.PrepareInsert("data_export", colNamesDataExport, export)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateDataExport updates the given data export in the database.
+func UpdateDataExport(export *accountModel.DataExport,
+	selectors map[string]interface{}) error {
+
+	stmt, err := cdb.PrepareUpdate("data_export", colNamesDataExport, export, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteDataExport deletes the given data export in the database.
+func DeleteDataExport(export *accountModel.DataExport) error {
+	stmt := cdb.PrepareDelete("data_export", colNamesDataExport, &accountModel.DataExport{ID: export.ID})
+	_, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package accountDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+)
+
+var colNamesEmailChange = map[string]string{
+	"ID":        "id",
+	"Account":   "account",
+	"Email":     "email",
+	"TokenHash": "token_hash",
+	"CreatedAt": "created_at",
+	"ExpiresAt": "expires_at",
+}
+
+// InsertEmailChange inserts the email change in the database.
+func InsertEmailChange(change *accountModel.EmailChange) error {
+	stmt, err := cdb.PrepareInsert("email_change", colNamesEmailChange, change)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeEmailChange deletes the unexpired email change with the given token hash and returns it.
+// Consuming happens in a single statement so a token can never be used twice.
+func ConsumeEmailChange(tokenHash string) (*accountModel.EmailChange, error) {
+	stmt := cdb.Prepare(`
+		delete from email_change
+		where token_hash = :token_hash:
+			and expires_at > now()
+		returning id, account, email
+	`)
+
+	stmt.Bind("token_hash", tokenHash)
+
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	change := accountModel.EmailChange{}
+	rows[0].UUID("id", &change.ID)
+	rows[0].UUID("account", &change.Account)
+	rows[0].Str("email", &change.Email)
+
+	if rows[0].HasErrorsLog("unmarshal email change", "") {
+		return nil, &cdb.ErrParseResult{}
+	}
+
+	return &change, nil
+}
+
+// DeleteEmailChanges deletes all the pending email changes of the given account.
+func DeleteEmailChanges(acc *accountModel.Account) error {
+	change := accountModel.EmailChange{
+		Account: acc.ID,
+	}
+
+	stmt := cdb.PrepareDelete("email_change", colNamesEmailChange, &change)
+	_, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
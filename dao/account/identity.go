@@ -0,0 +1,96 @@
+package accountDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+)
+
+var colNamesIdentity = map[string]string{
+	"ID":        "id",
+	"Account":   "account",
+	"Issuer":    "issuer",
+	"Subject":   "subject",
+	"Email":     "email",
+	"CreatedAt": "created_at",
+}
+
+// unmarshalIdentity parses the database row to the identity object.
+func unmarshalIdentity(identity *accountModel.Identity, r cdb.CdbResult) error {
+	r.UUID("id", &identity.ID)
+	r.UUID("account", &identity.Account)
+	r.Str("issuer", &identity.Issuer)
+	r.Str("subject", &identity.Subject)
+	r.OptStr("email", &identity.Email)
+	r.Time("created_at", &identity.CreatedAt)
+
+	if r.HasErrorsLog("unmarshal identity", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetIdentity fetches the identity that matches with the non nil values from the given identity.
+func GetIdentity(identity *accountModel.Identity) error {
+	fields := cdb.CreateFields(colNamesIdentity)
+	stmt := cdb.PrepareSelect("account_identity", fields, "ai", colNamesIdentity, identity)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalIdentity(identity, rows[0])
+}
+
+// InsertIdentity inserts the identity in the database.
+func InsertIdentity(identity *accountModel.Identity) error {
+	stmt, err := cdb.PrepareInsert("account_identity", colNamesIdentity, identity)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetIdentitiesByAccount fetches all the identities of the given account.
+func GetIdentitiesByAccount(acc *accountModel.Account) ([]*accountModel.Identity, error) {
+	identities := []*accountModel.Identity{}
+
+	stmt := cdb.Prepare(`
+		select id, account, issuer, subject, email, created_at
+		from account_identity
+		where account = :account:
+		order by created_at
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return identities, err
+	}
+
+	for _, rowIdentity := range rows {
+		identity := accountModel.Identity{}
+
+		err = unmarshalIdentity(&identity, rowIdentity)
+		if err != nil {
+			log.Error(err.Error())
+			return []*accountModel.Identity{}, err
+		}
+
+		identities = append(identities, &identity)
+	}
+
+	return identities, nil
+}
@@ -0,0 +1,119 @@
+package accountDao
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+)
+
+var colNamesLoginThrottle = map[string]string{
+	"Key":         "key",
+	"Failures":    "failures",
+	"LastFailure": "last_failure",
+	"LockedUntil": "locked_until",
+}
+
+// unmarshalLoginThrottle parses the database row to the login throttle object.
+func unmarshalLoginThrottle(throttle *accountModel.LoginThrottle, r cdb.CdbResult) error {
+	r.Str("key", &throttle.Key)
+	r.Int32("failures", &throttle.Failures)
+	r.OptTime("last_failure", &throttle.LastFailure)
+	r.OptTime("locked_until", &throttle.LockedUntil)
+
+	if r.HasErrorsLog("unmarshal login throttle", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetLoginThrottle fetches the login throttle that matches with the non nil values from the given
+// login throttle.
+func GetLoginThrottle(throttle *accountModel.LoginThrottle) error {
+	fields := cdb.CreateFields(colNamesLoginThrottle)
+	stmt := cdb.PrepareSelect("login_throttle", fields, "lt", colNamesLoginThrottle, throttle)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalLoginThrottle(throttle, rows[0])
+}
+
+// RecordLoginFailure increments the failed attempts for the given key and returns the new count.
+// Failures older than the given window are forgotten.
+func RecordLoginFailure(key string, window time.Duration) (int32, error) {
+	stmt := cdb.Prepare(`
+		insert into login_throttle (key, failures, last_failure)
+		values (:key:, 1, now())
+		on conflict (key) do update
+		set failures = case
+				when login_throttle.last_failure < :forget_before: then 1
+				else login_throttle.failures + 1
+			end,
+			last_failure = now()
+		returning key, failures, last_failure, locked_until
+	`)
+
+	stmt.Bind("key", key)
+	stmt.Bind("forget_before", time.Now().UTC().Add(-window))
+
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+
+	throttle := accountModel.LoginThrottle{}
+
+	err = unmarshalLoginThrottle(&throttle, rows[0])
+	if err != nil {
+		return 0, err
+	}
+
+	return *throttle.Failures, nil
+}
+
+// LockLogin locks the logins for the given key until the given time.
+func LockLogin(key string, until time.Time) error {
+	throttle := accountModel.LoginThrottle{
+		LockedUntil: &until,
+	}
+
+	selectors := map[string]interface{}{
+		"key": key,
+	}
+
+	stmt, err := cdb.PrepareUpdate("login_throttle", colNamesLoginThrottle, &throttle, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteLoginThrottle clears the failed attempts and the lock of the given key.
+func DeleteLoginThrottle(tx *cdb.Transaction, key string) error {
+	throttle := accountModel.LoginThrottle{
+		Key: &key,
+	}
+
+	stmt := cdb.PrepareDelete("login_throttle", colNamesLoginThrottle, &throttle)
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package accountDao
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+var colNamesPasswordReset = map[string]string{
+	"ID":        "id",
+	"Account":   "account",
+	"TokenHash": "token_hash",
+	"CreatedAt": "created_at",
+	"ExpiresAt": "expires_at",
+	"UsedAt":    "used_at",
+}
+
+// InsertPasswordReset inserts the password reset in the database.
+func InsertPasswordReset(reset *accountModel.PasswordReset) error {
+	stmt, err := cdb.PrepareInsert("password_reset", colNamesPasswordReset, reset)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetPasswordReset returns the account of the unused and unexpired password reset with the given
+// token hash.
+func GetPasswordReset(tokenHash string) (uuid.UUID, error) {
+	stmt := cdb.Prepare(`
+		select account
+		from password_reset
+		where token_hash = :token_hash:
+			and used_at is null
+			and expires_at > now()
+	`)
+
+	stmt.Bind("token_hash", tokenHash)
+
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	reset := accountModel.PasswordReset{}
+	rows[0].UUID("account", &reset.Account)
+
+	if rows[0].HasErrorsLog("unmarshal password reset", "") {
+		return uuid.UUID{}, &cdb.ErrParseResult{}
+	}
+
+	return *reset.Account, nil
+}
+
+// ConsumePasswordReset marks the unused and unexpired password reset with the given token hash as
+// used at the given time. The transaction is rolled back when it wasn't this transaction that
+// marked it, so a token can never be used twice.
+func ConsumePasswordReset(tx *cdb.Transaction, tokenHash string, usedAt time.Time) error {
+	stmt := cdb.Prepare(`
+		update password_reset
+		set used_at = :used_at:
+		where token_hash = :token_hash:
+			and used_at is null
+			and expires_at > now()
+	`)
+
+	stmt.Bind("token_hash", tokenHash)
+	stmt.Bind("used_at", usedAt)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	guard := cdb.PrepareGuard(`
+		select exists (
+			select 1
+			from password_reset
+			where token_hash = :token_hash: and used_at = :used_at:
+		)
+	`)
+
+	guard.Bind("token_hash", tokenHash)
+	guard.Bind("used_at", usedAt)
+
+	_, err = cdb.ExecTx(tx, &guard)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeletePasswordResets deletes all the password resets of the given account.
+func DeletePasswordResets(tx *cdb.Transaction, acc *accountModel.Account) error {
+	reset := accountModel.PasswordReset{
+		Account: acc.ID,
+	}
+
+	stmt := cdb.PrepareDelete("password_reset", colNamesPasswordReset, &reset)
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
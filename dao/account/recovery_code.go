@@ -0,0 +1,68 @@
+package accountDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+)
+
+var colNamesRecoveryCode = map[string]string{
+	"ID":        "id",
+	"Account":   "account",
+	"CodeHash":  "code_hash",
+	"CreatedAt": "created_at",
+	"UsedAt":    "used_at",
+}
+
+// InsertRecoveryCode inserts the recovery code in the database.
+func InsertRecoveryCode(code *accountModel.RecoveryCode) error {
+	stmt, err := cdb.PrepareInsert("recovery_code", colNamesRecoveryCode, code)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeRecoveryCode marks the unused recovery code of the account with the given hash as used. An
+// ErrMissingResult is returned when there is no such code.
+func ConsumeRecoveryCode(acc *accountModel.Account, codeHash string) error {
+	stmt := cdb.Prepare(`
+		update recovery_code
+		set used_at = now()
+		where account = :account:
+			and code_hash = :code_hash:
+			and used_at is null
+		returning id
+	`)
+
+	stmt.Bind("account", *acc.ID)
+	stmt.Bind("code_hash", codeHash)
+
+	_, err := dao.ExecuteStmt(stmt)
+	return err
+}
+
+// DeleteRecoveryCodes deletes all the recovery codes of the given account.
+func DeleteRecoveryCodes(acc *accountModel.Account) error {
+	code := accountModel.RecoveryCode{
+		Account: acc.ID,
+	}
+
+	stmt := cdb.PrepareDelete("recovery_code", colNamesRecoveryCode, &code)
+	_, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
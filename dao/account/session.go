@@ -0,0 +1,129 @@
+package accountDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+var colNamesSession = map[string]string{
+	"ID":        "id",
+	"Account":   "account",
+	"IP":        "ip",
+	"UserAgent": "user_agent",
+	"CreatedAt": "created_at",
+	"ExpiresAt": "expires_at",
+}
+
+// unmarshalSession parses the database row to the session object.
+func unmarshalSession(session *accountModel.Session, r cdb.CdbResult) error {
+	r.UUID("id", &session.ID)
+	r.UUID("account", &session.Account)
+	r.OptStr("ip", &session.IP)
+	r.OptStr("user_agent", &session.UserAgent)
+	r.Time("created_at", &session.CreatedAt)
+	r.Time("expires_at", &session.ExpiresAt)
+
+	if r.HasErrorsLog("unmarshal session", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetSession fetches the session that matches with the non nil values from the given session.
+func GetSession(session *accountModel.Session) error {
+	fields := cdb.CreateFields(colNamesSession)
+	stmt := cdb.PrepareSelect("session", fields, "s", colNamesSession, session)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalSession(session, rows[0])
+}
+
+// GetSessionsByAccount fetches all the sessions of the given account.
+func GetSessionsByAccount(acc *accountModel.Account) ([]*accountModel.Session, error) {
+	sessions := []*accountModel.Session{}
+
+	stmt := cdb.Prepare(`
+		select id, account, ip, user_agent, created_at, expires_at
+		from session
+		where account = :account:
+		order by created_at
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return sessions, err
+	}
+
+	for _, rowSession := range rows {
+		session := accountModel.Session{}
+
+		err = unmarshalSession(&session, rowSession)
+		if err != nil {
+			log.Error(err.Error())
+			return []*accountModel.Session{}, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, nil
+}
+
+// InsertSession inserts the session in the database.
+func InsertSession(session *accountModel.Session) error {
+	stmt, err := cdb.PrepareInsert("session", colNamesSession, session)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteSession deletes the sessions that match with the non nil values from the given session.
+func DeleteSession(tx *cdb.Transaction, session *accountModel.Session) error {
+	stmt := cdb.PrepareDelete("session", colNamesSession, session)
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteOtherSessions deletes all the sessions of the given account except the one to keep.
+func DeleteOtherSessions(acc *accountModel.Account, keep uuid.UUID) error {
+	stmt := cdb.Prepare(`
+		delete from session
+		where account = :account:
+			and id <> :keep:
+	`)
+
+	stmt.Bind("account", *acc.ID)
+	stmt.Bind("keep", keep)
+
+	_, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package auditDao
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+var colNamesAuditEntry = map[string]string{
+	"ID":         "id",
+	"Actor":      "actor",
+	"Action":     "action",
+	"EntityType": "entity_type",
+	"EntityID":   "entity_id",
+	"Before":     "before",
+	"After":      "after",
+	"IP":         "ip",
+	"RequestID":  "request_id",
+	"CreatedAt":  "created_at",
+}
+
+// Filter selects audit entries, nil fields match everything.
+type Filter struct {
+	Actor      *uuid.UUID
+	Action     *string
+	EntityType *string
+	EntityID   *uuid.UUID
+	From       *time.Time
+	To         *time.Time
+
+	// AfterTime and AfterID are the last entry of the previous page.
+	AfterTime *time.Time
+	AfterID   *uuid.UUID
+
+	Limit int32
+}
+
+// unmarshalAuditEntry parses the database row to the audit entry object.
+func unmarshalAuditEntry(entry *auditModel.AuditEntry, r cdb.CdbResult) error {
+	r.UUID("id", &entry.ID)
+	r.OptUUID("actor", &entry.Actor)
+	r.Str("action", &entry.Action)
+	r.Str("entity_type", &entry.EntityType)
+	r.OptUUID("entity_id", &entry.EntityID)
+	r.OptMapStrInterface("before", &entry.Before)
+	r.OptMapStrInterface("after", &entry.After)
+	r.OptStr("ip", &entry.IP)
+	r.OptStr("request_id", &entry.RequestID)
+	r.Time("created_at", &entry.CreatedAt)
+
+	if r.HasErrorsLog("unmarshal audit entry", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetAuditEntries fetches a page of the audit entries which match the filter, newest first.
+func GetAuditEntries(filter Filter) ([]*auditModel.AuditEntry, error) {
+	entries := []*auditModel.AuditEntry{}
+
+	query := `
+		select id, actor, action, entity_type, entity_id,
+			before, after, ip, request_id, created_at
+		from audit_entry
+		where true
+	`
+
+	params := map[string]interface{}{}
+
+	if filter.Actor != nil {
+		query += " and actor = :actor:"
+		params["actor"] = *filter.Actor
+	}
+
+	if filter.Action != nil {
+		query += " and action = :action:"
+		params["action"] = *filter.Action
+	}
+
+	if filter.EntityType != nil {
+		query += " and entity_type = :entity_type:"
+		params["entity_type"] = *filter.EntityType
+	}
+
+	if filter.EntityID != nil {
+		query += " and entity_id = :entity_id:"
+		params["entity_id"] = *filter.EntityID
+	}
+
+	if filter.From != nil {
+		query += " and created_at >= :from:"
+		params["from"] = *filter.From
+	}
+
+	if filter.To != nil {
+		query += " and created_at < :to:"
+		params["to"] = *filter.To
+	}
+
+	if filter.AfterTime != nil && filter.AfterID != nil {
+		query += " and (created_at, id) < (:after_time:, :after_id:)"
+		params["after_time"] = *filter.AfterTime
+		params["after_id"] = *filter.AfterID
+	}
+
+	query += `
+		order by created_at desc, id desc
+		limit :limit:
+	`
+	params["limit"] = filter.Limit
+
+	stmt := cdb.Prepare(query)
+	stmt.BindMap(params)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return entries, err
+	}
+
+	for _, rowEntry := range rows {
+		entry := auditModel.AuditEntry{}
+
+		err = unmarshalAuditEntry(&entry, rowEntry)
+		if err != nil {
+			log.Error(err.Error())
+			return []*auditModel.AuditEntry{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// GetAuditEntriesByActor fetches all the audit entries of changes made by the given account.
+func GetAuditEntriesByActor(actor uuid.UUID) ([]*auditModel.AuditEntry, error) {
+	entries := []*auditModel.AuditEntry{}
+
+	stmt := cdb.Prepare(`
+		select id, actor, action, entity_type, entity_id,
+			before, after, ip, request_id, created_at
+		from audit_entry
+		where actor = :actor:
+		order by created_at
+	`)
+
+	stmt.Bind("actor", actor)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return entries, err
+	}
+
+	for _, rowEntry := range rows {
+		entry := auditModel.AuditEntry{}
+
+		err = unmarshalAuditEntry(&entry, rowEntry)
+		if err != nil {
+			log.Error(err.Error())
+			return []*auditModel.AuditEntry{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// InsertAuditEntry inserts the audit entry in the database.
+func InsertAuditEntry(tx *cdb.Transaction, entry *auditModel.AuditEntry) error {
+	stmt, err := cdb.PrepareInsert("audit_entry", colNamesAuditEntry, entry)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
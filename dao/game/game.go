@@ -4,7 +4,9 @@ import (
 	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
 	"github.com/marvindeckmyn/drankspelletjes-server/dao"
 	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
 	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
 )
 
 var colNamesGame = map[string]string{
@@ -17,7 +19,11 @@ var colNamesGame = map[string]string{
 	"Description":  "description",
 	"Highlight":    "highlight",
 	"Order":        `"order"`,
+	"CreatedBy":    "created_by",
 	"CreatedAt":    "created_at",
+
+	"RatingCount":   "rating_count",
+	"RatingAverage": "rating_average",
 }
 
 // unmarshalGame parses the database row to the game object.
@@ -31,6 +37,10 @@ func unmarshalGame(game *gameModel.Game, r cdb.CdbResult) error {
 	r.MapStrStr("description", &game.Description)
 	r.Bool("highlight", &game.Highlight)
 	r.Int32("order", &game.Order)
+	r.OptUUID("created_by", &game.CreatedBy)
+	r.Time("created_at", &game.CreatedAt)
+	r.Int32("rating_count", &game.RatingCount)
+	r.Float64("rating_average", &game.RatingAverage)
 
 	if r.HasErrorsLog("unmarshal game", "") {
 		return &cdb.ErrParseResult{}
@@ -39,17 +49,20 @@ func unmarshalGame(game *gameModel.Game, r cdb.CdbResult) error {
 	return nil
 }
 
-// GetGamesByCategory fetches all the games by category.
+// GetGamesByCategory fetches all the games which are linked to the category, in the order of the
+// category. The order of the games is their place in the category.
 func GetGamesByCategory(category *gameModel.GameCategory) ([]*gameModel.Game, error) {
 	games := []*gameModel.Game{}
 
 	stmt := cdb.Prepare(`
-		select id, game_category, name, alias,
-			player_count, img,
-			description, highlight, "order"
+		select game.id, game.game_category, game.name, game.alias,
+			game.player_count, game.img,
+			game.description, game.highlight, gcl."order", game.created_by, game.created_at,
+			game.rating_count, game.rating_average
 		from game
-		where game_category = :category:
-		order by "order"
+		join game_category_link gcl on gcl.game = game.id
+		where gcl.category = :category:
+		order by gcl."order", game.id
 	`)
 
 	stmt.Bind("category", *category.ID)
@@ -75,13 +88,93 @@ func GetGamesByCategory(category *gameModel.GameCategory) ([]*gameModel.Game, er
 	return games, err
 }
 
+// GetGames fetches all the games, optionally only the ones linked to the given category. Within a
+// category the games are in the order of the category and their order is their place in it.
+func GetGames(category *uuid.UUID) ([]*gameModel.Game, error) {
+	games := []*gameModel.Game{}
+
+	stmt := cdb.Prepare(`
+		select game.id, game.game_category, game.name, game.alias,
+			game.player_count, game.img,
+			game.description, game.highlight, coalesce(gcl."order", game."order") as "order",
+			game.created_by, game.created_at,
+			game.rating_count, game.rating_average
+		from game
+		left join game_category_link gcl on gcl.game = game.id and gcl.category = :category:
+		where cast(:category: as uuid) is null or gcl.game is not null
+		order by coalesce(gcl."order", game."order"), game.id
+	`)
+
+	if category != nil {
+		stmt.Bind("category", *category)
+	} else {
+		stmt.Bind("category", nil)
+	}
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return games, err
+	}
+
+	for _, rowGame := range rows {
+		game := gameModel.Game{}
+
+		err = unmarshalGame(&game, rowGame)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.Game{}, err
+		}
+
+		games = append(games, &game)
+	}
+
+	return games, nil
+}
+
+// GetGamesByAuthor fetches all the games which were created by the given account.
+func GetGamesByAuthor(acc *accountModel.Account) ([]*gameModel.Game, error) {
+	games := []*gameModel.Game{}
+
+	stmt := cdb.Prepare(`
+		select id, game_category, name, alias,
+			player_count, img,
+			description, highlight, "order", created_by, created_at,
+			rating_count, rating_average
+		from game
+		where created_by = :account:
+		order by created_at
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return games, err
+	}
+
+	for _, rowGame := range rows {
+		game := gameModel.Game{}
+
+		err = unmarshalGame(&game, rowGame)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.Game{}, err
+		}
+
+		games = append(games, &game)
+	}
+
+	return games, nil
+}
+
 // GetGame fetches the game that matches with the non nil values from the given game.
 func GetGame(game *gameModel.Game) error {
 	fields := cdb.CreateFields(colNamesGame)
 	stmt := cdb.PrepareSelect("game", fields, "game", colNamesGame, game)
-	rows, err := cdb.Exec(&stmt)
+	rows, err := dao.ExecuteStmt(stmt)
 	if err != nil {
-		log.Error(err.Error())
 		return err
 	}
 
@@ -89,14 +182,14 @@ func GetGame(game *gameModel.Game) error {
 }
 
 // InsertGame inserts the game in the database
-func InsertGame(game *gameModel.Game) error {
+func InsertGame(tx *cdb.Transaction, game *gameModel.Game) error {
 	stmt, err := cdb.PrepareInsert("game", colNamesGame, game)
 	if err != nil {
 		log.Error(err.Error())
 		return err
 	}
 
-	_, err = cdb.Exec(&stmt)
+	_, err = cdb.ExecTx(tx, &stmt)
 	if err != nil {
 		log.Error(err.Error())
 		return err
@@ -106,16 +199,21 @@ func InsertGame(game *gameModel.Game) error {
 }
 
 // UpdateGame updates the given game in the database.
-func UpdateGame(game *gameModel.Game,
+func UpdateGame(tx *cdb.Transaction, game *gameModel.Game,
 	selectors map[string]interface{}) error {
 
-	stmt, err := cdb.PrepareUpdate("game", colNamesGame, game, selectors)
+	// The aggregates are only changed by the reviews, so a stale copy doesn't overwrite them
+	update := *game
+	update.RatingCount = nil
+	update.RatingAverage = nil
+
+	stmt, err := cdb.PrepareUpdate("game", colNamesGame, &update, selectors)
 	if err != nil {
 		log.Error(err.Error())
 		return err
 	}
 
-	_, err = cdb.Exec(&stmt)
+	_, err = cdb.ExecTx(tx, &stmt)
 	if err != nil {
 		log.Error(err.Error())
 		return err
@@ -124,12 +222,31 @@ func UpdateGame(game *gameModel.Game,
 }
 
 // DeleteGame deletes the given game in the database.
-func DeleteGame(game *gameModel.Game) error {
-	stmt := cdb.PrepareDelete("game", colNamesGame, game)
-	_, err := cdb.Exec(&stmt)
+func DeleteGame(tx *cdb.Transaction, game *gameModel.Game) error {
+	stmt := cdb.PrepareDelete("game", colNamesGame, &gameModel.Game{ID: game.ID})
+	_, err := cdb.ExecTx(tx, &stmt)
 	if err != nil {
 		log.Error(err.Error())
 		return err
 	}
 	return nil
 }
+
+// AnonymizeGames removes the given account as the author of its games.
+func AnonymizeGames(tx *cdb.Transaction, acc *accountModel.Account) error {
+	stmt := cdb.Prepare(`
+		update game
+		set created_by = null
+		where created_by = :account:
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
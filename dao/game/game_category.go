@@ -5,6 +5,7 @@ import (
 	"github.com/marvindeckmyn/drankspelletjes-server/dao"
 	"github.com/marvindeckmyn/drankspelletjes-server/log"
 	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
 )
 
 var colNamesCategory = map[string]string{
@@ -61,7 +62,7 @@ func GetCategories() ([]*gameModel.GameCategory, error) {
 func GetCategory(category *gameModel.GameCategory) error {
 	fields := cdb.CreateFields(colNamesCategory)
 	stmt := cdb.PrepareSelect("game_category", fields, "gc", colNamesCategory, category)
-	rows, err := cdb.Exec(&stmt)
+	rows, err := dao.ExecuteStmt(stmt)
 	if err != nil {
 		log.Error(err.Error())
 		return err
@@ -71,14 +72,14 @@ func GetCategory(category *gameModel.GameCategory) error {
 }
 
 // InsertCategory inserts the category in the database
-func InsertCategory(category *gameModel.GameCategory) error {
+func InsertCategory(tx *cdb.Transaction, category *gameModel.GameCategory) error {
 	stmt, err := cdb.PrepareInsert("game_category", colNamesCategory, category)
 	if err != nil {
 		log.Error(err.Error())
 		return err
 	}
 
-	_, err = cdb.Exec(&stmt)
+	_, err = cdb.ExecTx(tx, &stmt)
 	if err != nil {
 		log.Error(err.Error())
 		return err
@@ -87,7 +88,7 @@ func InsertCategory(category *gameModel.GameCategory) error {
 }
 
 // UpdateCategory updates the given category in the database.
-func UpdateCategory(category *gameModel.GameCategory,
+func UpdateCategory(tx *cdb.Transaction, category *gameModel.GameCategory,
 	selectors map[string]interface{}) error {
 
 	stmt, err := cdb.PrepareUpdate("game_category", colNamesCategory, category, selectors)
@@ -96,7 +97,7 @@ func UpdateCategory(category *gameModel.GameCategory,
 		return err
 	}
 
-	_, err = cdb.Exec(&stmt)
+	_, err = cdb.ExecTx(tx, &stmt)
 	if err != nil {
 		log.Error(err.Error())
 		return err
@@ -105,12 +106,54 @@ func UpdateCategory(category *gameModel.GameCategory,
 }
 
 // DeleteCategory deletes the given category in the database.
-func DeleteCategory(category *gameModel.GameCategory) error {
+func DeleteCategory(tx *cdb.Transaction, category *gameModel.GameCategory) error {
 	stmt := cdb.PrepareDelete("game_category", colNamesCategory, category)
-	_, err := cdb.Exec(&stmt)
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// GuardCategoryIDs rolls the transaction back when the categories aren't exactly the given ones
+// anymore.
+func GuardCategoryIDs(tx *cdb.Transaction, ids []uuid.UUID) error {
+	guard := cdb.PrepareGuard(`
+		select count(*) = cardinality(cast(:ids: as uuid[]))
+			and count(*) filter (where id = any(cast(:ids: as uuid[]))) = count(*)
+		from game_category
+	`)
+
+	guard.Bind("ids", idStrings(ids))
+
+	_, err := cdb.ExecTx(tx, &guard)
 	if err != nil {
 		log.Error(err.Error())
 		return err
 	}
+
+	return nil
+}
+
+// GuardCategoryGames rolls the transaction back when the games of the category aren't exactly the
+// given ones anymore.
+func GuardCategoryGames(tx *cdb.Transaction, category *gameModel.GameCategory, ids []uuid.UUID) error {
+	guard := cdb.PrepareGuard(`
+		select count(*) = cardinality(cast(:ids: as uuid[]))
+			and count(*) filter (where game = any(cast(:ids: as uuid[]))) = count(*)
+		from game_category_link
+		where category = :category:
+	`)
+
+	guard.Bind("category", *category.ID)
+	guard.Bind("ids", idStrings(ids))
+
+	_, err := cdb.ExecTx(tx, &guard)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
 	return nil
 }
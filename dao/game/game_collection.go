@@ -0,0 +1,339 @@
+package gameDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+var colNamesFavorite = map[string]string{
+	"Account":   "account",
+	"Game":      "game",
+	"CreatedAt": "created_at",
+}
+
+var colNamesCollection = map[string]string{
+	"ID":         "id",
+	"Account":    "account",
+	"Name":       "name",
+	"ShareToken": "share_token",
+	"CreatedAt":  "created_at",
+	"UpdatedAt":  "updated_at",
+}
+
+var colNamesCollectionItem = map[string]string{
+	"Collection": "collection",
+	"Game":       "game",
+	"Order":      `"order"`,
+}
+
+// unmarshalCollection parses the database row to the collection object.
+func unmarshalCollection(collection *gameModel.GameCollection, r cdb.CdbResult) error {
+	r.UUID("id", &collection.ID)
+	r.UUID("account", &collection.Account)
+	r.Str("name", &collection.Name)
+	r.Str("share_token", &collection.ShareToken)
+	r.Time("created_at", &collection.CreatedAt)
+	r.Time("updated_at", &collection.UpdatedAt)
+	r.OptInt32("game_count", &collection.GameCount)
+
+	if r.HasErrorsLog("unmarshal collection", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// queryGames fetches the games of the statement.
+func queryGames(stmt cdb.Statement) ([]*gameModel.Game, error) {
+	games := []*gameModel.Game{}
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return games, err
+	}
+
+	for _, rowGame := range rows {
+		game := gameModel.Game{}
+
+		err = unmarshalGame(&game, rowGame)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.Game{}, err
+		}
+
+		games = append(games, &game)
+	}
+
+	return games, nil
+}
+
+// GetFavoriteGames fetches the favorite games of the account, the most recent favorite first.
+func GetFavoriteGames(acc *accountModel.Account) ([]*gameModel.Game, error) {
+	stmt := cdb.Prepare(`
+		select g.id, g.game_category, g.name, g.alias,
+			g.player_count, g.img,
+			g.description, g.highlight, g."order", g.created_by, g.created_at,
+			g.rating_count, g.rating_average
+		from game_favorite gf
+		join game g on g.id = gf.game
+		where gf.account = :account:
+		order by gf.created_at desc, g.id
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	return queryGames(stmt)
+}
+
+// GetFavoriteIDs fetches which of the given games are favorites of the account.
+func GetFavoriteIDs(acc *accountModel.Account, games []*gameModel.Game) (map[uuid.UUID]bool, error) {
+	favorites := map[uuid.UUID]bool{}
+
+	if len(games) == 0 {
+		return favorites, nil
+	}
+
+	stmt := cdb.Prepare(`
+		select game
+		from game_favorite
+		where account = :account: and game = any(cast(:games: as uuid[]))
+	`)
+
+	stmt.Bind("account", *acc.ID)
+	stmt.Bind("games", gameIDs(games))
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return favorites, err
+	}
+
+	for _, row := range rows {
+		var game *uuid.UUID
+
+		row.UUID("game", &game)
+
+		if row.HasErrorsLog("unmarshal favorite", "") {
+			return map[uuid.UUID]bool{}, &cdb.ErrParseResult{}
+		}
+
+		favorites[*game] = true
+	}
+
+	return favorites, nil
+}
+
+// InsertFavorite marks the game as a favorite of the account, nothing changes when it already is.
+func InsertFavorite(tx *cdb.Transaction, favorite *gameModel.GameFavorite) error {
+	stmt := cdb.Prepare(`
+		insert into game_favorite (account, game)
+		values (:account:, :game:)
+		on conflict do nothing
+	`)
+
+	stmt.BindObject(colNamesFavorite, "", favorite)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteFavorite removes the game from the favorites of the account.
+func DeleteFavorite(tx *cdb.Transaction, favorite *gameModel.GameFavorite) error {
+	stmt := cdb.PrepareDelete("game_favorite", colNamesFavorite, &gameModel.GameFavorite{
+		Account: favorite.Account,
+		Game:    favorite.Game,
+	})
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetCollection fetches the collection that matches with the non nil values from the given
+// collection.
+func GetCollection(collection *gameModel.GameCollection) error {
+	fields := cdb.CreateFields(colNamesCollection)
+	stmt := cdb.PrepareSelect("game_collection", fields, "gc", colNamesCollection, collection)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalCollection(collection, rows[0])
+}
+
+// GetCollectionsByAccount fetches the collections of the account together with how many games they
+// hold, the most recently changed first.
+func GetCollectionsByAccount(acc *accountModel.Account) ([]*gameModel.GameCollection, error) {
+	collections := []*gameModel.GameCollection{}
+
+	stmt := cdb.Prepare(`
+		select gc.id, gc.account, gc.name, gc.share_token, gc.created_at, gc.updated_at,
+			(select count(*) from game_collection_item gci where gci.collection = gc.id) as game_count
+		from game_collection gc
+		where gc.account = :account:
+		order by gc.updated_at desc, gc.id
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return collections, err
+	}
+
+	for _, rowCollection := range rows {
+		collection := gameModel.GameCollection{}
+
+		err = unmarshalCollection(&collection, rowCollection)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.GameCollection{}, err
+		}
+
+		collections = append(collections, &collection)
+	}
+
+	return collections, nil
+}
+
+// InsertCollection inserts the collection in the database.
+func InsertCollection(tx *cdb.Transaction, collection *gameModel.GameCollection) error {
+	stmt, err := cdb.PrepareInsert("game_collection", colNamesCollection, collection)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateCollection updates the given collection in the database.
+func UpdateCollection(tx *cdb.Transaction, collection *gameModel.GameCollection,
+	selectors map[string]interface{}) error {
+
+	stmt, err := cdb.PrepareUpdate("game_collection", colNamesCollection, collection, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCollection deletes the given collection in the database, its items go along with it.
+func DeleteCollection(tx *cdb.Transaction, collection *gameModel.GameCollection) error {
+	stmt := cdb.PrepareDelete("game_collection", colNamesCollection,
+		&gameModel.GameCollection{ID: collection.ID})
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetCollectionGames fetches the games of the collection in the order of the collection.
+func GetCollectionGames(collection *gameModel.GameCollection) ([]*gameModel.Game, error) {
+	stmt := cdb.Prepare(`
+		select g.id, g.game_category, g.name, g.alias,
+			g.player_count, g.img,
+			g.description, g.highlight, g."order", g.created_by, g.created_at,
+			g.rating_count, g.rating_average
+		from game_collection_item gci
+		join game g on g.id = gci.game
+		where gci.collection = :collection:
+		order by gci."order", g.id
+	`)
+
+	stmt.Bind("collection", *collection.ID)
+
+	return queryGames(stmt)
+}
+
+// InsertCollectionItem adds the game at the end of the collection, nothing changes when the game
+// already is in it.
+func InsertCollectionItem(tx *cdb.Transaction, item *gameModel.GameCollectionItem) error {
+	stmt := cdb.Prepare(`
+		insert into game_collection_item (collection, game, "order")
+		select :collection:, :game:, coalesce(max("order"), 0) + 1
+		from game_collection_item
+		where collection = :collection:
+		on conflict do nothing
+	`)
+
+	stmt.BindObject(colNamesCollectionItem, "", item)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateCollectionItem updates the given collection item in the database.
+func UpdateCollectionItem(tx *cdb.Transaction, item *gameModel.GameCollectionItem,
+	selectors map[string]interface{}) error {
+
+	stmt, err := cdb.PrepareUpdate("game_collection_item", colNamesCollectionItem, item, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCollectionItem removes the game from the collection.
+func DeleteCollectionItem(tx *cdb.Transaction, item *gameModel.GameCollectionItem) error {
+	stmt := cdb.PrepareDelete("game_collection_item", colNamesCollectionItem,
+		&gameModel.GameCollectionItem{
+			Collection: item.Collection,
+			Game:       item.Game,
+		})
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
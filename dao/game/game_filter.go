@@ -0,0 +1,165 @@
+package gameDao
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// SortColumnsGame maps the fields games can be sorted on to their columns.
+var SortColumnsGame = map[string]string{
+	"order":        `"order"`,
+	"player_count": "player_count",
+	"created_at":   "created_at",
+	"rating":       "rating_average",
+}
+
+// GameFilter selects games, nil fields match everything. Games match a category when they are
+// linked to it, their order is then their place in the category. Games match the tags when they
+// have all of them.
+type GameFilter struct {
+	Category       *uuid.UUID
+	Tags           []uuid.UUID
+	MinPlayers     *int32
+	MaxPlayers     *int32
+	Highlight      *bool
+	HasNecessities *bool
+	CreatedAfter   *time.Time
+
+	// Sort is a key of SortColumnsGame, Desc reverses the order.
+	Sort string
+	Desc bool
+
+	// AfterValue and AfterID are the sort value and ID of the last game of the previous page.
+	AfterValue interface{}
+	AfterID    *uuid.UUID
+
+	// Limit is the size of the page, all the matching games are fetched when it's 0.
+	Limit int32
+}
+
+// GetGamesFiltered fetches a page of the games which match the filter. Games with the same sort
+// value are ordered by ID, so pages stay stable.
+func GetGamesFiltered(filter GameFilter) ([]*gameModel.Game, error) {
+	games := []*gameModel.Game{}
+
+	column, ok := SortColumnsGame[filter.Sort]
+	if !ok {
+		return games, &cdb.ErrNoSuchKey{Key: filter.Sort}
+	}
+
+	// Within a category the games are in the order of the category
+	order := `game."order"`
+	join := ""
+	params := map[string]interface{}{}
+
+	if filter.Category != nil {
+		order = `gcl."order"`
+		join = "join game_category_link gcl on gcl.game = game.id and gcl.category = :category:"
+		params["category"] = *filter.Category
+	}
+
+	if filter.Sort == "order" {
+		column = order
+	}
+
+	query := fmt.Sprintf(`
+		select game.id, game.game_category, game.name, game.alias,
+			game.player_count, game.img,
+			game.description, game.highlight, %s as "order", game.created_by, game.created_at,
+			game.rating_count, game.rating_average
+		from game
+		%s
+		where true
+	`, order, join)
+
+	// Games need all the tags
+	if len(filter.Tags) > 0 {
+		tags := []string{}
+		for _, tag := range filter.Tags {
+			tags = append(tags, tag.String())
+		}
+
+		query += `
+			and (
+				select count(*) from game_tag_link gtl
+				where gtl.game = game.id and gtl.tag = any(cast(:tags: as uuid[]))
+			) = cardinality(cast(:tags: as uuid[]))
+		`
+		params["tags"] = tags
+	}
+
+	if filter.MinPlayers != nil {
+		query += " and player_count >= :min_players:"
+		params["min_players"] = *filter.MinPlayers
+	}
+
+	if filter.MaxPlayers != nil {
+		query += " and player_count <= :max_players:"
+		params["max_players"] = *filter.MaxPlayers
+	}
+
+	if filter.Highlight != nil {
+		query += " and highlight = :highlight:"
+		params["highlight"] = *filter.Highlight
+	}
+
+	if filter.HasNecessities != nil {
+		exists := "exists"
+		if !*filter.HasNecessities {
+			exists = "not exists"
+		}
+
+		query += fmt.Sprintf(" and %s (select 1 from game_necessity gn where gn.game = game.id)", exists)
+	}
+
+	if filter.CreatedAfter != nil {
+		query += " and game.created_at > :created_after:"
+		params["created_after"] = *filter.CreatedAfter
+	}
+
+	direction, comparison := "asc", ">"
+	if filter.Desc {
+		direction, comparison = "desc", "<"
+	}
+
+	if filter.AfterValue != nil && filter.AfterID != nil {
+		query += fmt.Sprintf(" and (%s, game.id) %s (:after_value:, :after_id:)", column, comparison)
+		params["after_value"] = filter.AfterValue
+		params["after_id"] = *filter.AfterID
+	}
+
+	query += fmt.Sprintf(" order by %s %s, game.id %s", column, direction, direction)
+
+	if filter.Limit > 0 {
+		query += " limit :limit:"
+		params["limit"] = filter.Limit
+	}
+
+	stmt := cdb.Prepare(query)
+	stmt.BindMap(params)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return games, err
+	}
+
+	for _, rowGame := range rows {
+		game := gameModel.Game{}
+
+		err = unmarshalGame(&game, rowGame)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.Game{}, err
+		}
+
+		games = append(games, &game)
+	}
+
+	return games, nil
+}
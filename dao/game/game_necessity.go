@@ -2,8 +2,10 @@ package gameDao
 
 import (
 	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
 	"github.com/marvindeckmyn/drankspelletjes-server/log"
 	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
 )
 
 var colNamesGameNecessity = map[string]string{
@@ -12,15 +14,134 @@ var colNamesGameNecessity = map[string]string{
 	"Name": "name",
 }
 
+// unmarshalNecessity parses the database row to the game necessity object.
+func unmarshalNecessity(necessity *gameModel.GameNecessity, r cdb.CdbResult) error {
+	r.UUID("id", &necessity.ID)
+	r.UUID("game", &necessity.Game)
+	r.MapStrStr("name", &necessity.Name)
+
+	if r.HasErrorsLog("unmarshal game necessity", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetNecessity fetches the game necessity that matches with the non nil values from the given
+// game necessity.
+func GetNecessity(necessity *gameModel.GameNecessity) error {
+	fields := cdb.CreateFields(colNamesGameNecessity)
+	stmt := cdb.PrepareSelect("game_necessity", fields, "gn", colNamesGameNecessity, necessity)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalNecessity(necessity, rows[0])
+}
+
+// GetNecessitiesByGames fetches the necessities of all the given games in a single query. The
+// necessities are grouped by the ID of their game.
+func GetNecessitiesByGames(games []*gameModel.Game) (map[uuid.UUID][]*gameModel.GameNecessity, error) {
+	necessities := map[uuid.UUID][]*gameModel.GameNecessity{}
+
+	if len(games) == 0 {
+		return necessities, nil
+	}
+
+	ids := []string{}
+	for _, game := range games {
+		ids = append(ids, game.ID.String())
+	}
+
+	stmt := cdb.Prepare(`
+		select id, game, name
+		from game_necessity
+		where game = any(cast(:games: as uuid[]))
+		order by game, id
+	`)
+
+	stmt.Bind("games", ids)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return necessities, err
+	}
+
+	for _, rowNecessity := range rows {
+		necessity := gameModel.GameNecessity{}
+
+		err = unmarshalNecessity(&necessity, rowNecessity)
+		if err != nil {
+			log.Error(err.Error())
+			return map[uuid.UUID][]*gameModel.GameNecessity{}, err
+		}
+
+		necessities[*necessity.Game] = append(necessities[*necessity.Game], &necessity)
+	}
+
+	return necessities, nil
+}
+
 // InsertNecessity inserts the game necessity in the database.
-func InsertNecessity(necessity *gameModel.GameNecessity) error {
+func InsertNecessity(tx *cdb.Transaction, necessity *gameModel.GameNecessity) error {
 	stmt, err := cdb.PrepareInsert("game_necessity", colNamesGameNecessity, necessity)
 	if err != nil {
 		log.Error(err.Error())
 		return err
 	}
 
-	_, err = cdb.Exec(&stmt)
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNecessitiesByGame deletes all the necessities of the given game.
+func DeleteNecessitiesByGame(tx *cdb.Transaction, game *gameModel.Game) error {
+	necessity := gameModel.GameNecessity{
+		Game: game.ID,
+	}
+
+	stmt := cdb.PrepareDelete("game_necessity", colNamesGameNecessity, &necessity)
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateNecessity updates the given game necessity in the database.
+func UpdateNecessity(tx *cdb.Transaction, necessity *gameModel.GameNecessity,
+	selectors map[string]interface{}) error {
+
+	stmt, err := cdb.PrepareUpdate("game_necessity", colNamesGameNecessity, necessity, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNecessity deletes the given game necessity in the database.
+func DeleteNecessity(tx *cdb.Transaction, necessity *gameModel.GameNecessity) error {
+	stmt := cdb.PrepareDelete("game_necessity", colNamesGameNecessity,
+		&gameModel.GameNecessity{ID: necessity.ID})
+
+	_, err := cdb.ExecTx(tx, &stmt)
 	if err != nil {
 		log.Error(err.Error())
 		return err
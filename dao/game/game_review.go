@@ -0,0 +1,328 @@
+package gameDao
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+var colNamesReview = map[string]string{
+	"ID":        "id",
+	"Game":      "game",
+	"Account":   "account",
+	"Rating":    "rating",
+	"Text":      "text",
+	"CreatedAt": "created_at",
+	"UpdatedAt": "updated_at",
+}
+
+var colNamesReviewReport = map[string]string{
+	"Review":    "review",
+	"Account":   "account",
+	"Reason":    "reason",
+	"CreatedAt": "created_at",
+}
+
+// unmarshalReview parses the database row to the review object.
+func unmarshalReview(review *gameModel.GameReview, r cdb.CdbResult) error {
+	r.UUID("id", &review.ID)
+	r.UUID("game", &review.Game)
+	r.OptUUID("account", &review.Account)
+	r.Int32("rating", &review.Rating)
+	r.MapStrStr("text", &review.Text)
+	r.Time("created_at", &review.CreatedAt)
+	r.Time("updated_at", &review.UpdatedAt)
+	r.OptStr("account_name", &review.AccountName)
+	r.OptInt32("reports", &review.Reports)
+
+	if r.HasErrorsLog("unmarshal review", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// unmarshalReviewReport parses the database row to the review report object.
+func unmarshalReviewReport(report *gameModel.GameReviewReport, r cdb.CdbResult) error {
+	r.UUID("review", &report.Review)
+	r.UUID("account", &report.Account)
+	r.Str("reason", &report.Reason)
+	r.Time("created_at", &report.CreatedAt)
+
+	if r.HasErrorsLog("unmarshal review report", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// queryReviews fetches the reviews of the statement.
+func queryReviews(stmt cdb.Statement) ([]*gameModel.GameReview, error) {
+	reviews := []*gameModel.GameReview{}
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return reviews, err
+	}
+
+	for _, rowReview := range rows {
+		review := gameModel.GameReview{}
+
+		err = unmarshalReview(&review, rowReview)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.GameReview{}, err
+		}
+
+		reviews = append(reviews, &review)
+	}
+
+	return reviews, nil
+}
+
+// GetReview fetches the review that matches with the non nil values from the given review.
+func GetReview(review *gameModel.GameReview) error {
+	fields := cdb.CreateFields(colNamesReview)
+	stmt := cdb.PrepareSelect("game_review", fields, "gr", colNamesReview, review)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalReview(review, rows[0])
+}
+
+// GetReviewsByGame fetches a page of the reviews of the game, the newest first. Pass the creation
+// time and ID of the last review of the previous page to get the next one.
+func GetReviewsByGame(game *gameModel.Game, afterTime *time.Time, afterID *uuid.UUID,
+	limit int32) ([]*gameModel.GameReview, error) {
+
+	stmt := cdb.Prepare(`
+		select gr.id, gr.game, gr.account, gr.rating, gr.text, gr.created_at, gr.updated_at,
+			a.name as account_name
+		from game_review gr
+		left join account a on a.id = gr.account
+		where gr.game = :game:
+			and (cast(:after_time: as timestamptz) is null
+				or (gr.created_at, gr.id) < (:after_time:, :after_id:))
+		order by gr.created_at desc, gr.id desc
+		limit :limit:
+	`)
+
+	stmt.Bind("game", *game.ID)
+	stmt.Bind("limit", limit)
+
+	if afterTime != nil && afterID != nil {
+		stmt.Bind("after_time", *afterTime)
+		stmt.Bind("after_id", *afterID)
+	} else {
+		stmt.Bind("after_time", nil)
+		stmt.Bind("after_id", nil)
+	}
+
+	return queryReviews(stmt)
+}
+
+// GetReviewsByAccount fetches all the reviews the account wrote.
+func GetReviewsByAccount(acc *accountModel.Account) ([]*gameModel.GameReview, error) {
+	stmt := cdb.Prepare(`
+		select id, game, account, rating, text, created_at, updated_at
+		from game_review
+		where account = :account:
+		order by created_at
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	return queryReviews(stmt)
+}
+
+// GetReportedReviews fetches the reviews which were reported, the most reported first.
+func GetReportedReviews() ([]*gameModel.GameReview, error) {
+	stmt := cdb.Prepare(`
+		select gr.id, gr.game, gr.account, gr.rating, gr.text, gr.created_at, gr.updated_at,
+			a.name as account_name, grr.reports
+		from game_review gr
+		left join account a on a.id = gr.account
+		join (
+			select review, count(*) as reports
+			from game_review_report
+			group by review
+		) grr on grr.review = gr.id
+		order by grr.reports desc, gr.created_at desc, gr.id
+	`)
+
+	return queryReviews(stmt)
+}
+
+// InsertReview inserts the review in the database.
+func InsertReview(tx *cdb.Transaction, review *gameModel.GameReview) error {
+	stmt, err := cdb.PrepareInsert("game_review", colNamesReview, review)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpsertReview inserts the review, or edits the rating and text of the review the account already
+// wrote for the game. The ID and creation time of an existing review are kept.
+func UpsertReview(tx *cdb.Transaction, review *gameModel.GameReview) error {
+	stmt := cdb.Prepare(`
+		insert into game_review (id, game, account, rating, text, created_at, updated_at)
+		values (:id:, :game:, :account:, :rating:, :text:, :created_at:, :updated_at:)
+		on conflict (game, account) do update
+		set rating = excluded.rating, text = excluded.text, updated_at = excluded.updated_at
+	`)
+
+	stmt.BindObject(colNamesReview, "", review)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteReview deletes the given review in the database, its reports go along with it.
+func DeleteReview(tx *cdb.Transaction, review *gameModel.GameReview) error {
+	stmt := cdb.PrepareDelete("game_review", colNamesReview, &gameModel.GameReview{ID: review.ID})
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// LockGame locks the row of the game until the end of the transaction. Transactions which change
+// the reviews of the game lock it first, so they run one after the other and the aggregates of the
+// last one see the changes of the others.
+func LockGame(tx *cdb.Transaction, game *uuid.UUID) error {
+	stmt := cdb.Prepare(`
+		select 1 from game
+		where id = :game:
+		for update
+	`)
+
+	stmt.Bind("game", *game)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateRatingAggregates recomputes the rating count and average of the game from its reviews. Run
+// it in the transaction which changed the reviews, after locking the game with LockGame, so the
+// aggregates are never out of date.
+func UpdateRatingAggregates(tx *cdb.Transaction, game *uuid.UUID) error {
+	stmt := cdb.Prepare(`
+		update game
+		set rating_count = (select count(*) from game_review where game = :game:),
+			rating_average = coalesce((select avg(rating) from game_review where game = :game:), 0)
+		where id = :game:
+	`)
+
+	stmt.Bind("game", *game)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// AnonymizeReviews removes the given account as the author of its reviews. The reviews and their
+// ratings are kept.
+func AnonymizeReviews(tx *cdb.Transaction, acc *accountModel.Account) error {
+	stmt := cdb.Prepare(`
+		update game_review
+		set account = null
+		where account = :account:
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// InsertReviewReport reports the review, nothing changes when the account already reported it.
+func InsertReviewReport(tx *cdb.Transaction, report *gameModel.GameReviewReport) error {
+	stmt := cdb.Prepare(`
+		insert into game_review_report (review, account, reason)
+		values (:review:, :account:, :reason:)
+		on conflict do nothing
+	`)
+
+	stmt.BindObject(colNamesReviewReport, "", report)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetReviewReportsByAccount fetches all the reports the account made.
+func GetReviewReportsByAccount(acc *accountModel.Account) ([]*gameModel.GameReviewReport, error) {
+	reports := []*gameModel.GameReviewReport{}
+
+	stmt := cdb.Prepare(`
+		select review, account, reason, created_at
+		from game_review_report
+		where account = :account:
+		order by created_at
+	`)
+
+	stmt.Bind("account", *acc.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return reports, err
+	}
+
+	for _, rowReport := range rows {
+		report := gameModel.GameReviewReport{}
+
+		err = unmarshalReviewReport(&report, rowReport)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.GameReviewReport{}, err
+		}
+
+		reports = append(reports, &report)
+	}
+
+	return reports, nil
+}
@@ -0,0 +1,276 @@
+package gameDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+)
+
+var colNamesRule = map[string]string{
+	"ID":        "id",
+	"Game":      "game",
+	"Variation": "variation",
+	"Kind":      "kind",
+	"Order":     `"order"`,
+	"Text":      "text",
+}
+
+var colNamesVariation = map[string]string{
+	"ID":          "id",
+	"Game":        "game",
+	"Name":        "name",
+	"Description": "description",
+	"Order":       `"order"`,
+}
+
+// unmarshalRule parses the database row to the rule object.
+func unmarshalRule(rule *gameModel.GameRule, r cdb.CdbResult) error {
+	r.UUID("id", &rule.ID)
+	r.UUID("game", &rule.Game)
+	r.OptUUID("variation", &rule.Variation)
+	r.Str("kind", &rule.Kind)
+	r.Int32("order", &rule.Order)
+	r.MapStrStr("text", &rule.Text)
+
+	if r.HasErrorsLog("unmarshal rule", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// unmarshalVariation parses the database row to the variation object.
+func unmarshalVariation(variation *gameModel.GameVariation, r cdb.CdbResult) error {
+	r.UUID("id", &variation.ID)
+	r.UUID("game", &variation.Game)
+	r.MapStrStr("name", &variation.Name)
+	r.MapStrStr("description", &variation.Description)
+	r.Int32("order", &variation.Order)
+
+	if r.HasErrorsLog("unmarshal variation", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetRule fetches the rule that matches with the non nil values from the given rule.
+func GetRule(rule *gameModel.GameRule) error {
+	fields := cdb.CreateFields(colNamesRule)
+	stmt := cdb.PrepareSelect("game_rule", fields, "gr", colNamesRule, rule)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalRule(rule, rows[0])
+}
+
+// queryRules fetches the rules of the statement.
+func queryRules(stmt cdb.Statement) ([]*gameModel.GameRule, error) {
+	rules := []*gameModel.GameRule{}
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return rules, err
+	}
+
+	for _, rowRule := range rows {
+		rule := gameModel.GameRule{}
+
+		err = unmarshalRule(&rule, rowRule)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.GameRule{}, err
+		}
+
+		rules = append(rules, &rule)
+	}
+
+	return rules, nil
+}
+
+// GetRules fetches the rules of all the games.
+func GetRules() ([]*gameModel.GameRule, error) {
+	stmt := cdb.Prepare(`
+		select id, game, variation, kind, "order", text
+		from game_rule
+		order by game, "order", id
+	`)
+
+	return queryRules(stmt)
+}
+
+// GetRulesByGame fetches all the rules of the game, of the base rules and of every variation.
+func GetRulesByGame(game *gameModel.Game) ([]*gameModel.GameRule, error) {
+	stmt := cdb.Prepare(`
+		select id, game, variation, kind, "order", text
+		from game_rule
+		where game = :game:
+		order by "order", id
+	`)
+
+	stmt.Bind("game", *game.ID)
+
+	return queryRules(stmt)
+}
+
+// InsertRule inserts the rule in the database.
+func InsertRule(tx *cdb.Transaction, rule *gameModel.GameRule) error {
+	stmt, err := cdb.PrepareInsert("game_rule", colNamesRule, rule)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateRule updates the given rule in the database.
+func UpdateRule(tx *cdb.Transaction, rule *gameModel.GameRule, selectors map[string]interface{}) error {
+	stmt, err := cdb.PrepareUpdate("game_rule", colNamesRule, rule, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteRule deletes the given rule in the database.
+func DeleteRule(tx *cdb.Transaction, rule *gameModel.GameRule) error {
+	stmt := cdb.PrepareDelete("game_rule", colNamesRule, &gameModel.GameRule{ID: rule.ID})
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetVariation fetches the variation that matches with the non nil values from the given variation.
+func GetVariation(variation *gameModel.GameVariation) error {
+	fields := cdb.CreateFields(colNamesVariation)
+	stmt := cdb.PrepareSelect("game_variation", fields, "gv", colNamesVariation, variation)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalVariation(variation, rows[0])
+}
+
+// queryVariations fetches the variations of the statement.
+func queryVariations(stmt cdb.Statement) ([]*gameModel.GameVariation, error) {
+	variations := []*gameModel.GameVariation{}
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return variations, err
+	}
+
+	for _, rowVariation := range rows {
+		variation := gameModel.GameVariation{}
+
+		err = unmarshalVariation(&variation, rowVariation)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.GameVariation{}, err
+		}
+
+		variations = append(variations, &variation)
+	}
+
+	return variations, nil
+}
+
+// GetVariations fetches the variations of all the games.
+func GetVariations() ([]*gameModel.GameVariation, error) {
+	stmt := cdb.Prepare(`
+		select id, game, name, description, "order"
+		from game_variation
+		order by game, "order", id
+	`)
+
+	return queryVariations(stmt)
+}
+
+// GetVariationsByGame fetches all the variations of the game.
+func GetVariationsByGame(game *gameModel.Game) ([]*gameModel.GameVariation, error) {
+	stmt := cdb.Prepare(`
+		select id, game, name, description, "order"
+		from game_variation
+		where game = :game:
+		order by "order", id
+	`)
+
+	stmt.Bind("game", *game.ID)
+
+	return queryVariations(stmt)
+}
+
+// InsertVariation inserts the variation in the database.
+func InsertVariation(tx *cdb.Transaction, variation *gameModel.GameVariation) error {
+	stmt, err := cdb.PrepareInsert("game_variation", colNamesVariation, variation)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateVariation updates the given variation in the database.
+func UpdateVariation(tx *cdb.Transaction, variation *gameModel.GameVariation,
+	selectors map[string]interface{}) error {
+
+	stmt, err := cdb.PrepareUpdate("game_variation", colNamesVariation, variation, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteVariation deletes the given variation in the database, its rules go along with it.
+func DeleteVariation(tx *cdb.Transaction, variation *gameModel.GameVariation) error {
+	stmt := cdb.PrepareDelete("game_variation", colNamesVariation,
+		&gameModel.GameVariation{ID: variation.ID})
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package gameDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+var colNamesSearchText = map[string]string{
+	"Game":   "game",
+	"Locale": "locale",
+	"Field":  "field",
+	"Text":   "text",
+}
+
+// GameSearch is a trigram search over the searched texts of the games.
+type GameSearch struct {
+	// Query is the normalized query.
+	Query string
+
+	// Pattern is the like pattern of a literal match, nil when the query is too short for one.
+	Pattern *string
+
+	// Threshold is the lowest word similarity which counts as a match.
+	Threshold float64
+
+	Locale   *string
+	Category *uuid.UUID
+
+	// Weights are the weights of the fields, a game scores the weighted sum of its best match
+	// per field.
+	Weights map[string]float64
+
+	Limit int32
+}
+
+// GameMatch is a game found by a search together with how well it matched.
+type GameMatch struct {
+	Game  *gameModel.Game
+	Score float64
+}
+
+// ReplaceSearchTexts replaces the searched texts of the game.
+func ReplaceSearchTexts(tx *cdb.Transaction, game *gameModel.Game,
+	texts []*gameModel.GameSearchText) error {
+
+	stmt := cdb.PrepareDelete("game_search", colNamesSearchText, &gameModel.GameSearchText{Game: game.ID})
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	for _, text := range texts {
+		stmt, err := cdb.PrepareInsert("game_search", colNamesSearchText, text)
+		if err != nil {
+			log.Error(err.Error())
+			return err
+		}
+
+		_, err = cdb.ExecTx(tx, &stmt)
+		if err != nil {
+			log.Error(err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// searchStatement builds the statement of the search. A literal match of the pattern scores 1,
+// other texts score their word similarity with the query once it reaches the threshold.
+func searchStatement(search GameSearch) cdb.Statement {
+	stmt := cdb.Prepare(`
+		with matched as (
+			select gs.game, gs.field,
+				max(case
+					when gs.text like cast(:pattern: as text) then 1
+					else word_similarity(:query:, gs.text)
+				end) as score
+			from game_search gs
+			where (gs.text like cast(:pattern: as text)
+					or word_similarity(:query:, gs.text) >= cast(:threshold: as float8))
+				and (cast(:locale: as text) is null or gs.locale = :locale:)
+			group by gs.game, gs.field
+		), ranked as (
+			select game, sum(score * case field
+				when 'name' then cast(:name_weight: as float8)
+				when 'alias' then cast(:alias_weight: as float8)
+				else cast(:description_weight: as float8)
+			end) as score
+			from matched
+			group by game
+		)
+		select g.id, g.game_category, g.name, g.alias,
+			g.player_count, g.img,
+			g.description, g.highlight, g."order", g.created_by, g.created_at,
+			g.rating_count, g.rating_average, cast(r.score as float8) as score
+		from ranked r
+		join game g on g.id = r.game
+		where cast(:category: as uuid) is null or exists (
+			select 1 from game_category_link gcl
+			where gcl.game = g.id and gcl.category = :category:
+		)
+		order by score desc, g."order", g.id
+		limit :limit:
+	`)
+
+	stmt.Bind("query", search.Query)
+	stmt.Bind("threshold", search.Threshold)
+	stmt.Bind("name_weight", search.Weights["name"])
+	stmt.Bind("alias_weight", search.Weights["alias"])
+	stmt.Bind("description_weight", search.Weights["description"])
+	stmt.Bind("limit", search.Limit)
+
+	if search.Pattern != nil {
+		stmt.Bind("pattern", *search.Pattern)
+	} else {
+		stmt.Bind("pattern", nil)
+	}
+
+	if search.Locale != nil {
+		stmt.Bind("locale", *search.Locale)
+	} else {
+		stmt.Bind("locale", nil)
+	}
+
+	if search.Category != nil {
+		stmt.Bind("category", *search.Category)
+	} else {
+		stmt.Bind("category", nil)
+	}
+
+	return stmt
+}
+
+// SearchGames fetches the games which match the search, best match first.
+func SearchGames(search GameSearch) ([]*GameMatch, error) {
+	matches := []*GameMatch{}
+
+	stmt := searchStatement(search)
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return matches, err
+	}
+
+	for _, row := range rows {
+		game := gameModel.Game{}
+		var score *float64
+
+		err = unmarshalGame(&game, row)
+		if err != nil {
+			log.Error(err.Error())
+			return []*GameMatch{}, err
+		}
+
+		row.Float64("score", &score)
+		if row.HasErrorsLog("unmarshal game match", "") {
+			return []*GameMatch{}, &cdb.ErrParseResult{}
+		}
+
+		matches = append(matches, &GameMatch{Game: &game, Score: *score})
+	}
+
+	return matches, nil
+}
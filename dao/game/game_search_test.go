@@ -0,0 +1,98 @@
+package gameDao
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// search is a search with every filter set.
+func search() GameSearch {
+	return GameSearch{
+		Query:     "kings cup",
+		Pattern:   types.Ptr("%kings cup%"),
+		Threshold: 0.3,
+		Locale:    types.Ptr("en"),
+		Category:  types.Ptr(uuid.UUIDv4()),
+		Weights:   map[string]float64{"name": 3, "alias": 2, "description": 1},
+		Limit:     20,
+	}
+}
+
+func TestSearchStatementBindings(t *testing.T) {
+	s := search()
+	stmt := searchStatement(s)
+	query, params := stmt.GetString()
+
+	// Every placeholder has a value, so the statement can be executed
+	for _, match := range regexp.MustCompile(`:.*?:`).FindAllString(query, -1) {
+		key := strings.ReplaceAll(match, ":", "")
+		if _, ok := params[key]; key != "" && !ok {
+			t.Fatalf("expected a value for %s", key)
+		}
+	}
+
+	expected := map[string]interface{}{
+		"query":              "kings cup",
+		"pattern":            "%kings cup%",
+		"threshold":          0.3,
+		"locale":             "en",
+		"category":           *s.Category,
+		"name_weight":        3.0,
+		"alias_weight":       2.0,
+		"description_weight": 1.0,
+		"limit":              int32(20),
+	}
+
+	for key, value := range expected {
+		if params[key] != value {
+			t.Fatalf("expected %s to be bound to %v, got %v", key, value, params[key])
+		}
+	}
+}
+
+func TestSearchStatementWithoutFilters(t *testing.T) {
+	s := search()
+	s.Pattern = nil
+	s.Locale = nil
+	s.Category = nil
+
+	stmt := searchStatement(s)
+	_, params := stmt.GetString()
+
+	// The filters are bound to null, which the query treats as no filter
+	for _, key := range []string{"pattern", "locale", "category"} {
+		value, ok := params[key]
+		if !ok || value != nil {
+			t.Fatalf("expected %s to be bound to null, got %v", key, value)
+		}
+	}
+}
+
+func TestSearchStatementQuery(t *testing.T) {
+	stmt := searchStatement(search())
+	query, _ := stmt.GetString()
+
+	// The threshold is part of the query, it doesn't depend on a setting of the database
+	clauses := []string{
+		"gs.text like cast(:pattern: as text)",
+		"word_similarity(:query:, gs.text) >= cast(:threshold: as float8)",
+		"gs.locale = :locale:",
+		"gcl.category = :category:",
+		`order by score desc, g."order", g.id`,
+		"limit :limit:",
+	}
+
+	for _, clause := range clauses {
+		if !strings.Contains(query, clause) {
+			t.Fatalf("expected the query to contain %q", clause)
+		}
+	}
+
+	if strings.Contains(query, "<%") {
+		t.Fatal("expected the query not to use the threshold of the database")
+	}
+}
@@ -0,0 +1,319 @@
+package gameDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+var colNamesTag = map[string]string{
+	"ID":   "id",
+	"Name": "name",
+}
+
+var colNamesTagLink = map[string]string{
+	"Game": "game",
+	"Tag":  "tag",
+}
+
+var colNamesCategoryLink = map[string]string{
+	"Game":     "game",
+	"Category": "category",
+	"Order":    `"order"`,
+}
+
+// unmarshalTag parses the database row to the tag object.
+func unmarshalTag(tag *gameModel.GameTag, r cdb.CdbResult) error {
+	r.UUID("id", &tag.ID)
+	r.MapStrStr("name", &tag.Name)
+
+	if r.HasErrorsLog("unmarshal tag", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// gameIDs returns the IDs of the games as strings, so they can be bound to a uuid array.
+func gameIDs(games []*gameModel.Game) []string {
+	ids := []string{}
+	for _, game := range games {
+		ids = append(ids, game.ID.String())
+	}
+
+	return ids
+}
+
+// idStrings returns the IDs as strings, so they can be bound to a uuid array.
+func idStrings(ids []uuid.UUID) []string {
+	strs := []string{}
+	for _, id := range ids {
+		strs = append(strs, id.String())
+	}
+
+	return strs
+}
+
+// GetTags fetches all the tags.
+func GetTags() ([]*gameModel.GameTag, error) {
+	tags := []*gameModel.GameTag{}
+
+	stmt := cdb.Prepare(`
+		select id, name
+		from game_tag
+		order by id
+	`)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return tags, err
+	}
+
+	for _, rowTag := range rows {
+		tag := gameModel.GameTag{}
+
+		err = unmarshalTag(&tag, rowTag)
+		if err != nil {
+			log.Error(err.Error())
+			return []*gameModel.GameTag{}, err
+		}
+
+		tags = append(tags, &tag)
+	}
+
+	return tags, nil
+}
+
+// GetTag fetches the tag that matches with the non nil values from the given tag.
+func GetTag(tag *gameModel.GameTag) error {
+	fields := cdb.CreateFields(colNamesTag)
+	stmt := cdb.PrepareSelect("game_tag", fields, "gt", colNamesTag, tag)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalTag(tag, rows[0])
+}
+
+// InsertTag inserts the tag in the database.
+func InsertTag(tx *cdb.Transaction, tag *gameModel.GameTag) error {
+	stmt, err := cdb.PrepareInsert("game_tag", colNamesTag, tag)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateTag updates the given tag in the database.
+func UpdateTag(tx *cdb.Transaction, tag *gameModel.GameTag, selectors map[string]interface{}) error {
+	stmt, err := cdb.PrepareUpdate("game_tag", colNamesTag, tag, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteTag deletes the given tag in the database, the links to games go along with it.
+func DeleteTag(tx *cdb.Transaction, tag *gameModel.GameTag) error {
+	stmt := cdb.PrepareDelete("game_tag", colNamesTag, &gameModel.GameTag{ID: tag.ID})
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetTagsByGames fetches the tags of all the given games in a single query. The tags are grouped by
+// the ID of their game.
+func GetTagsByGames(games []*gameModel.Game) (map[uuid.UUID][]*gameModel.GameTag, error) {
+	tags := map[uuid.UUID][]*gameModel.GameTag{}
+
+	if len(games) == 0 {
+		return tags, nil
+	}
+
+	stmt := cdb.Prepare(`
+		select gtl.game, gt.id, gt.name
+		from game_tag_link gtl
+		join game_tag gt on gt.id = gtl.tag
+		where gtl.game = any(cast(:games: as uuid[]))
+		order by gtl.game, gt.id
+	`)
+
+	stmt.Bind("games", gameIDs(games))
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return tags, err
+	}
+
+	for _, rowTag := range rows {
+		var game *uuid.UUID
+		tag := gameModel.GameTag{}
+
+		rowTag.UUID("game", &game)
+
+		err = unmarshalTag(&tag, rowTag)
+		if err != nil {
+			log.Error(err.Error())
+			return map[uuid.UUID][]*gameModel.GameTag{}, err
+		}
+
+		tags[*game] = append(tags[*game], &tag)
+	}
+
+	return tags, nil
+}
+
+// InsertTagLink puts the tag on the game, nothing changes when it already is.
+func InsertTagLink(tx *cdb.Transaction, link *gameModel.GameTagLink) error {
+	stmt := cdb.Prepare(`
+		insert into game_tag_link (game, tag)
+		values (:game:, :tag:)
+		on conflict do nothing
+	`)
+
+	stmt.BindObject(colNamesTagLink, "", link)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteTagLink removes the tag from the game.
+func DeleteTagLink(tx *cdb.Transaction, link *gameModel.GameTagLink) error {
+	stmt := cdb.PrepareDelete("game_tag_link", colNamesTagLink, link)
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetCategoriesByGames fetches the categories of all the given games in a single query. The IDs of
+// the categories are grouped by the ID of their game.
+func GetCategoriesByGames(games []*gameModel.Game) (map[uuid.UUID][]uuid.UUID, error) {
+	categories := map[uuid.UUID][]uuid.UUID{}
+
+	if len(games) == 0 {
+		return categories, nil
+	}
+
+	stmt := cdb.Prepare(`
+		select gcl.game, gcl.category
+		from game_category_link gcl
+		join game_category gc on gc.id = gcl.category
+		where gcl.game = any(cast(:games: as uuid[]))
+		order by gcl.game, gc."order", gc.id
+	`)
+
+	stmt.Bind("games", gameIDs(games))
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return categories, err
+	}
+
+	for _, row := range rows {
+		var game, category *uuid.UUID
+
+		row.UUID("game", &game)
+		row.UUID("category", &category)
+
+		if row.HasErrorsLog("unmarshal game category", "") {
+			return map[uuid.UUID][]uuid.UUID{}, &cdb.ErrParseResult{}
+		}
+
+		categories[*game] = append(categories[*game], *category)
+	}
+
+	return categories, nil
+}
+
+// InsertCategoryLink makes the game part of the category, nothing changes when it already is. The
+// game is put last in the category.
+func InsertCategoryLink(tx *cdb.Transaction, link *gameModel.GameCategoryLink) error {
+	stmt := cdb.Prepare(`
+		insert into game_category_link (game, category, "order")
+		select :game:, :category:, coalesce(max("order"), 0) + 1
+		from game_category_link
+		where category = :category:
+		on conflict do nothing
+	`)
+
+	stmt.Bind("game", *link.Game)
+	stmt.Bind("category", *link.Category)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateCategoryLink moves the game to the order of the link within the category.
+func UpdateCategoryLink(tx *cdb.Transaction, link *gameModel.GameCategoryLink) error {
+	selectors := map[string]interface{}{
+		"Game":     link.Game,
+		"Category": link.Category,
+	}
+
+	stmt, err := cdb.PrepareUpdate("game_category_link", colNamesCategoryLink,
+		&gameModel.GameCategoryLink{Order: link.Order}, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCategoryLink removes the game from the category.
+func DeleteCategoryLink(tx *cdb.Transaction, link *gameModel.GameCategoryLink) error {
+	stmt := cdb.PrepareDelete("game_category_link", colNamesCategoryLink, link)
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -1,6 +1,10 @@
 package dao
 
-import "github.com/marvindeckmyn/drankspelletjes-server/cdb"
+import (
+	"errors"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+)
 
 // ExecuteStmt executes the given statement and gives rows back
 func ExecuteStmt(stmt cdb.Statement) ([]cdb.CdbResult, error) {
@@ -15,3 +19,22 @@ func ExecuteStmt(stmt cdb.Statement) ([]cdb.CdbResult, error) {
 
 	return rows, err
 }
+
+// IsMissingResult returns true when the error was caused by a statement which returned no rows.
+func IsMissingResult(err error) bool {
+	switch e := err.(type) {
+	case *cdb.ErrMissingResult:
+		return true
+	case *cdb.ErrQuery:
+		return IsMissingResult(e.Cause)
+	}
+
+	return false
+}
+
+// IsGuardFailed returns true when the error was caused by a guard statement of a transaction, which
+// means the data changed since it was read.
+func IsGuardFailed(err error) bool {
+	guard := &cdb.ErrGuard{}
+	return errors.As(err, &guard)
+}
@@ -0,0 +1,451 @@
+package partyDao
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	partyModel "github.com/marvindeckmyn/drankspelletjes-server/model/party"
+)
+
+var colNamesSession = map[string]string{
+	"ID":        "id",
+	"Host":      "host",
+	"Name":      "name",
+	"Status":    "status",
+	"CreatedAt": "created_at",
+	"UpdatedAt": "updated_at",
+	"ClosedAt":  "closed_at",
+}
+
+var colNamesPlayer = map[string]string{
+	"ID":        "id",
+	"Session":   "session",
+	"Name":      "name",
+	"Order":     `"order"`,
+	"CreatedAt": "created_at",
+}
+
+var colNamesRound = map[string]string{
+	"ID":        "id",
+	"Session":   "session",
+	"Game":      "game",
+	"Number":    "number",
+	"StartedAt": "started_at",
+	"EndedAt":   "ended_at",
+}
+
+var colNamesEvent = map[string]string{
+	"ID":        "id",
+	"Session":   "session",
+	"Round":     "round",
+	"Player":    "player",
+	"Kind":      "kind",
+	"Amount":    "amount",
+	"Note":      "note",
+	"CreatedAt": "created_at",
+}
+
+// unmarshalSession parses the database row to the session object.
+func unmarshalSession(session *partyModel.Session, r cdb.CdbResult) error {
+	r.UUID("id", &session.ID)
+	r.UUID("host", &session.Host)
+	r.Str("name", &session.Name)
+	r.Str("status", &session.Status)
+	r.Time("created_at", &session.CreatedAt)
+	r.Time("updated_at", &session.UpdatedAt)
+	r.OptTime("closed_at", &session.ClosedAt)
+
+	if r.HasErrorsLog("unmarshal party session", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// unmarshalPlayer parses the database row to the player object.
+func unmarshalPlayer(player *partyModel.Player, r cdb.CdbResult) error {
+	r.UUID("id", &player.ID)
+	r.UUID("session", &player.Session)
+	r.Str("name", &player.Name)
+	r.Int32("order", &player.Order)
+	r.Time("created_at", &player.CreatedAt)
+
+	if r.HasErrorsLog("unmarshal party player", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// unmarshalRound parses the database row to the round object.
+func unmarshalRound(round *partyModel.Round, r cdb.CdbResult) error {
+	r.UUID("id", &round.ID)
+	r.UUID("session", &round.Session)
+	r.OptUUID("game", &round.Game)
+	r.Int32("number", &round.Number)
+	r.Time("started_at", &round.StartedAt)
+	r.OptTime("ended_at", &round.EndedAt)
+
+	if r.HasErrorsLog("unmarshal party round", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// unmarshalEvent parses the database row to the event object.
+func unmarshalEvent(event *partyModel.Event, r cdb.CdbResult) error {
+	r.UUID("id", &event.ID)
+	r.UUID("session", &event.Session)
+	r.UUID("round", &event.Round)
+	r.UUID("player", &event.Player)
+	r.Str("kind", &event.Kind)
+	r.Int32("amount", &event.Amount)
+	r.Str("note", &event.Note)
+	r.Time("created_at", &event.CreatedAt)
+
+	if r.HasErrorsLog("unmarshal party event", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetSession fetches the session that matches with the non nil values from the given session.
+func GetSession(session *partyModel.Session) error {
+	fields := cdb.CreateFields(colNamesSession)
+	stmt := cdb.PrepareSelect("party_session", fields, "ps", colNamesSession, session)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalSession(session, rows[0])
+}
+
+// GetSessionsByHost fetches the sessions the account hosts, the ones which weren't closed first and
+// then the most recently changed.
+func GetSessionsByHost(acc *accountModel.Account) ([]*partyModel.Session, error) {
+	sessions := []*partyModel.Session{}
+
+	stmt := cdb.Prepare(`
+		select id, host, name, status, created_at, updated_at, closed_at
+		from party_session
+		where host = :host:
+		order by status = 'closed', updated_at desc, id
+	`)
+
+	stmt.Bind("host", *acc.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return sessions, err
+	}
+
+	for _, rowSession := range rows {
+		session := partyModel.Session{}
+
+		err = unmarshalSession(&session, rowSession)
+		if err != nil {
+			log.Error(err.Error())
+			return []*partyModel.Session{}, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, nil
+}
+
+// InsertSession inserts the session in the database.
+func InsertSession(tx *cdb.Transaction, session *partyModel.Session) error {
+	stmt, err := cdb.PrepareInsert("party_session", colNamesSession, session)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateSession updates the given session in the database.
+func UpdateSession(tx *cdb.Transaction, session *partyModel.Session,
+	selectors map[string]interface{}) error {
+
+	stmt, err := cdb.PrepareUpdate("party_session", colNamesSession, session, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteSession deletes the given session in the database, its players, rounds and events go along
+// with it.
+func DeleteSession(tx *cdb.Transaction, session *partyModel.Session) error {
+	stmt := cdb.PrepareDelete("party_session", colNamesSession, &partyModel.Session{ID: session.ID})
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetPlayer fetches the player that matches with the non nil values from the given player.
+func GetPlayer(player *partyModel.Player) error {
+	fields := cdb.CreateFields(colNamesPlayer)
+	stmt := cdb.PrepareSelect("party_player", fields, "pp", colNamesPlayer, player)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalPlayer(player, rows[0])
+}
+
+// GetPlayers fetches the players of the session in their order.
+func GetPlayers(session *partyModel.Session) ([]*partyModel.Player, error) {
+	players := []*partyModel.Player{}
+
+	stmt := cdb.Prepare(`
+		select id, session, name, "order", created_at
+		from party_player
+		where session = :session:
+		order by "order", created_at, id
+	`)
+
+	stmt.Bind("session", *session.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return players, err
+	}
+
+	for _, rowPlayer := range rows {
+		player := partyModel.Player{}
+
+		err = unmarshalPlayer(&player, rowPlayer)
+		if err != nil {
+			log.Error(err.Error())
+			return []*partyModel.Player{}, err
+		}
+
+		players = append(players, &player)
+	}
+
+	return players, nil
+}
+
+// InsertPlayer inserts the player in the database.
+func InsertPlayer(tx *cdb.Transaction, player *partyModel.Player) error {
+	stmt, err := cdb.PrepareInsert("party_player", colNamesPlayer, player)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePlayer updates the given player in the database.
+func UpdatePlayer(tx *cdb.Transaction, player *partyModel.Player,
+	selectors map[string]interface{}) error {
+
+	stmt, err := cdb.PrepareUpdate("party_player", colNamesPlayer, player, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeletePlayer deletes the given player in the database, the events of the player go along with it.
+func DeletePlayer(tx *cdb.Transaction, player *partyModel.Player) error {
+	stmt := cdb.PrepareDelete("party_player", colNamesPlayer, &partyModel.Player{ID: player.ID})
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetRounds fetches the rounds of the session in the order they were played.
+func GetRounds(session *partyModel.Session) ([]*partyModel.Round, error) {
+	rounds := []*partyModel.Round{}
+
+	stmt := cdb.Prepare(`
+		select id, session, game, number, started_at, ended_at
+		from party_round
+		where session = :session:
+		order by number
+	`)
+
+	stmt.Bind("session", *session.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return rounds, err
+	}
+
+	for _, rowRound := range rows {
+		round := partyModel.Round{}
+
+		err = unmarshalRound(&round, rowRound)
+		if err != nil {
+			log.Error(err.Error())
+			return []*partyModel.Round{}, err
+		}
+
+		rounds = append(rounds, &round)
+	}
+
+	return rounds, nil
+}
+
+// InsertRound inserts the round in the database.
+func InsertRound(tx *cdb.Transaction, round *partyModel.Round) error {
+	stmt, err := cdb.PrepareInsert("party_round", colNamesRound, round)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// EndRounds ends the round of the session which is being played, if there is one.
+func EndRounds(tx *cdb.Transaction, session *partyModel.Session, endedAt time.Time) error {
+	stmt := cdb.Prepare(`
+		update party_round
+		set ended_at = :ended_at:
+		where session = :session: and ended_at is null
+	`)
+
+	stmt.Bind("session", *session.ID)
+	stmt.Bind("ended_at", endedAt)
+
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetEvent fetches the event that matches with the non nil values from the given event.
+func GetEvent(event *partyModel.Event) error {
+	fields := cdb.CreateFields(colNamesEvent)
+	stmt := cdb.PrepareSelect("party_event", fields, "pe", colNamesEvent, event)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalEvent(event, rows[0])
+}
+
+// GetEvents fetches the events of the session in the order they happened.
+func GetEvents(session *partyModel.Session) ([]*partyModel.Event, error) {
+	events := []*partyModel.Event{}
+
+	stmt := cdb.Prepare(`
+		select id, session, round, player, kind, amount, note, created_at
+		from party_event
+		where session = :session:
+		order by created_at, id
+	`)
+
+	stmt.Bind("session", *session.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return events, err
+	}
+
+	for _, rowEvent := range rows {
+		event := partyModel.Event{}
+
+		err = unmarshalEvent(&event, rowEvent)
+		if err != nil {
+			log.Error(err.Error())
+			return []*partyModel.Event{}, err
+		}
+
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// InsertEvent inserts the event in the database.
+func InsertEvent(tx *cdb.Transaction, event *partyModel.Event) error {
+	stmt, err := cdb.PrepareInsert("party_event", colNamesEvent, event)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteEvent deletes the given event in the database.
+func DeleteEvent(tx *cdb.Transaction, event *partyModel.Event) error {
+	stmt := cdb.PrepareDelete("party_event", colNamesEvent, &partyModel.Event{ID: event.ID})
+	_, err := cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
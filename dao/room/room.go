@@ -0,0 +1,280 @@
+package roomDao
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	roomModel "github.com/marvindeckmyn/drankspelletjes-server/model/room"
+)
+
+var colNamesRoom = map[string]string{
+	"ID":          "id",
+	"Code":        "code",
+	"HostAccount": "host_account",
+	"Session":     "session",
+	"Status":      "status",
+	"CreatedAt":   "created_at",
+	"ClosedAt":    "closed_at",
+}
+
+var colNamesParticipant = map[string]string{
+	"ID":        "id",
+	"Room":      "room",
+	"Name":      "name",
+	"TokenHash": "token_hash",
+	"JoinedAt":  "joined_at",
+}
+
+var colNamesEvent = map[string]string{
+	"Room":        "room",
+	"Seq":         "seq",
+	"Kind":        "kind",
+	"Participant": "participant",
+	"Data":        "data",
+	"CreatedAt":   "created_at",
+}
+
+// unmarshalRoom parses the database row to the room object.
+func unmarshalRoom(room *roomModel.Room, r cdb.CdbResult) error {
+	r.UUID("id", &room.ID)
+	r.Str("code", &room.Code)
+	r.UUID("host_account", &room.HostAccount)
+	r.OptUUID("session", &room.Session)
+	r.Str("status", &room.Status)
+	r.Time("created_at", &room.CreatedAt)
+	r.OptTime("closed_at", &room.ClosedAt)
+
+	if r.HasErrorsLog("unmarshal room", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// unmarshalParticipant parses the database row to the participant object.
+func unmarshalParticipant(participant *roomModel.Participant, r cdb.CdbResult) error {
+	r.UUID("id", &participant.ID)
+	r.UUID("room", &participant.Room)
+	r.Str("name", &participant.Name)
+	r.Str("token_hash", &participant.TokenHash)
+	r.Time("joined_at", &participant.JoinedAt)
+
+	if r.HasErrorsLog("unmarshal room participant", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// unmarshalEvent parses the database row to the event object.
+func unmarshalEvent(event *roomModel.Event, r cdb.CdbResult) error {
+	r.UUID("room", &event.Room)
+	r.Int64("seq", &event.Seq)
+	r.Str("kind", &event.Kind)
+	r.OptUUID("participant", &event.Participant)
+	r.MapStrInterface("data", &event.Data)
+	r.Time("created_at", &event.CreatedAt)
+
+	if r.HasErrorsLog("unmarshal room event", "") {
+		return &cdb.ErrParseResult{}
+	}
+
+	return nil
+}
+
+// GetRoom fetches the room that matches with the non nil values from the given room.
+func GetRoom(room *roomModel.Room) error {
+	fields := cdb.CreateFields(colNamesRoom)
+	stmt := cdb.PrepareSelect("room", fields, "r", colNamesRoom, room)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalRoom(room, rows[0])
+}
+
+// GetOpenRooms fetches all the rooms which are open.
+func GetOpenRooms() ([]*roomModel.Room, error) {
+	rooms := []*roomModel.Room{}
+
+	stmt := cdb.Prepare(`
+		select id, code, host_account, session, status, created_at, closed_at
+		from room
+		where status = :status:
+		order by created_at, id
+	`)
+
+	stmt.Bind("status", roomModel.StatusOpen)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return rooms, err
+	}
+
+	for _, rowRoom := range rows {
+		room := roomModel.Room{}
+
+		err = unmarshalRoom(&room, rowRoom)
+		if err != nil {
+			log.Error(err.Error())
+			return []*roomModel.Room{}, err
+		}
+
+		rooms = append(rooms, &room)
+	}
+
+	return rooms, nil
+}
+
+// GetRoomsByHostAccount fetches the rooms the account created, the most recent first.
+func GetRoomsByHostAccount(acc *accountModel.Account) ([]*roomModel.Room, error) {
+	rooms := []*roomModel.Room{}
+
+	stmt := cdb.Prepare(`
+		select id, code, host_account, session, status, created_at, closed_at
+		from room
+		where host_account = :host_account:
+		order by created_at desc, id
+	`)
+
+	stmt.Bind("host_account", *acc.ID)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return rooms, err
+	}
+
+	for _, rowRoom := range rows {
+		room := roomModel.Room{}
+
+		err = unmarshalRoom(&room, rowRoom)
+		if err != nil {
+			log.Error(err.Error())
+			return []*roomModel.Room{}, err
+		}
+
+		rooms = append(rooms, &room)
+	}
+
+	return rooms, nil
+}
+
+// InsertRoom inserts the room in the database.
+func InsertRoom(tx *cdb.Transaction, room *roomModel.Room) error {
+	stmt, err := cdb.PrepareInsert("room", colNamesRoom, room)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateRoom updates the given room in the database.
+func UpdateRoom(tx *cdb.Transaction, room *roomModel.Room, selectors map[string]interface{}) error {
+	stmt, err := cdb.PrepareUpdate("room", colNamesRoom, room, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetParticipant fetches the participant that matches with the non nil values from the given
+// participant.
+func GetParticipant(participant *roomModel.Participant) error {
+	fields := cdb.CreateFields(colNamesParticipant)
+	stmt := cdb.PrepareSelect("room_participant", fields, "rp", colNamesParticipant, participant)
+	rows, err := dao.ExecuteStmt(stmt)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalParticipant(participant, rows[0])
+}
+
+// InsertParticipant inserts the participant in the database.
+func InsertParticipant(tx *cdb.Transaction, participant *roomModel.Participant) error {
+	stmt, err := cdb.PrepareInsert("room_participant", colNamesParticipant, participant)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetEvents fetches the events of the room after the given sequence number in order.
+func GetEvents(room *roomModel.Room, afterSeq int64) ([]*roomModel.Event, error) {
+	events := []*roomModel.Event{}
+
+	stmt := cdb.Prepare(`
+		select room, seq, kind, participant, data, created_at
+		from room_event
+		where room = :room: and seq > :after_seq:
+		order by seq
+	`)
+
+	stmt.Bind("room", *room.ID)
+	stmt.Bind("after_seq", afterSeq)
+
+	rows, err := cdb.Exec(&stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return events, err
+	}
+
+	for _, rowEvent := range rows {
+		event := roomModel.Event{}
+
+		err = unmarshalEvent(&event, rowEvent)
+		if err != nil {
+			log.Error(err.Error())
+			return []*roomModel.Event{}, err
+		}
+
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// InsertEvent inserts the event in the database.
+func InsertEvent(tx *cdb.Transaction, event *roomModel.Event) error {
+	stmt, err := cdb.PrepareInsert("room_event", colNamesEvent, event)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	_, err = cdb.ExecTx(tx, &stmt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+)
+
+// Archive is the zip file which is handed to the collectors of an export.
+type Archive struct {
+	zw *zip.Writer
+}
+
+// newArchive creates an archive which writes to the given writer.
+func newArchive(w io.Writer) *Archive {
+	return &Archive{
+		zw: zip.NewWriter(w),
+	}
+}
+
+// AddJSON adds the value as an indented JSON file with the given name.
+func (a *Archive) AddJSON(name string, v interface{}) error {
+	w, err := a.zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+// AddFile copies the file at the given path into the archive directory. Missing files are skipped,
+// so an image which was removed from disk doesn't break the export.
+func (a *Archive) AddFile(dir string, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+	defer f.Close()
+
+	w, err := a.zw.Create(path.Join(dir, path.Base(filePath)))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Close writes the central directory of the archive.
+func (a *Archive) Close() error {
+	return a.zw.Close()
+}
@@ -0,0 +1,236 @@
+package export
+
+import (
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	auditDao "github.com/marvindeckmyn/drankspelletjes-server/dao/audit"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	partyDao "github.com/marvindeckmyn/drankspelletjes-server/dao/party"
+	roomDao "github.com/marvindeckmyn/drankspelletjes-server/dao/room"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	partyModel "github.com/marvindeckmyn/drankspelletjes-server/model/party"
+	roomModel "github.com/marvindeckmyn/drankspelletjes-server/model/room"
+)
+
+// Collector adds the data of one kind which belongs to the account to the archive.
+type Collector func(acc *accountModel.Account, archive *Archive) error
+
+type namedCollector struct {
+	name    string
+	collect Collector
+}
+
+// collectors are run in the order they were registered.
+var collectors = []namedCollector{}
+
+// Register adds a collector which is run for every export. Packages which store personal data
+// register their collector here, so the export keeps covering everything tied to an account.
+func Register(name string, collect Collector) {
+	collectors = append(collectors, namedCollector{
+		name:    name,
+		collect: collect,
+	})
+}
+
+func init() {
+	Register("profile", collectProfile)
+	Register("sessions", collectSessions)
+	Register("api_tokens", collectApiTokens)
+	Register("identities", collectIdentities)
+	Register("games", collectGames)
+	Register("reviews", collectReviews)
+	Register("favorites", collectFavorites)
+	Register("collections", collectCollections)
+	Register("party_sessions", collectPartySessions)
+	Register("rooms", collectRooms)
+	Register("audit", collectAuditEntries)
+}
+
+// collectProfile adds the account itself, without its credentials.
+func collectProfile(acc *accountModel.Account, archive *Archive) error {
+	return archive.AddJSON("profile.json", map[string]interface{}{
+		"id":           acc.ID,
+		"name":         acc.Name,
+		"email":        acc.Email,
+		"role":         acc.Role,
+		"totp_enabled": acc.TotpEnabled,
+		"deleted_at":   acc.DeletedAt,
+		"delete_after": acc.DeleteAfter,
+	})
+}
+
+// collectSessions adds the sessions of the account.
+func collectSessions(acc *accountModel.Account, archive *Archive) error {
+	sessions, err := accountDao.GetSessionsByAccount(acc)
+	if err != nil {
+		return err
+	}
+
+	return archive.AddJSON("sessions.json", sessions)
+}
+
+// collectApiTokens adds the API tokens of the account. Only their hashes are stored, so the tokens
+// themselves can't leak through an export.
+func collectApiTokens(acc *accountModel.Account, archive *Archive) error {
+	tokens, err := accountDao.GetApiTokensByAccount(acc)
+	if err != nil {
+		return err
+	}
+
+	return archive.AddJSON("api_tokens.json", tokens)
+}
+
+// collectIdentities adds the OpenID Connect identities which are linked to the account.
+func collectIdentities(acc *accountModel.Account, archive *Archive) error {
+	identities, err := accountDao.GetIdentitiesByAccount(acc)
+	if err != nil {
+		return err
+	}
+
+	return archive.AddJSON("identities.json", identities)
+}
+
+// collectGames adds the games the account created together with their images.
+func collectGames(acc *accountModel.Account, archive *Archive) error {
+	games, err := gameDao.GetGamesByAuthor(acc)
+	if err != nil {
+		return err
+	}
+
+	for _, game := range games {
+		if game.Img == nil || *game.Img == "" {
+			continue
+		}
+
+		err = archive.AddFile("images", *game.Img)
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.AddJSON("games.json", games)
+}
+
+// collectReviews adds the reviews the account wrote and the reviews it reported.
+func collectReviews(acc *accountModel.Account, archive *Archive) error {
+	reviews, err := gameDao.GetReviewsByAccount(acc)
+	if err != nil {
+		return err
+	}
+
+	err = archive.AddJSON("reviews.json", reviews)
+	if err != nil {
+		return err
+	}
+
+	reports, err := gameDao.GetReviewReportsByAccount(acc)
+	if err != nil {
+		return err
+	}
+
+	return archive.AddJSON("review_reports.json", reports)
+}
+
+// collectFavorites adds the favorite games of the account.
+func collectFavorites(acc *accountModel.Account, archive *Archive) error {
+	games, err := gameDao.GetFavoriteGames(acc)
+	if err != nil {
+		return err
+	}
+
+	return archive.AddJSON("favorites.json", games)
+}
+
+// collectCollections adds the collections of the account together with their games.
+func collectCollections(acc *accountModel.Account, archive *Archive) error {
+	collections, err := gameDao.GetCollectionsByAccount(acc)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range collections {
+		games, err := gameDao.GetCollectionGames(collection)
+		if err != nil {
+			return err
+		}
+
+		collection.Games = &games
+	}
+
+	return archive.AddJSON("collections.json", collections)
+}
+
+// partySession is a hosted session with everything that was recorded in it.
+type partySession struct {
+	Session *partyModel.Session  `json:"session"`
+	Players []*partyModel.Player `json:"players"`
+	Rounds  []*partyModel.Round  `json:"rounds"`
+	Events  []*partyModel.Event  `json:"events"`
+}
+
+// collectPartySessions adds the party sessions the account hosted.
+func collectPartySessions(acc *accountModel.Account, archive *Archive) error {
+	sessions, err := partyDao.GetSessionsByHost(acc)
+	if err != nil {
+		return err
+	}
+
+	hosted := []*partySession{}
+
+	for _, session := range sessions {
+		players, err := partyDao.GetPlayers(session)
+		if err != nil {
+			return err
+		}
+
+		rounds, err := partyDao.GetRounds(session)
+		if err != nil {
+			return err
+		}
+
+		events, err := partyDao.GetEvents(session)
+		if err != nil {
+			return err
+		}
+
+		hosted = append(hosted, &partySession{session, players, rounds, events})
+	}
+
+	return archive.AddJSON("party_sessions.json", hosted)
+}
+
+// hostedRoom is a room the account created with everything that happened in it.
+type hostedRoom struct {
+	Room   *roomModel.Room    `json:"room"`
+	Events []*roomModel.Event `json:"events"`
+}
+
+// collectRooms adds the rooms the account created.
+func collectRooms(acc *accountModel.Account, archive *Archive) error {
+	rooms, err := roomDao.GetRoomsByHostAccount(acc)
+	if err != nil {
+		return err
+	}
+
+	hosted := []*hostedRoom{}
+
+	for _, room := range rooms {
+		events, err := roomDao.GetEvents(room, 0)
+		if err != nil {
+			return err
+		}
+
+		hosted = append(hosted, &hostedRoom{room, events})
+	}
+
+	return archive.AddJSON("rooms.json", hosted)
+}
+
+// collectAuditEntries adds the audit entries of the changes the account made.
+func collectAuditEntries(acc *accountModel.Account, archive *Archive) error {
+	entries, err := auditDao.GetAuditEntriesByActor(*acc.ID)
+	if err != nil {
+		return err
+	}
+
+	return archive.AddJSON("audit.json", entries)
+}
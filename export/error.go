@@ -0,0 +1,15 @@
+package export
+
+// ErrCollect is thrown when a collector could not add its data to the archive.
+type ErrCollect struct {
+	Name  string
+	Cause error
+}
+
+func (e *ErrCollect) Error() string {
+	if e.Cause != nil {
+		return "could not collect " + e.Name + ": " + e.Cause.Error()
+	}
+
+	return "could not collect " + e.Name
+}
@@ -0,0 +1,332 @@
+// The export package builds archives with all the personal data of an account, so data-access
+// requests can be answered. Archives are built in the background and downloaded through a signed,
+// time-limited link.
+package export
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/marvindeckmyn/drankspelletjes-server/auth"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	"github.com/marvindeckmyn/drankspelletjes-server/mail"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// Dir is the directory in which the archives are stored until they expire.
+var Dir = "exports"
+
+// DownloadURL is the link to download an archive, the export ID and token are filled in.
+var DownloadURL = "https://drankspelletjes.local/api/account/export/%s/download?token=%s"
+
+// downloadDuration is how long an archive can be downloaded after it was built.
+const downloadDuration = 24 * time.Hour
+
+// pendingDuration is how long a pending export is kept when it never finishes, e.g. because the
+// server restarted while building it.
+const pendingDuration = 24 * time.Hour
+
+type ExportURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// validateExportURL checks if the export URL is valid.
+func validateExportURL(r *server.Request) (*ExportURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := ExportURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// downloadToken creates a token which allows downloading the archive until it expires.
+func downloadToken(export *accountModel.DataExport) (string, error) {
+	return auth.SignToken(jwt.MapClaims{
+		"purpose": "export",
+		"EID":     export.ID.String(),
+		"exp":     export.ExpiresAt.Unix(),
+	})
+}
+
+// downloadLink creates a signed link to download the archive until it expires.
+func downloadLink(export *accountModel.DataExport) (string, error) {
+	token, err := downloadToken(export)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(DownloadURL, export.ID, token), nil
+}
+
+// checkDownloadToken verifies that the token is a valid download token for the export.
+func checkDownloadToken(token string, id uuid.UUID) bool {
+	claims, err := auth.ParseToken(token)
+	if err != nil || claims == nil || claims["purpose"] != "export" {
+		return false
+	}
+
+	return claims["EID"] == id.String()
+}
+
+// writeArchive runs all the collectors for the account and writes the archive to the given path.
+func writeArchive(acc *accountModel.Account, path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	archive := newArchive(f)
+
+	for _, collector := range collectors {
+		err = collector.collect(acc, archive)
+		if err != nil {
+			return &ErrCollect{Name: collector.name, Cause: err}
+		}
+	}
+
+	return archive.Close()
+}
+
+// build builds the archive of the export and mails the download link to the account.
+func build(acc *accountModel.Account, export *accountModel.DataExport) {
+	path := filepath.Join(Dir, export.ID.String()+".zip")
+	now := time.Now().UTC()
+
+	update := accountModel.DataExport{
+		CompletedAt: &now,
+	}
+
+	selectors := map[string]interface{}{
+		"ID": export.ID,
+	}
+
+	err := writeArchive(acc, path)
+	if err != nil {
+		log.Error(err.Error())
+		os.Remove(path)
+
+		update.Status = types.Ptr(accountModel.ExportFailed)
+
+		err = accountDao.UpdateDataExport(&update, selectors)
+		if err != nil {
+			log.Error(err.Error())
+		}
+
+		return
+	}
+
+	update.Status = types.Ptr(accountModel.ExportReady)
+	update.File = &path
+	update.ExpiresAt = types.Ptr(now.Add(downloadDuration))
+
+	err = accountDao.UpdateDataExport(&update, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		os.Remove(path)
+		return
+	}
+
+	export.ExpiresAt = update.ExpiresAt
+
+	link, err := downloadLink(export)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	err = mail.Send(mail.Message{
+		To:      *acc.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("Use the link below to download your data. It expires in 24 hours.\r\n\r\n%s",
+			link),
+	})
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	log.Info("Built data export %s for %s", export.ID, acc.ID)
+}
+
+// CleanupExports removes the archives and records of the expired exports.
+func CleanupExports() {
+	exports, err := accountDao.GetExpiredDataExports()
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	for _, export := range exports {
+		if export.File != nil {
+			err = os.Remove(*export.File)
+			if err != nil && !os.IsNotExist(err) {
+				log.Error(err.Error())
+				continue
+			}
+		}
+
+		err = accountDao.DeleteDataExport(export)
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
+
+// StartCleanup removes the expired exports in the background at the given interval.
+func StartCleanup(interval time.Duration) {
+	go func() {
+		for {
+			CleanupExports()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// getOwnExport fetches the export with the ID from the URL when it belongs to the current user.
+func getOwnExport(r *server.Request) (*accountModel.DataExport, error) {
+	url, err := validateExportURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	export := accountModel.DataExport{
+		ID:      &url.ID,
+		Account: r.Account().ID,
+	}
+
+	err = accountDao.GetDataExport(&export)
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// PostExport starts building an archive with the data of the current user.
+func PostExport(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	// Only one export is built at a time
+	pending := accountModel.DataExport{
+		Account: acc.ID,
+		Status:  types.Ptr(accountModel.ExportPending),
+	}
+
+	err := accountDao.GetDataExport(&pending)
+	if err == nil {
+		rw.JSON(http.StatusConflict, pending)
+		return
+	}
+
+	if !dao.IsMissingResult(err) {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	now := time.Now().UTC()
+
+	export := accountModel.DataExport{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Account:   acc.ID,
+		Status:    types.Ptr(accountModel.ExportPending),
+		CreatedAt: &now,
+		ExpiresAt: types.Ptr(now.Add(pendingDuration)),
+	}
+
+	err = accountDao.InsertDataExport(&export)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	go build(acc, &export)
+
+	log.Info("%s requested a data export", acc.ID)
+
+	rw.JSON(http.StatusAccepted, export)
+}
+
+// GetExport retrieves the status of an export of the current user, together with the download link
+// once the archive is ready.
+func GetExport(rw server.ResponseWriter, r *server.Request) {
+	export, err := getOwnExport(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	response := map[string]interface{}{
+		"export": export,
+	}
+
+	if *export.Status == accountModel.ExportReady {
+		link, err := downloadLink(export)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+
+		response["download_url"] = link
+	}
+
+	rw.JSON(http.StatusOK, response)
+}
+
+// DownloadExport sends the archive of an export. The request is authenticated with the signed
+// token from the download link, so the link also works from the mail.
+func DownloadExport(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateExportURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if !checkDownloadToken(r.R.URL.Query().Get("token"), url.ID) {
+		rw.JSON(http.StatusForbidden, nil)
+		return
+	}
+
+	export := accountModel.DataExport{
+		ID:     &url.ID,
+		Status: types.Ptr(accountModel.ExportReady),
+	}
+
+	err = accountDao.GetDataExport(&export)
+	if err != nil || export.File == nil || time.Now().After(*export.ExpiresAt) {
+		rw.JSON(http.StatusGone, nil)
+		return
+	}
+
+	log.Info("Data export %s is downloaded", export.ID)
+
+	rw.W.Header().Set("Content-Disposition", `attachment; filename="drankspelletjes-export.zip"`)
+	http.ServeFile(rw.W, r.R, *export.File)
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+func TestArchive(t *testing.T) {
+	img := filepath.Join(t.TempDir(), "game_test.png")
+
+	err := os.WriteFile(img, []byte("png"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.Buffer{}
+	archive := newArchive(&buf)
+
+	err = archive.AddJSON("profile.json", map[string]string{"name": "Joske"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = archive.AddFile("images", img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing files are skipped
+	err = archive.AddFile("images", filepath.Join(t.TempDir(), "missing.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = archive.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+
+	if len(names) != 2 || names[0] != "profile.json" || names[1] != "images/game_test.png" {
+		t.Fatalf("unexpected archive content %v", names)
+	}
+}
+
+func TestDownloadToken(t *testing.T) {
+	export := accountModel.DataExport{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		ExpiresAt: types.Ptr(time.Now().Add(time.Hour)),
+	}
+
+	token, err := downloadToken(&export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !checkDownloadToken(token, *export.ID) {
+		t.Fatal("expected the token to be valid for its export")
+	}
+
+	if checkDownloadToken(token, uuid.UUIDv4()) {
+		t.Fatal("expected the token to be refused for another export")
+	}
+
+	export.ExpiresAt = types.Ptr(time.Now().Add(-time.Hour))
+
+	expired, err := downloadToken(&export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if checkDownloadToken(expired, *export.ID) {
+		t.Fatal("expected an expired token to be refused")
+	}
+}
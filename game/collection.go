@@ -0,0 +1,628 @@
+package game
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/auth"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// maxCollectionNameLength is the largest number of characters of the name of a collection.
+const maxCollectionNameLength = 100
+
+type CollectionBody struct {
+	Name string `json:"name"`
+}
+
+type CollectionURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type CollectionGameURL struct {
+	ID   uuid.UUID `json:"id"`
+	Game uuid.UUID `json:"game"`
+}
+
+type SharedCollectionURL struct {
+	Token string `json:"token"`
+}
+
+// validateCollectionBody checks if the body is valid.
+func validateCollectionBody(requestBody io.Reader) (*CollectionBody, error) {
+	v := validator.V{
+		"name": validator.IsString,
+	}
+
+	body := CollectionBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+
+	if body.Name == "" || utf8.RuneCountInString(body.Name) > maxCollectionNameLength {
+		return nil, &validator.ErrInvalidContent{Cause: "name"}
+	}
+
+	return &body, nil
+}
+
+// validateCollectionURL checks if the collection URL is valid.
+func validateCollectionURL(r *server.Request) (*CollectionURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := CollectionURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// validateCollectionGameURL checks if the URL of a game in a collection is valid.
+func validateCollectionGameURL(r *server.Request) (*CollectionGameURL, error) {
+	v := validator.V{
+		"id":   validator.IsUUIDV4,
+		"game": validator.IsUUIDV4,
+	}
+
+	url := CollectionGameURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// validateSharedCollectionURL checks if the URL of a shared collection is valid.
+func validateSharedCollectionURL(r *server.Request) (*SharedCollectionURL, error) {
+	v := validator.V{
+		"token": validator.IsString,
+	}
+
+	url := SharedCollectionURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// getOwnCollection fetches the collection with the given ID of the caller. Collections of other
+// accounts are reported as missing. The status to respond with is returned when it fails.
+func getOwnCollection(r *server.Request, id uuid.UUID) (*gameModel.GameCollection, int, error) {
+	collection := gameModel.GameCollection{
+		ID:      &id,
+		Account: r.Account().ID,
+	}
+
+	err := gameDao.GetCollection(&collection)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, http.StatusNotFound, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return &collection, http.StatusOK, nil
+}
+
+// loadCollectionGames loads the games of the collection, flagging the favorites of the caller.
+func loadCollectionGames(r *server.Request, collection *gameModel.GameCollection) error {
+	games, err := gameDao.GetCollectionGames(collection)
+	if err != nil {
+		return err
+	}
+
+	err = loadFavorites(r, games)
+	if err != nil {
+		return err
+	}
+
+	collection.Games = &games
+	return nil
+}
+
+// sharedCollection returns the collection as it is shown to whoever it was shared with, without its
+// owner and share token.
+func sharedCollection(collection *gameModel.GameCollection) *gameModel.GameCollection {
+	shared := *collection
+	shared.Account = nil
+	shared.ShareToken = nil
+
+	return &shared
+}
+
+// touchCollection marks the collection as changed.
+func touchCollection(tx *cdb.Transaction, collection *gameModel.GameCollection) error {
+	selectors := map[string]interface{}{
+		"ID": collection.ID,
+	}
+
+	changes := gameModel.GameCollection{
+		UpdatedAt: types.Ptr(time.Now().UTC()),
+	}
+
+	return gameDao.UpdateCollection(tx, &changes, selectors)
+}
+
+// GetCollections to retrieve the collections of the caller.
+func GetCollections(rw server.ResponseWriter, r *server.Request) {
+	collections, err := gameDao.GetCollectionsByAccount(r.Account())
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, collections)
+}
+
+// GetCollection to retrieve a collection of the caller together with its games.
+func GetCollection(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateCollectionURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	collection, status, err := getOwnCollection(r, url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	err = loadCollectionGames(r, collection)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	writeLocalized(rw, r, collection)
+}
+
+// GetSharedCollection to retrieve a collection through its share token, no login is needed.
+func GetSharedCollection(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateSharedCollectionURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	collection := gameModel.GameCollection{
+		ShareToken: &url.Token,
+	}
+
+	err = gameDao.GetCollection(&collection)
+	if err != nil {
+		log.Error(err.Error())
+
+		if dao.IsMissingResult(err) {
+			rw.JSON(http.StatusNotFound, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = loadCollectionGames(r, &collection)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	writeLocalized(rw, r, sharedCollection(&collection))
+}
+
+// PostCollection creates a collection for the caller.
+func PostCollection(rw server.ResponseWriter, r *server.Request) {
+	// Validate collection body
+	body, err := validateCollectionBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	// Add collection
+	now := time.Now().UTC()
+
+	collection := gameModel.GameCollection{
+		ID:         types.Ptr(uuid.UUIDv4()),
+		Account:    r.Account().ID,
+		Name:       &body.Name,
+		ShareToken: &token,
+		CreatedAt:  &now,
+		UpdatedAt:  &now,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertCollection(tx, &collection)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, collection)
+}
+
+// UpdateCollection renames a collection of the caller.
+func UpdateCollection(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateCollectionURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Validate collection body
+	body, err := validateCollectionBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	collection, status, err := getOwnCollection(r, url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Update collection
+	collection.Name = &body.Name
+	collection.UpdatedAt = types.Ptr(time.Now().UTC())
+
+	selectors := map[string]interface{}{
+		"ID": collection.ID,
+	}
+
+	changes := gameModel.GameCollection{
+		Name:      collection.Name,
+		UpdatedAt: collection.UpdatedAt,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.UpdateCollection(tx, &changes, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, collection)
+}
+
+// ShareCollection replaces the share token of a collection of the caller, the old link stops
+// working.
+func ShareCollection(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateCollectionURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	collection, status, err := getOwnCollection(r, url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	// Replace share token
+	collection.ShareToken = &token
+
+	selectors := map[string]interface{}{
+		"ID": collection.ID,
+	}
+
+	changes := gameModel.GameCollection{
+		ShareToken: collection.ShareToken,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.UpdateCollection(tx, &changes, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, collection)
+}
+
+// DeleteCollection deletes a collection of the caller.
+func DeleteCollection(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateCollectionURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	collection, status, err := getOwnCollection(r, url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Delete collection
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteCollection(tx, collection)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// PutCollectionGame adds a game at the end of a collection of the caller.
+func PutCollectionGame(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateCollectionGameURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	collection, status, err := getOwnCollection(r, url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	status, err = checkGameExists(url.Game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Add game
+	item := gameModel.GameCollectionItem{
+		Collection: collection.ID,
+		Game:       &url.Game,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertCollectionItem(tx, &item)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = touchCollection(tx, collection)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, item)
+}
+
+// DeleteCollectionGame removes a game from a collection of the caller.
+func DeleteCollectionGame(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateCollectionGameURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	collection, status, err := getOwnCollection(r, url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Remove game
+	item := gameModel.GameCollectionItem{
+		Collection: collection.ID,
+		Game:       &url.Game,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteCollectionItem(tx, &item)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = touchCollection(tx, collection)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// ReorderCollection rewrites the order of the games of a collection of the caller at once. The body
+// has to list every game of the collection exactly once.
+func ReorderCollection(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateCollectionURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Validate reorder body
+	body, err := validateReorderBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	collection, status, err := getOwnCollection(r, url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Get games
+	games, err := gameDao.GetCollectionGames(collection)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	current := []uuid.UUID{}
+	for _, game := range games {
+		current = append(current, *game.ID)
+	}
+
+	if !sameIDs(body.IDs, current) {
+		log.Error("The games to reorder don't match the games of the collection")
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Update the orders
+	orders := positions(body.IDs)
+	tx := cdb.NewTx()
+
+	for _, id := range body.IDs {
+		selectors := map[string]interface{}{
+			"collection": collection.ID,
+			"game":       id,
+		}
+
+		changes := gameModel.GameCollectionItem{
+			Order: types.Ptr(orders[id]),
+		}
+
+		err = gameDao.UpdateCollectionItem(tx, &changes, selectors)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	err = touchCollection(tx, collection)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, body)
+}
@@ -0,0 +1,64 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+func TestValidateCollectionBody(t *testing.T) {
+	body, err := validateCollectionBody(strings.NewReader(`{"name": "  Saturday pre-drinks "}`))
+	if err != nil || body.Name != "Saturday pre-drinks" {
+		t.Fatalf("unexpected body %+v %v", body, err)
+	}
+
+	invalid := []string{
+		`{"name": "   "}`,
+		`{"name": "` + strings.Repeat("a", maxCollectionNameLength+1) + `"}`,
+		`{}`,
+	}
+
+	for _, raw := range invalid {
+		_, err := validateCollectionBody(strings.NewReader(raw))
+		if err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestSharedCollection(t *testing.T) {
+	games := []*gameModel.Game{{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: &map[string]string{"en": "Kings", "nl": "Koningen"},
+	}}
+
+	collection := gameModel.GameCollection{
+		ID:         types.Ptr(uuid.UUIDv4()),
+		Account:    types.Ptr(uuid.UUIDv4()),
+		Name:       types.Ptr("Saturday pre-drinks"),
+		ShareToken: types.Ptr("secret"),
+		Games:      &games,
+	}
+
+	// The owner and the token aren't revealed to whoever the collection was shared with
+	shared := sharedCollection(&collection)
+	if shared.ShareToken != nil || shared.Account != nil || len(*shared.Games) != 1 {
+		t.Fatalf("unexpected shared collection %+v", shared)
+	}
+
+	if collection.ShareToken == nil || collection.Account == nil {
+		t.Fatal("expected the collection itself to be left alone")
+	}
+}
+
+func TestLoadFavoritesAnonymous(t *testing.T) {
+	games := []*gameModel.Game{{ID: types.Ptr(uuid.UUIDv4())}}
+
+	err := loadFavorites(listRequest(""), games)
+	if err != nil || games[0].IsFavorite != nil {
+		t.Fatal("expected nothing to be flagged for anonymous requests")
+	}
+}
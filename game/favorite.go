@@ -0,0 +1,117 @@
+package game
+
+import (
+	"net/http"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+)
+
+// loadFavorites flags which of the games are favorites of the caller. Nothing is flagged for
+// anonymous requests.
+func loadFavorites(r *server.Request, games []*gameModel.Game) error {
+	acc := r.Account()
+	if acc == nil {
+		return nil
+	}
+
+	favorites, err := gameDao.GetFavoriteIDs(acc, games)
+	if err != nil {
+		return err
+	}
+
+	for _, game := range games {
+		game.IsFavorite = types.Ptr(favorites[*game.ID])
+	}
+
+	return nil
+}
+
+// GetFavorites to retrieve the favorite games of the caller, the most recent favorite first.
+func GetFavorites(rw server.ResponseWriter, r *server.Request) {
+	games, err := gameDao.GetFavoriteGames(r.Account())
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	for _, game := range games {
+		game.IsFavorite = types.Ptr(true)
+	}
+
+	writeLocalized(rw, r, games)
+}
+
+// PutFavorite marks a game as a favorite of the caller.
+func PutFavorite(rw server.ResponseWriter, r *server.Request) {
+	// Get game
+	game, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Add favorite
+	favorite := gameModel.GameFavorite{
+		Account: r.Account().ID,
+		Game:    game.ID,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertFavorite(tx, &favorite)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, favorite)
+}
+
+// DeleteFavorite removes a game from the favorites of the caller.
+func DeleteFavorite(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateGameURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Remove favorite
+	favorite := gameModel.GameFavorite{
+		Account: r.Account().ID,
+		Game:    &url.ID,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteFavorite(tx, &favorite)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
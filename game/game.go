@@ -1,18 +1,17 @@
 package game
 
 import (
-	"encoding/base64"
-	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
-	"github.com/marvindeckmyn/drankspelletjes-server/auth"
-	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
 	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
 	"github.com/marvindeckmyn/drankspelletjes-server/log"
-	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
 	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
 	"github.com/marvindeckmyn/drankspelletjes-server/server"
 	"github.com/marvindeckmyn/drankspelletjes-server/types"
@@ -31,6 +30,17 @@ type GameBody struct {
 	Order        int32             `json:"order"`
 }
 
+type GamePatchBody struct {
+	GameCategory *uuid.UUID         `json:"game_category"`
+	Name         *map[string]string `json:"name"`
+	Alias        *map[string]string `json:"alias"`
+	Description  *map[string]string `json:"description"`
+	Highlight    *bool              `json:"highlight"`
+	Img          *string            `json:"img"`
+	PlayerCount  *int32             `json:"player_count"`
+	Order        *int32             `json:"order"`
+}
+
 type GameURL struct {
 	ID uuid.UUID `json:"id"`
 }
@@ -59,6 +69,30 @@ func validateGameBody(requestBody io.Reader) (*GameBody, error) {
 	return &body, nil
 }
 
+// validateGamePatchBody checks if the body is valid. All the fields are optional.
+func validateGamePatchBody(requestBody io.Reader) (*GamePatchBody, error) {
+	v := validator.V{
+		"game_category": validator.IsOptUUIDV4,
+		"name":          validator.IsOptMapStrStr,
+		"alias":         validator.IsOptMapStrStr,
+		"description":   validator.IsOptMapStrStr,
+		"highlight":     validator.IsOptBool,
+		"img":           validator.IsOptString,
+		"player_count":  validator.IsOptInt,
+		"order":         validator.IsOptInt,
+	}
+
+	body := GamePatchBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &body, nil
+}
+
 // validateGameURL checks if the game URL is valid.
 func validateGameURL(r *server.Request) (*GameURL, error) {
 	v := validator.V{
@@ -76,7 +110,99 @@ func validateGameURL(r *server.Request) (*GameURL, error) {
 	return &url, nil
 }
 
-// GetGamesByCategory to retrieve all the games by category.
+// includes checks whether the relation was asked for in the include query parameter, e.g.
+// ?include=necessities.
+func includes(r *server.Request, relation string) bool {
+	for _, include := range r.QueryParams["include"] {
+		for _, name := range strings.Split(include, ",") {
+			if strings.TrimSpace(name) == relation {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// loadIncludes loads the relations which were asked for into the games. The favorites of a
+// logged-in caller are always flagged.
+func loadIncludes(r *server.Request, games []*gameModel.Game) error {
+	if includes(r, "necessities") {
+		necessities, err := gameDao.GetNecessitiesByGames(games)
+		if err != nil {
+			return err
+		}
+
+		for _, game := range games {
+			gameNecessities := necessities[*game.ID]
+			if gameNecessities == nil {
+				gameNecessities = []*gameModel.GameNecessity{}
+			}
+
+			game.Necessities = &gameNecessities
+		}
+	}
+
+	if includes(r, "categories") {
+		categories, err := gameDao.GetCategoriesByGames(games)
+		if err != nil {
+			return err
+		}
+
+		for _, game := range games {
+			gameCategories := categories[*game.ID]
+			if gameCategories == nil {
+				gameCategories = []uuid.UUID{}
+			}
+
+			game.Categories = &gameCategories
+		}
+	}
+
+	if includes(r, "tags") {
+		tags, err := gameDao.GetTagsByGames(games)
+		if err != nil {
+			return err
+		}
+
+		for _, game := range games {
+			gameTags := tags[*game.ID]
+			if gameTags == nil {
+				gameTags = []*gameModel.GameTag{}
+			}
+
+			game.Tags = &gameTags
+		}
+	}
+
+	return loadFavorites(r, games)
+}
+
+// getGame fetches the game from the URL. The status to respond with is returned when it fails.
+func getGame(r *server.Request) (*gameModel.Game, int, error) {
+	url, err := validateGameURL(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	game := gameModel.Game{
+		ID: &url.ID,
+	}
+
+	err = gameDao.GetGame(&game)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, http.StatusNotFound, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return &game, http.StatusOK, nil
+}
+
+// GetGamesByCategory to retrieve the games of a category. The games can be filtered and sorted with
+// query parameters and are returned in pages, the cursor of the next page is in X-Next-Cursor.
 func GetGamesByCategory(rw server.ResponseWriter, r *server.Request) {
 	// Get category
 	url, err := validateGameURL(r)
@@ -97,36 +223,44 @@ func GetGamesByCategory(rw server.ResponseWriter, r *server.Request) {
 		return
 	}
 
-	games, err := gameDao.GetGamesByCategory(&category)
+	// Get games
+	filter, err := validateGameListQuery(r)
 	if err != nil {
 		log.Error(err.Error())
 		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
-	rw.JSON(http.StatusOK, games)
-}
+	filter.Category = category.ID
 
-// PostGame inserts a game in the database.
-func PostGame(rw server.ResponseWriter, r *server.Request) {
-	// Check account
-	accID, err := auth.GetID(r)
+	games, err := gameDao.GetGamesFiltered(*filter)
 	if err != nil {
 		log.Error(err.Error())
-		rw.JSON(http.StatusUnauthorized, nil)
+		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
-	acc := accountModel.Account{
-		ID: &accID,
-	}
-
-	err = accountDao.GetAccount(&acc)
+	err = loadIncludes(r, games)
 	if err != nil {
-		rw.JSON(http.StatusUnauthorized, nil)
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 
+	if int32(len(games)) == filter.Limit {
+		sort := filter.Sort
+		if filter.Desc {
+			sort = "-" + sort
+		}
+
+		rw.W.Header().Set(NextCursorHeader, encodeListCursor(games[len(games)-1], sort))
+	}
+
+	writeLocalized(rw, r, games)
+}
+
+// PostGame inserts a game in the database.
+func PostGame(rw server.ResponseWriter, r *server.Request) {
 	// Validate game body
 	body, err := validateGameBody(r.R.Body)
 	if err != nil {
@@ -139,45 +273,312 @@ func PostGame(rw server.ResponseWriter, r *server.Request) {
 	gameUuid := types.Ptr(uuid.UUIDv4())
 
 	if body.Img != "" {
-		data, err := base64.StdEncoding.DecodeString(body.Img)
+		body.Img, err = saveGameImage(*gameUuid, body.Img)
 		if err != nil {
 			log.Error(err.Error())
-			rw.JSON(http.StatusInternalServerError, nil)
+			rw.JSON(http.StatusBadRequest, nil)
 			return
 		}
+	}
+
+	// Add game
+	game := gameModel.Game{
+		ID:           gameUuid,
+		GameCategory: &body.GameCategory,
+		Name:         &body.Name,
+		Alias:        &body.Alias,
+		Description:  &body.Description,
+		Highlight:    &body.Highlight,
+		Img:          &body.Img,
+		PlayerCount:  &body.PlayerCount,
+		Order:        &body.Order,
+		CreatedBy:    r.Account().ID,
+		CreatedAt:    types.Ptr(time.Now().UTC()),
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertGame(tx, &game)
+	if err != nil {
+		log.Error(err.Error())
+		removeGameImage(body.Img)
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = gameDao.ReplaceSearchTexts(tx, &game, searchTexts(&game))
+	if err != nil {
+		log.Error(err.Error())
+		removeGameImage(body.Img)
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	// The primary category is always one of the categories of the game
+	link := gameModel.GameCategoryLink{Game: game.ID, Category: game.GameCategory}
+
+	err = gameDao.InsertCategoryLink(tx, &link)
+	if err != nil {
+		log.Error(err.Error())
+		removeGameImage(body.Img)
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionCreate, audit.EntityGame, game.ID, nil, game)
+	if err != nil {
+		log.Error(err.Error())
+		removeGameImage(body.Img)
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		removeGameImage(body.Img)
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, game)
+}
+
+// GetGame to retrieve a game by UUID.
+func GetGame(rw server.ResponseWriter, r *server.Request) {
+	game, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	err = loadIncludes(r, []*gameModel.Game{game})
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	writeLocalized(rw, r, game)
+}
+
+// saveGame stores the changes to the game together with their audit entry. A replaced image is
+// only removed once the change is committed.
+func saveGame(rw server.ResponseWriter, r *server.Request, before *gameModel.Game,
+	game *gameModel.Game, img string) {
+
+	// Replace image
+	newImg, err := resolveGameImage(*game.ID, *before.Img, img)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	game.Img = &newImg
+	replaced := newImg != *before.Img
+
+	// Clean up the new image when the change isn't stored
+	failed := func(status int, err error) {
+		log.Error(err.Error())
+
+		if replaced {
+			removeGameImage(newImg)
+		}
+
+		rw.JSON(status, nil)
+	}
+
+	// Update game
+	selectors := map[string]interface{}{
+		"ID": game.ID,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.UpdateGame(tx, game, selectors)
+	if err != nil {
+		failed(http.StatusBadRequest, err)
+		return
+	}
+
+	err = gameDao.ReplaceSearchTexts(tx, game, searchTexts(game))
+	if err != nil {
+		failed(http.StatusInternalServerError, err)
+		return
+	}
+
+	// Move the link of the primary category along with it
+	if before.GameCategory != nil && *game.GameCategory != *before.GameCategory {
+		link := gameModel.GameCategoryLink{Game: game.ID, Category: before.GameCategory}
 
-		filename := fmt.Sprintf("img/game_%s.png", gameUuid.String())
+		err = gameDao.DeleteCategoryLink(tx, &link)
+		if err != nil {
+			failed(http.StatusInternalServerError, err)
+			return
+		}
 
-		body.Img = filename
+		link.Category = game.GameCategory
 
-		err = os.WriteFile(filename, data, 0644)
+		err = gameDao.InsertCategoryLink(tx, &link)
 		if err != nil {
-			log.Error(err.Error())
-			rw.JSON(http.StatusInternalServerError, nil)
+			failed(http.StatusInternalServerError, err)
 			return
 		}
 	}
 
-	// Add game
+	err = audit.Record(tx, r, auditModel.ActionUpdate, audit.EntityGame, game.ID, before, game)
+	if err != nil {
+		failed(http.StatusInternalServerError, err)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		failed(http.StatusInternalServerError, err)
+		return
+	}
+
+	if replaced {
+		removeGameImage(*before.Img)
+	}
+
+	rw.JSON(http.StatusOK, game)
+}
+
+// UpdateGame replaces a selected game in the database.
+func UpdateGame(rw server.ResponseWriter, r *server.Request) {
+	// Get game
+	before, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate game body
+	body, err := validateGameBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
 	game := gameModel.Game{
-		ID:           gameUuid,
+		ID:           before.ID,
 		GameCategory: &body.GameCategory,
 		Name:         &body.Name,
 		Alias:        &body.Alias,
 		Description:  &body.Description,
 		Highlight:    &body.Highlight,
-		Img:          &body.Img,
 		PlayerCount:  &body.PlayerCount,
 		Order:        &body.Order,
-		CreatedAt:    types.Ptr(time.Now().UTC()),
+		CreatedBy:    before.CreatedBy,
+		CreatedAt:    before.CreatedAt,
+	}
+
+	saveGame(rw, r, before, &game, body.Img)
+}
+
+// PatchGame changes the given fields of a selected game in the database.
+func PatchGame(rw server.ResponseWriter, r *server.Request) {
+	// Get game
+	before, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
 	}
 
-	err = gameDao.InsertGame(&game)
+	// Validate game body
+	body, err := validateGamePatchBody(r.R.Body)
 	if err != nil {
 		log.Error(err.Error())
 		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
-	rw.JSON(http.StatusOK, game)
+	game := *before
+	img := *before.Img
+
+	if body.GameCategory != nil {
+		game.GameCategory = body.GameCategory
+	}
+
+	if body.Name != nil {
+		game.Name = body.Name
+	}
+
+	if body.Alias != nil {
+		game.Alias = body.Alias
+	}
+
+	if body.Description != nil {
+		game.Description = body.Description
+	}
+
+	if body.Highlight != nil {
+		game.Highlight = body.Highlight
+	}
+
+	if body.Img != nil {
+		img = *body.Img
+	}
+
+	if body.PlayerCount != nil {
+		game.PlayerCount = body.PlayerCount
+	}
+
+	if body.Order != nil {
+		game.Order = body.Order
+	}
+
+	saveGame(rw, r, before, &game, img)
+}
+
+// DeleteGame deletes a game and its necessities in the database.
+func DeleteGame(rw server.ResponseWriter, r *server.Request) {
+	// Get game
+	game, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Delete game
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteNecessitiesByGame(tx, game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = gameDao.DeleteGame(tx, game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionDelete, audit.EntityGame, game.ID, game, nil)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	removeGameImage(*game.Img)
+
+	rw.JSON(http.StatusOK, nil)
 }
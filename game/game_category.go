@@ -4,11 +4,11 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/marvindeckmyn/drankspelletjes-server/auth"
-	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
 	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
 	"github.com/marvindeckmyn/drankspelletjes-server/log"
-	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
 	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
 	"github.com/marvindeckmyn/drankspelletjes-server/server"
 	"github.com/marvindeckmyn/drankspelletjes-server/types"
@@ -69,7 +69,7 @@ func GetCategories(rw server.ResponseWriter, r *server.Request) {
 		return
 	}
 
-	rw.JSON(http.StatusOK, categories)
+	writeLocalized(rw, r, categories)
 }
 
 // GetCategoryById to retrieve a category by UUID.
@@ -93,29 +93,11 @@ func GetCategoryById(rw server.ResponseWriter, r *server.Request) {
 		return
 	}
 
-	rw.JSON(http.StatusOK, category)
+	writeLocalized(rw, r, category)
 }
 
 // PostCategory inserts a category in the database.
 func PostCategory(rw server.ResponseWriter, r *server.Request) {
-	// Check account
-	accID, err := auth.GetID(r)
-	if err != nil {
-		log.Error(err.Error())
-		rw.JSON(http.StatusUnauthorized, nil)
-		return
-	}
-
-	acc := accountModel.Account{
-		ID: &accID,
-	}
-
-	err = accountDao.GetAccount(&acc)
-	if err != nil {
-		rw.JSON(http.StatusUnauthorized, nil)
-		return
-	}
-
 	// Validate category body
 	body, err := validateCategoryBody(r.R.Body)
 	if err != nil {
@@ -131,36 +113,34 @@ func PostCategory(rw server.ResponseWriter, r *server.Request) {
 		Order: &body.Order,
 	}
 
-	err = gameDao.InsertCategory(&category)
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertCategory(tx, &category)
 	if err != nil {
 		log.Error(err.Error())
 		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
-	rw.JSON(http.StatusOK, category)
-}
-
-// UpdateCategory updates a selected category the database.
-func UpdateCategory(rw server.ResponseWriter, r *server.Request) {
-	// Check account
-	accID, err := auth.GetID(r)
+	err = audit.Record(tx, r, auditModel.ActionCreate, audit.EntityCategory, category.ID, nil, category)
 	if err != nil {
 		log.Error(err.Error())
-		rw.JSON(http.StatusUnauthorized, nil)
+		rw.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 
-	acc := accountModel.Account{
-		ID: &accID,
-	}
-
-	err = accountDao.GetAccount(&acc)
+	err = tx.Exec()
 	if err != nil {
-		rw.JSON(http.StatusUnauthorized, nil)
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 
+	rw.JSON(http.StatusOK, category)
+}
+
+// UpdateCategory updates a selected category the database.
+func UpdateCategory(rw server.ResponseWriter, r *server.Request) {
 	// Validate category URL
 	url, err := validateCategoryURL(r)
 	if err != nil {
@@ -190,6 +170,7 @@ func UpdateCategory(rw server.ResponseWriter, r *server.Request) {
 	}
 
 	// Update category
+	before := category
 	category.Name = &body.Name
 	category.Order = &body.Order
 
@@ -197,36 +178,34 @@ func UpdateCategory(rw server.ResponseWriter, r *server.Request) {
 		"ID": category.ID,
 	}
 
-	err = gameDao.UpdateCategory(&category, selectors)
+	tx := cdb.NewTx()
+
+	err = gameDao.UpdateCategory(tx, &category, selectors)
 	if err != nil {
 		log.Error(err.Error())
 		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
-	rw.JSON(http.StatusOK, category)
-}
-
-// DeletCategory deletes a category in the database.
-func DeleteCategory(rw server.ResponseWriter, r *server.Request) {
-	// Check account
-	accID, err := auth.GetID(r)
+	err = audit.Record(tx, r, auditModel.ActionUpdate, audit.EntityCategory, category.ID, before, category)
 	if err != nil {
 		log.Error(err.Error())
-		rw.JSON(http.StatusUnauthorized, nil)
+		rw.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 
-	acc := accountModel.Account{
-		ID: &accID,
-	}
-
-	err = accountDao.GetAccount(&acc)
+	err = tx.Exec()
 	if err != nil {
-		rw.JSON(http.StatusUnauthorized, nil)
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
 		return
 	}
 
+	rw.JSON(http.StatusOK, category)
+}
+
+// DeletCategory deletes a category in the database.
+func DeleteCategory(rw server.ResponseWriter, r *server.Request) {
 	// Validate category URL
 	url, err := validateCategoryURL(r)
 	if err != nil {
@@ -248,12 +227,28 @@ func DeleteCategory(rw server.ResponseWriter, r *server.Request) {
 	}
 
 	// Delete category
-	err = gameDao.DeleteCategory(&category)
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteCategory(tx, &category)
 	if err != nil {
 		log.Error(err.Error())
 		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
+	err = audit.Record(tx, r, auditModel.ActionDelete, audit.EntityCategory, category.ID, category, nil)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
 	rw.JSON(http.StatusOK, nil)
 }
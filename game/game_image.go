@@ -0,0 +1,51 @@
+package game
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// saveGameImage decodes the base64 image and stores it as a new file for the game. Every upload
+// gets a new name, so the previous image stays intact until the change is committed.
+func saveGameImage(id uuid.UUID, img string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(img)
+	if err != nil {
+		return "", err
+	}
+
+	filename := fmt.Sprintf("img/game_%s_%d.png", id.String(), time.Now().UnixNano())
+
+	err = os.WriteFile(filename, data, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return filename, nil
+}
+
+// removeGameImage removes an image which is no longer used by its game.
+func removeGameImage(filename string) {
+	if filename == "" {
+		return
+	}
+
+	err := os.Remove(filename)
+	if err != nil && !os.IsNotExist(err) {
+		log.Error(err.Error())
+	}
+}
+
+// resolveGameImage returns the image the game should use. The current image is kept when the
+// client sends its path back, an empty string removes the image and base64 data replaces it.
+func resolveGameImage(id uuid.UUID, current string, img string) (string, error) {
+	if img == current || img == "" {
+		return img, nil
+	}
+
+	return saveGameImage(id, img)
+}
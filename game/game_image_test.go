@@ -0,0 +1,27 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+func TestResolveGameImage(t *testing.T) {
+	id := uuid.UUIDv4()
+	current := "img/game_" + id.String() + ".png"
+
+	img, err := resolveGameImage(id, current, current)
+	if err != nil || img != current {
+		t.Fatalf("expected the current image to be kept, got %q", img)
+	}
+
+	img, err = resolveGameImage(id, current, "")
+	if err != nil || img != "" {
+		t.Fatalf("expected the image to be removed, got %q", img)
+	}
+
+	_, err = resolveGameImage(id, current, "not base64!")
+	if err == nil {
+		t.Fatal("expected invalid image data to be rejected")
+	}
+}
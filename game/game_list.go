@@ -0,0 +1,198 @@
+package game
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// defaultListLimit is the page size of game listings when none was given.
+const defaultListLimit = 50
+
+// maxListLimit is the largest page size of game listings.
+const maxListLimit = 200
+
+// NextCursorHeader is the header which holds the cursor of the next page.
+const NextCursorHeader = "X-Next-Cursor"
+
+type GameListQuery struct {
+	MinPlayers     *int32     `json:"min_players,string"`
+	MaxPlayers     *int32     `json:"max_players,string"`
+	Highlight      *bool      `json:"highlight,string"`
+	HasNecessities *bool      `json:"has_necessities,string"`
+	CreatedAfter   *time.Time `json:"created_after"`
+	Tag            *string    `json:"tag"`
+	Sort           string     `json:"sort"`
+	Cursor         *string    `json:"cursor"`
+	Limit          int32      `json:"limit,string"`
+}
+
+// listCursor is the position after the last game of a page. The sort is part of the cursor, so it
+// can't be used with a different sort.
+type listCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// sortValue returns the value of the sort field of the game as a string.
+func sortValue(game *gameModel.Game, field string) string {
+	switch field {
+	case "player_count":
+		return strconv.Itoa(int(*game.PlayerCount))
+	case "created_at":
+		return game.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "rating":
+		return strconv.FormatFloat(*game.RatingAverage, 'g', -1, 64)
+	}
+
+	return strconv.Itoa(int(*game.Order))
+}
+
+// parseSortValue parses a value from sortValue back to the type of its column.
+func parseSortValue(field string, value string) (interface{}, error) {
+	switch field {
+	case "created_at":
+		return time.Parse(time.RFC3339Nano, value)
+	case "rating":
+		return strconv.ParseFloat(value, 64)
+	}
+
+	number, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return int32(number), nil
+}
+
+// splitSort splits a sort like "-created_at" in its field and direction.
+func splitSort(sort string) (string, bool) {
+	if strings.HasPrefix(sort, "-") {
+		return sort[1:], true
+	}
+
+	return sort, false
+}
+
+// encodeListCursor creates the cursor which starts the page after the given game.
+func encodeListCursor(game *gameModel.Game, sort string) string {
+	field, _ := splitSort(sort)
+
+	// Marshal a pointer, as the UUID only marshals to a string when it's addressable
+	encoded, _ := json.Marshal(&listCursor{
+		Sort:  sort,
+		Value: sortValue(game, field),
+		ID:    *game.ID,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeListCursor parses a cursor from encodeListCursor for the given sort.
+func decodeListCursor(cursor string, sort string) (interface{}, *uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, nil, &validator.ErrInvalidContent{Cause: "cursor"}
+	}
+
+	decoded := listCursor{}
+
+	err = json.Unmarshal(raw, &decoded)
+	if err != nil || decoded.Sort != sort {
+		return nil, nil, &validator.ErrInvalidContent{Cause: "cursor"}
+	}
+
+	field, _ := splitSort(sort)
+
+	value, err := parseSortValue(field, decoded.Value)
+	if err != nil {
+		return nil, nil, &validator.ErrInvalidContent{Cause: "cursor"}
+	}
+
+	return value, &decoded.ID, nil
+}
+
+// isOptBoolString checks that an optional query parameter is "true" or "false".
+func isOptBoolString(item interface{}) bool {
+	return item == nil || item == "true" || item == "false"
+}
+
+// validateGameListQuery checks if the query is valid and converts it to a filter.
+func validateGameListQuery(r *server.Request) (*gameDao.GameFilter, error) {
+	v := validator.V{
+		"min_players":     validator.IsOptInt,
+		"max_players":     validator.IsOptInt,
+		"highlight":       isOptBoolString,
+		"has_necessities": isOptBoolString,
+		"created_after":   validator.IsOptTimestamp,
+		"tag":             validator.IsOptString,
+		"sort":            validator.IsOptString,
+		"cursor":          validator.IsOptString,
+		"limit":           validator.IsOptInt,
+	}
+
+	query := GameListQuery{}
+
+	err := v.ValidateAndMarshalQuery(r, &query)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	if query.Sort == "" {
+		query.Sort = "order"
+	}
+
+	field, desc := splitSort(query.Sort)
+	if _, ok := gameDao.SortColumnsGame[field]; !ok {
+		return nil, &validator.ErrInvalidContent{Cause: "sort"}
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultListLimit
+	}
+
+	if query.Limit < 0 || query.Limit > maxListLimit {
+		return nil, &validator.ErrInvalidContent{Cause: "limit"}
+	}
+
+	if query.MinPlayers != nil && query.MaxPlayers != nil && *query.MinPlayers > *query.MaxPlayers {
+		return nil, &validator.ErrInvalidContent{Cause: "min_players, max_players"}
+	}
+
+	tags, err := parseUUIDList(query.Tag, "tag")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := gameDao.GameFilter{
+		Tags:           tags,
+		MinPlayers:     query.MinPlayers,
+		MaxPlayers:     query.MaxPlayers,
+		Highlight:      query.Highlight,
+		HasNecessities: query.HasNecessities,
+		CreatedAfter:   query.CreatedAfter,
+		Sort:           field,
+		Desc:           desc,
+		Limit:          query.Limit,
+	}
+
+	if query.Cursor != nil {
+		filter.AfterValue, filter.AfterID, err = decodeListCursor(*query.Cursor, query.Sort)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &filter, nil
+}
@@ -0,0 +1,98 @@
+package game
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// listRequest creates a request for a game listing with the given query.
+func listRequest(query string) *server.Request {
+	req := httptest.NewRequest("GET", "/api/game/category/x?"+query, nil)
+	return &server.Request{R: req, QueryParams: req.URL.Query()}
+}
+
+func TestValidateGameListQuery(t *testing.T) {
+	filter, err := validateGameListQuery(listRequest(
+		"min_players=2&max_players=6&highlight=true&has_necessities=false&sort=-created_at&limit=10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *filter.MinPlayers != 2 || *filter.MaxPlayers != 6 || !*filter.Highlight ||
+		*filter.HasNecessities || filter.Sort != "created_at" || !filter.Desc || filter.Limit != 10 {
+		t.Fatalf("unexpected filter %+v", filter)
+	}
+
+	filter, err = validateGameListQuery(listRequest(""))
+	if err != nil || filter.Sort != "order" || filter.Desc || filter.Limit != defaultListLimit {
+		t.Fatalf("unexpected default filter %+v", filter)
+	}
+
+	tag := uuid.UUIDv4()
+
+	filter, err = validateGameListQuery(listRequest("tag=" + tag.String()))
+	if err != nil || len(filter.Tags) != 1 || filter.Tags[0] != tag {
+		t.Fatalf("unexpected tag filter %+v", filter)
+	}
+
+	invalid := []string{
+		"tag=nope",
+		"sort=name",
+		"min_players=6&max_players=2",
+		"highlight=yes",
+		"limit=1000",
+		"created_after=yesterday",
+		"cursor=garbage",
+	}
+
+	for _, query := range invalid {
+		_, err := validateGameListQuery(listRequest(query))
+		if err == nil {
+			t.Fatalf("expected %s to be rejected", query)
+		}
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	game := gameModel.Game{
+		ID:          types.Ptr(uuid.UUIDv4()),
+		Order:       types.Ptr(int32(4)),
+		PlayerCount: types.Ptr(int32(3)),
+		CreatedAt:   types.Ptr(time.Date(2024, 5, 1, 12, 0, 0, 42, time.UTC)),
+	}
+
+	cursor := encodeListCursor(&game, "-created_at")
+
+	value, id, err := decodeListCursor(cursor, "-created_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !value.(time.Time).Equal(*game.CreatedAt) || *id != *game.ID {
+		t.Fatalf("unexpected cursor %v %v", value, id)
+	}
+
+	// A cursor can't be used with another sort
+	_, _, err = decodeListCursor(cursor, "order")
+	if err == nil {
+		t.Fatal("expected the cursor to be rejected for another sort")
+	}
+
+	value, _, err = decodeListCursor(encodeListCursor(&game, "order"), "order")
+	if err != nil || value.(int32) != 4 {
+		t.Fatalf("unexpected order cursor %v", value)
+	}
+
+	game.RatingAverage = types.Ptr(11.0 / 3)
+
+	value, _, err = decodeListCursor(encodeListCursor(&game, "-rating"), "-rating")
+	if err != nil || value.(float64) != *game.RatingAverage {
+		t.Fatalf("unexpected rating cursor %v", value)
+	}
+}
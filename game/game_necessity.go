@@ -4,11 +4,12 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/marvindeckmyn/drankspelletjes-server/auth"
-	accountDao "github.com/marvindeckmyn/drankspelletjes-server/dao/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
 	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
 	"github.com/marvindeckmyn/drankspelletjes-server/log"
-	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
 	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
 	"github.com/marvindeckmyn/drankspelletjes-server/server"
 	"github.com/marvindeckmyn/drankspelletjes-server/types"
@@ -21,6 +22,10 @@ type GameNecessityBody struct {
 	Name map[string]string `json:"name"`
 }
 
+type GameNecessityURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
 // validateGameNecessityBody checks if the body is valid.
 func validateGameNecessityBody(requestBody io.Reader) (*GameNecessityBody, error) {
 	v := validator.V{
@@ -39,23 +44,147 @@ func validateGameNecessityBody(requestBody io.Reader) (*GameNecessityBody, error
 	return &body, nil
 }
 
+// validateGameNecessityURL checks if the game necessity URL is valid.
+func validateGameNecessityURL(r *server.Request) (*GameNecessityURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := GameNecessityURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// checkGameExists returns the status to respond with when the game doesn't exist.
+func checkGameExists(id uuid.UUID) (int, error) {
+	game := gameModel.Game{
+		ID: &id,
+	}
+
+	err := gameDao.GetGame(&game)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return http.StatusNotFound, err
+		}
+
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// getGameNecessity fetches the game necessity from the URL. The status to respond with is returned
+// when it fails.
+func getGameNecessity(r *server.Request) (*gameModel.GameNecessity, int, error) {
+	url, err := validateGameNecessityURL(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	necessity := gameModel.GameNecessity{
+		ID: &url.ID,
+	}
+
+	err = gameDao.GetNecessity(&necessity)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, http.StatusNotFound, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return &necessity, http.StatusOK, nil
+}
+
+// GetGameNecessities to retrieve the necessities of a game.
+func GetGameNecessities(rw server.ResponseWriter, r *server.Request) {
+	game, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	necessities, err := gameDao.GetNecessitiesByGames([]*gameModel.Game{game})
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	gameNecessities := necessities[*game.ID]
+	if gameNecessities == nil {
+		gameNecessities = []*gameModel.GameNecessity{}
+	}
+
+	writeLocalized(rw, r, gameNecessities)
+}
+
 // PostGameNecessity inserts a game necessity in the database.
 func PostGameNecessity(rw server.ResponseWriter, r *server.Request) {
-	// Check account
-	accID, err := auth.GetID(r)
+	// Validate game necessity body
+	body, err := validateGameNecessityBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Check game
+	status, err := checkGameExists(body.Game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Add game necessity
+	gameNecessity := gameModel.GameNecessity{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Game: &body.Game,
+		Name: &body.Name,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertNecessity(tx, &gameNecessity)
 	if err != nil {
 		log.Error(err.Error())
-		rw.JSON(http.StatusUnauthorized, nil)
+		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
-	acc := accountModel.Account{
-		ID: &accID,
+	err = audit.Record(tx, r, auditModel.ActionCreate, audit.EntityNecessity, gameNecessity.ID, nil, gameNecessity)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
 	}
 
-	err = accountDao.GetAccount(&acc)
+	err = tx.Exec()
 	if err != nil {
-		rw.JSON(http.StatusUnauthorized, nil)
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, gameNecessity)
+}
+
+// UpdateGameNecessity updates a selected game necessity in the database.
+func UpdateGameNecessity(rw server.ResponseWriter, r *server.Request) {
+	// Get game necessity
+	before, status, err := getGameNecessity(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
 		return
 	}
 
@@ -67,19 +196,86 @@ func PostGameNecessity(rw server.ResponseWriter, r *server.Request) {
 		return
 	}
 
-	// Add game necessity
+	// Check game
+	status, err = checkGameExists(body.Game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Update game necessity
 	gameNecessity := gameModel.GameNecessity{
-		ID:   types.Ptr(uuid.UUIDv4()),
+		ID:   before.ID,
 		Game: &body.Game,
 		Name: &body.Name,
 	}
 
-	err = gameDao.InsertNecessity(&gameNecessity)
+	selectors := map[string]interface{}{
+		"ID": gameNecessity.ID,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.UpdateNecessity(tx, &gameNecessity, selectors)
 	if err != nil {
 		log.Error(err.Error())
 		rw.JSON(http.StatusBadRequest, nil)
 		return
 	}
 
+	err = audit.Record(tx, r, auditModel.ActionUpdate, audit.EntityNecessity, gameNecessity.ID,
+		before, gameNecessity)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
 	rw.JSON(http.StatusOK, gameNecessity)
 }
+
+// DeleteGameNecessity deletes a game necessity in the database.
+func DeleteGameNecessity(rw server.ResponseWriter, r *server.Request) {
+	// Get game necessity
+	gameNecessity, status, err := getGameNecessity(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Delete game necessity
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteNecessity(tx, gameNecessity)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionDelete, audit.EntityNecessity, gameNecessity.ID,
+		gameNecessity, nil)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,685 @@
+package game
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+type RuleBody struct {
+	Game      uuid.UUID         `json:"game"`
+	Variation *uuid.UUID        `json:"variation"`
+	Kind      string            `json:"kind"`
+	Order     int32             `json:"order"`
+	Text      map[string]string `json:"text"`
+}
+
+type RuleUpdateBody struct {
+	Kind  string            `json:"kind"`
+	Order int32             `json:"order"`
+	Text  map[string]string `json:"text"`
+}
+
+type RuleURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type RulesQuery struct {
+	Variation *uuid.UUID `json:"variation"`
+}
+
+type VariationBody struct {
+	Game        uuid.UUID          `json:"game"`
+	Name        map[string]string  `json:"name"`
+	Description *map[string]string `json:"description"`
+	Order       int32              `json:"order"`
+}
+
+type VariationUpdateBody struct {
+	Name        map[string]string  `json:"name"`
+	Description *map[string]string `json:"description"`
+	Order       int32              `json:"order"`
+}
+
+type VariationURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// isRuleKind checks if the item is one of the kinds of rules.
+func isRuleKind(item interface{}) bool {
+	for _, kind := range gameModel.RuleKinds {
+		if item == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateRuleBody checks if the body is valid.
+func validateRuleBody(requestBody io.Reader) (*RuleBody, error) {
+	v := validator.V{
+		"game":      validator.IsUUIDV4,
+		"variation": validator.IsOptUUIDV4,
+		"kind":      isRuleKind,
+		"order":     validator.IsInt,
+		"text":      validator.IsMapStrStr,
+	}
+
+	body := RuleBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validateRuleUpdateBody checks if the body is valid.
+func validateRuleUpdateBody(requestBody io.Reader) (*RuleUpdateBody, error) {
+	v := validator.V{
+		"kind":  isRuleKind,
+		"order": validator.IsInt,
+		"text":  validator.IsMapStrStr,
+	}
+
+	body := RuleUpdateBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validateRuleURL checks if the rule URL is valid.
+func validateRuleURL(r *server.Request) (*RuleURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := RuleURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// validateRulesQuery checks if the query of the rules is valid.
+func validateRulesQuery(r *server.Request) (*RulesQuery, error) {
+	v := validator.V{
+		"variation": validator.IsOptUUIDV4,
+	}
+
+	query := RulesQuery{}
+
+	err := v.ValidateAndMarshalQuery(r, &query)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &query, nil
+}
+
+// validateVariationBody checks if the body is valid.
+func validateVariationBody(requestBody io.Reader) (*VariationBody, error) {
+	v := validator.V{
+		"game":        validator.IsUUIDV4,
+		"name":        validator.IsMapStrStr,
+		"description": validator.IsOptMapStrStr,
+		"order":       validator.IsInt,
+	}
+
+	body := VariationBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validateVariationUpdateBody checks if the body is valid.
+func validateVariationUpdateBody(requestBody io.Reader) (*VariationUpdateBody, error) {
+	v := validator.V{
+		"name":        validator.IsMapStrStr,
+		"description": validator.IsOptMapStrStr,
+		"order":       validator.IsInt,
+	}
+
+	body := VariationUpdateBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validateVariationURL checks if the variation URL is valid.
+func validateVariationURL(r *server.Request) (*VariationURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := VariationURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// getVariation fetches the variation with the given ID. The status to respond with is returned
+// when it fails.
+func getVariation(id uuid.UUID) (*gameModel.GameVariation, int, error) {
+	variation := gameModel.GameVariation{
+		ID: &id,
+	}
+
+	err := gameDao.GetVariation(&variation)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, http.StatusNotFound, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return &variation, http.StatusOK, nil
+}
+
+// getRule fetches the rule from the URL. The status to respond with is returned when it fails.
+func getRule(r *server.Request) (*gameModel.GameRule, int, error) {
+	url, err := validateRuleURL(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	rule := gameModel.GameRule{
+		ID: &url.ID,
+	}
+
+	err = gameDao.GetRule(&rule)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, http.StatusNotFound, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return &rule, http.StatusOK, nil
+}
+
+// buildGameRules groups the rules by kind as they are played in the variation. A variation only
+// replaces the base rules of the kinds it has rules for, the other kinds are played as usual.
+func buildGameRules(game uuid.UUID, rules []*gameModel.GameRule, variations []*gameModel.GameVariation,
+	variation *uuid.UUID) *gameModel.GameRules {
+
+	base := map[string][]*gameModel.GameRule{}
+	varied := map[string][]*gameModel.GameRule{}
+
+	for _, rule := range rules {
+		if rule.Variation == nil {
+			base[*rule.Kind] = append(base[*rule.Kind], rule)
+		} else if variation != nil && *rule.Variation == *variation {
+			varied[*rule.Kind] = append(varied[*rule.Kind], rule)
+		}
+	}
+
+	kind := func(kind string) []*gameModel.GameRule {
+		if len(varied[kind]) > 0 {
+			return varied[kind]
+		}
+
+		if base[kind] == nil {
+			return []*gameModel.GameRule{}
+		}
+
+		return base[kind]
+	}
+
+	return &gameModel.GameRules{
+		Game:       &game,
+		Variation:  variation,
+		Setup:      kind(gameModel.RuleSetup),
+		Turn:       kind(gameModel.RuleTurn),
+		Win:        kind(gameModel.RuleWin),
+		Lose:       kind(gameModel.RuleLose),
+		Variations: variations,
+	}
+}
+
+// GetGameRules to retrieve the rules of a game as a walkthrough. With the variation parameter the
+// rules are returned as they are played in that variation.
+func GetGameRules(rw server.ResponseWriter, r *server.Request) {
+	game, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	query, err := validateRulesQuery(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	variations, err := gameDao.GetVariationsByGame(game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if query.Variation != nil {
+		found := false
+		for _, variation := range variations {
+			if *variation.ID == *query.Variation {
+				found = true
+			}
+		}
+
+		if !found {
+			log.Error("Variation %s isn't a variation of game %s", query.Variation, game.ID)
+			rw.JSON(http.StatusNotFound, nil)
+			return
+		}
+	}
+
+	rules, err := gameDao.GetRulesByGame(game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	gameRules := buildGameRules(*game.ID, rules, variations, query.Variation)
+
+	writeLocalized(rw, r, gameRules)
+}
+
+// PostGameRule inserts a rule in the database. A rule with a variation has to belong to a variation
+// of the same game.
+func PostGameRule(rw server.ResponseWriter, r *server.Request) {
+	// Validate rule body
+	body, err := validateRuleBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	status, err := checkGameExists(body.Game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	if body.Variation != nil {
+		variation, status, err := getVariation(*body.Variation)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(status, nil)
+			return
+		}
+
+		if *variation.Game != body.Game {
+			log.Error("Variation %s isn't a variation of game %s", variation.ID, body.Game)
+			rw.JSON(http.StatusBadRequest, nil)
+			return
+		}
+	}
+
+	// Add rule
+	rule := gameModel.GameRule{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Game:      &body.Game,
+		Variation: body.Variation,
+		Kind:      &body.Kind,
+		Order:     &body.Order,
+		Text:      &body.Text,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertRule(tx, &rule)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionCreate, audit.EntityRule, rule.ID, nil, rule)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, rule)
+}
+
+// UpdateGameRule updates a selected rule in the database. The game and variation of a rule can't
+// change.
+func UpdateGameRule(rw server.ResponseWriter, r *server.Request) {
+	// Get rule
+	before, status, err := getRule(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate rule body
+	body, err := validateRuleUpdateBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Update rule
+	rule := *before
+	rule.Kind = &body.Kind
+	rule.Order = &body.Order
+	rule.Text = &body.Text
+
+	selectors := map[string]interface{}{
+		"ID": rule.ID,
+	}
+
+	tx := cdb.NewTx()
+
+	changes := gameModel.GameRule{
+		Kind:  rule.Kind,
+		Order: rule.Order,
+		Text:  rule.Text,
+	}
+
+	err = gameDao.UpdateRule(tx, &changes, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionUpdate, audit.EntityRule, rule.ID, before, rule)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, rule)
+}
+
+// DeleteGameRule deletes a rule in the database.
+func DeleteGameRule(rw server.ResponseWriter, r *server.Request) {
+	// Get rule
+	rule, status, err := getRule(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Delete rule
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteRule(tx, rule)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionDelete, audit.EntityRule, rule.ID, rule, nil)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// GetGameVariations to retrieve the variations of a game.
+func GetGameVariations(rw server.ResponseWriter, r *server.Request) {
+	game, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	variations, err := gameDao.GetVariationsByGame(game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	writeLocalized(rw, r, variations)
+}
+
+// PostGameVariation inserts a variation in the database.
+func PostGameVariation(rw server.ResponseWriter, r *server.Request) {
+	// Validate variation body
+	body, err := validateVariationBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	status, err := checkGameExists(body.Game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Add variation
+	description := map[string]string{}
+	if body.Description != nil {
+		description = *body.Description
+	}
+
+	variation := gameModel.GameVariation{
+		ID:          types.Ptr(uuid.UUIDv4()),
+		Game:        &body.Game,
+		Name:        &body.Name,
+		Description: &description,
+		Order:       &body.Order,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertVariation(tx, &variation)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionCreate, audit.EntityVariation, variation.ID, nil, variation)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, variation)
+}
+
+// UpdateGameVariation updates a selected variation in the database.
+func UpdateGameVariation(rw server.ResponseWriter, r *server.Request) {
+	// Validate variation URL
+	url, err := validateVariationURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Validate variation body
+	body, err := validateVariationUpdateBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Get variation
+	before, status, err := getVariation(url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Update variation
+	description := map[string]string{}
+	if body.Description != nil {
+		description = *body.Description
+	}
+
+	variation := *before
+	variation.Name = &body.Name
+	variation.Description = &description
+	variation.Order = &body.Order
+
+	selectors := map[string]interface{}{
+		"ID": variation.ID,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.UpdateVariation(tx, &variation, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionUpdate, audit.EntityVariation, variation.ID, before, variation)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, variation)
+}
+
+// DeleteGameVariation deletes a variation in the database together with its rules.
+func DeleteGameVariation(rw server.ResponseWriter, r *server.Request) {
+	// Validate variation URL
+	url, err := validateVariationURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Get variation
+	variation, status, err := getVariation(url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Delete variation
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteVariation(tx, variation)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionDelete, audit.EntityVariation, variation.ID, variation, nil)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
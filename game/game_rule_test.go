@@ -0,0 +1,69 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// rule creates a rule of the given kind, in the variation when one is given.
+func rule(kind string, variation *uuid.UUID) *gameModel.GameRule {
+	return &gameModel.GameRule{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Variation: variation,
+		Kind:      types.Ptr(kind),
+		Text:      &map[string]string{"en": kind},
+	}
+}
+
+func TestBuildGameRules(t *testing.T) {
+	game := uuid.UUIDv4()
+	variation := types.Ptr(uuid.UUIDv4())
+	other := types.Ptr(uuid.UUIDv4())
+
+	setup := rule(gameModel.RuleSetup, nil)
+	turn := rule(gameModel.RuleTurn, nil)
+	variedTurn := rule(gameModel.RuleTurn, variation)
+	otherLose := rule(gameModel.RuleLose, other)
+
+	rules := []*gameModel.GameRule{setup, turn, variedTurn, otherLose}
+
+	base := buildGameRules(game, rules, nil, nil)
+	if len(base.Setup) != 1 || base.Turn[0] != turn || len(base.Lose) != 0 || base.Win == nil {
+		t.Fatalf("unexpected base rules %+v", base)
+	}
+
+	varied := buildGameRules(game, rules, nil, variation)
+
+	// The variation replaces the turn, the setup is played as usual
+	if varied.Setup[0] != setup || len(varied.Turn) != 1 || varied.Turn[0] != variedTurn {
+		t.Fatalf("unexpected varied rules %+v", varied)
+	}
+
+	if len(varied.Lose) != 0 {
+		t.Fatal("expected the rules of other variations to be left out")
+	}
+}
+
+func TestValidateRuleBody(t *testing.T) {
+	game := uuid.UUIDv4().String()
+
+	body, err := validateRuleBody(strings.NewReader(
+		`{"game": "` + game + `", "kind": "turn", "order": 2, "text": {"nl": "Trek een kaart"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body.Kind != gameModel.RuleTurn || body.Variation != nil || body.Order != 2 {
+		t.Fatalf("unexpected body %+v", body)
+	}
+
+	_, err = validateRuleBody(strings.NewReader(
+		`{"game": "` + game + `", "kind": "ending", "order": 1, "text": {}}`))
+	if err == nil {
+		t.Fatal("expected an unknown kind to be rejected")
+	}
+}
@@ -0,0 +1,525 @@
+package game
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+type TagBody struct {
+	Name map[string]string `json:"name"`
+}
+
+type TagURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type GameTagURL struct {
+	ID  uuid.UUID `json:"id"`
+	Tag uuid.UUID `json:"tag"`
+}
+
+type GameCategoryURL struct {
+	ID       uuid.UUID `json:"id"`
+	Category uuid.UUID `json:"category"`
+}
+
+// validateTagBody checks if the body is valid.
+func validateTagBody(requestBody io.Reader) (*TagBody, error) {
+	v := validator.V{
+		"name": validator.IsMapStrStr,
+	}
+
+	body := TagBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validateTagURL checks if the tag URL is valid.
+func validateTagURL(r *server.Request) (*TagURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := TagURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// validateGameTagURL checks if the URL of a tag on a game is valid.
+func validateGameTagURL(r *server.Request) (*GameTagURL, error) {
+	v := validator.V{
+		"id":  validator.IsUUIDV4,
+		"tag": validator.IsUUIDV4,
+	}
+
+	url := GameTagURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// validateGameCategoryURL checks if the URL of a category of a game is valid.
+func validateGameCategoryURL(r *server.Request) (*GameCategoryURL, error) {
+	v := validator.V{
+		"id":       validator.IsUUIDV4,
+		"category": validator.IsUUIDV4,
+	}
+
+	url := GameCategoryURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// getTag fetches the tag with the given ID. The status to respond with is returned when it fails.
+func getTag(id uuid.UUID) (*gameModel.GameTag, int, error) {
+	tag := gameModel.GameTag{
+		ID: &id,
+	}
+
+	err := gameDao.GetTag(&tag)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, http.StatusNotFound, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return &tag, http.StatusOK, nil
+}
+
+// GetTags to retrieve all the tags.
+func GetTags(rw server.ResponseWriter, r *server.Request) {
+	tags, err := gameDao.GetTags()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	writeLocalized(rw, r, tags)
+}
+
+// GetTag to retrieve a tag by UUID.
+func GetTag(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateTagURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	tag, status, err := getTag(url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	writeLocalized(rw, r, tag)
+}
+
+// PostTag inserts a tag in the database.
+func PostTag(rw server.ResponseWriter, r *server.Request) {
+	// Validate tag body
+	body, err := validateTagBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Add tag
+	tag := gameModel.GameTag{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: &body.Name,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertTag(tx, &tag)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionCreate, audit.EntityTag, tag.ID, nil, tag)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, tag)
+}
+
+// UpdateTag updates a selected tag in the database.
+func UpdateTag(rw server.ResponseWriter, r *server.Request) {
+	// Validate tag URL
+	url, err := validateTagURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Validate tag body
+	body, err := validateTagBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Get tag
+	before, status, err := getTag(url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Update tag
+	tag := gameModel.GameTag{
+		ID:   before.ID,
+		Name: &body.Name,
+	}
+
+	selectors := map[string]interface{}{
+		"ID": tag.ID,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.UpdateTag(tx, &tag, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionUpdate, audit.EntityTag, tag.ID, before, tag)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag deletes a tag in the database, it is removed from all its games.
+func DeleteTag(rw server.ResponseWriter, r *server.Request) {
+	// Validate tag URL
+	url, err := validateTagURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Get tag
+	tag, status, err := getTag(url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Delete tag
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteTag(tx, tag)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionDelete, audit.EntityTag, tag.ID, tag, nil)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// PutGameTag puts a tag on a game. Putting a tag on a game which already has it changes nothing.
+func PutGameTag(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateGameTagURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	status, err := checkGameExists(url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	_, status, err = getTag(url.Tag)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Link tag
+	link := gameModel.GameTagLink{
+		Game: &url.ID,
+		Tag:  &url.Tag,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertTagLink(tx, &link)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionCreate, audit.EntityGameTag, link.Game, nil, link)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, link)
+}
+
+// DeleteGameTag removes a tag from a game.
+func DeleteGameTag(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateGameTagURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Unlink tag
+	link := gameModel.GameTagLink{
+		Game: &url.ID,
+		Tag:  &url.Tag,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteTagLink(tx, &link)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionDelete, audit.EntityGameTag, link.Game, link, nil)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// PutGameCategory adds a game to another category. Adding a game to a category it is already in
+// changes nothing.
+func PutGameCategory(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateGameCategoryURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	status, err := checkGameExists(url.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	category := gameModel.GameCategory{
+		ID: &url.Category,
+	}
+
+	err = gameDao.GetCategory(&category)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	// Link category
+	link := gameModel.GameCategoryLink{
+		Game:     &url.ID,
+		Category: &url.Category,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertCategoryLink(tx, &link)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionCreate, audit.EntityGameCategory, link.Game, nil, link)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, link)
+}
+
+// DeleteGameCategory removes a game from a category. A game can't be removed from its primary
+// category, the game has to be moved to another one first.
+func DeleteGameCategory(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateGameCategoryURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	game := gameModel.Game{
+		ID: &url.ID,
+	}
+
+	err = gameDao.GetGame(&game)
+	if err != nil {
+		log.Error(err.Error())
+
+		if dao.IsMissingResult(err) {
+			rw.JSON(http.StatusNotFound, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if game.GameCategory != nil && *game.GameCategory == url.Category {
+		log.Error("Game %s can't be removed from its primary category", game.ID)
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Unlink category
+	link := gameModel.GameCategoryLink{
+		Game:     &url.ID,
+		Category: &url.Category,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.DeleteCategoryLink(tx, &link)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = audit.Record(tx, r, auditModel.ActionDelete, audit.EntityGameCategory, link.Game, link, nil)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
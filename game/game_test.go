@@ -0,0 +1,27 @@
+package game
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+)
+
+func TestIncludes(t *testing.T) {
+	cases := map[string]bool{
+		"/api/game/x":                                  false,
+		"/api/game/x?include=necessities":              true,
+		"/api/game/x?include=tags,%20necessities":      true,
+		"/api/game/x?include=tags&include=necessities": true,
+		"/api/game/x?include=necessity":                false,
+	}
+
+	for target, expected := range cases {
+		req := httptest.NewRequest("GET", target, nil)
+		r := &server.Request{R: req, QueryParams: req.URL.Query()}
+
+		if includes(r, "necessities") != expected {
+			t.Fatalf("expected includes for %s to be %t", target, expected)
+		}
+	}
+}
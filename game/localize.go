@@ -0,0 +1,25 @@
+package game
+
+import (
+	"net/http"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/locale"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+)
+
+// writeLocalized responds with the content and all its translations. When the request asks for a
+// language with lang or for localize=true, the translations are resolved to a single string.
+func writeLocalized(rw server.ResponseWriter, r *server.Request, content interface{}) {
+	if !locale.IsLocalized(r) {
+		rw.JSON(http.StatusOK, content)
+		return
+	}
+
+	l := locale.FromRequest(r)
+	localized := l.Flatten(content)
+
+	// Caches have to keep the languages apart
+	rw.W.Header().Add("Vary", "Accept-Language")
+	l.SetHeader(rw)
+	rw.JSON(http.StatusOK, localized)
+}
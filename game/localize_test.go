@@ -0,0 +1,83 @@
+package game
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/locale"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// writeGame responds with the game to a request with the given query and Accept-Language header.
+func writeGame(game *gameModel.Game, query string, acceptLanguage string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/game/x?"+query, nil)
+	req.Header.Set("Accept-Language", acceptLanguage)
+
+	rec := httptest.NewRecorder()
+	r := &server.Request{R: req, QueryParams: req.URL.Query()}
+
+	writeLocalized(server.ResponseWriter{W: rec}, r, game)
+
+	return rec
+}
+
+func TestWriteLocalized(t *testing.T) {
+	game := gameModel.Game{
+		ID:          types.Ptr(uuid.UUIDv4()),
+		Name:        &map[string]string{"en": "Kings", "nl": "Koningen"},
+		Description: &map[string]string{"en": "Draw a card"},
+		Categories:  &[]uuid.UUID{uuid.UUIDv4()},
+		Tags:        &[]*gameModel.GameTag{{ID: types.Ptr(uuid.UUIDv4()), Name: &map[string]string{"nl": "Klassieker"}}},
+	}
+
+	// The translations are returned unless a language is asked for
+	rec := writeGame(&game, "", "nl-BE")
+
+	raw := gameModel.Game{}
+	json.Unmarshal(rec.Body.Bytes(), &raw)
+
+	if (*raw.Name)["nl"] != "Koningen" || rec.Header().Get(locale.ContentLanguageHeader) != "" {
+		t.Fatalf("expected the raw game, got %s", rec.Body.String())
+	}
+
+	rec = writeGame(&game, "localize=true", "nl-BE,en;q=0.5")
+
+	localized := struct {
+		ID          *uuid.UUID  `json:"id"`
+		Name        *string     `json:"name"`
+		Alias       *string     `json:"alias"`
+		Description *string     `json:"description"`
+		Categories  []uuid.UUID `json:"categories"`
+		Tags        []*struct {
+			Name *string `json:"name"`
+		} `json:"tags"`
+	}{}
+	json.Unmarshal(rec.Body.Bytes(), &localized)
+
+	if *localized.ID != *game.ID || *localized.Name != "Koningen" || *localized.Description != "Draw a card" ||
+		localized.Alias != nil || localized.Categories[0] != (*game.Categories)[0] ||
+		*localized.Tags[0].Name != "Klassieker" {
+		t.Fatalf("unexpected localized game %s", rec.Body.String())
+	}
+
+	if rec.Header().Get(locale.ContentLanguageHeader) != "nl, en" {
+		t.Fatalf("unexpected locales %s", rec.Header().Get(locale.ContentLanguageHeader))
+	}
+
+	// Fields which aren't set are left out like in the raw game
+	if strings.Contains(rec.Body.String(), "is_favorite") || strings.Contains(rec.Body.String(), "necessities") {
+		t.Fatalf("expected the empty fields to be left out, got %s", rec.Body.String())
+	}
+
+	rec = writeGame(&game, "lang=en", "nl-BE")
+	json.Unmarshal(rec.Body.Bytes(), &localized)
+
+	if *localized.Name != "Kings" {
+		t.Fatalf("expected the lang parameter to flatten, got %s", rec.Body.String())
+	}
+}
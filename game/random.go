@@ -0,0 +1,344 @@
+package game
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// SeedHeader is the header which holds the seed of a random pick, so the pick can be repeated.
+const SeedHeader = "X-Random-Seed"
+
+// highlightWeight is how much more likely a highlighted game is picked.
+const highlightWeight = 3.0
+
+// unratedWeight is the rating weight of games which weren't rated yet, the middle of the scale.
+const unratedWeight = 3.0
+
+type RandomGameQuery struct {
+	Category       *uuid.UUID `json:"category"`
+	MinPlayers     *int32     `json:"min_players,string"`
+	MaxPlayers     *int32     `json:"max_players,string"`
+	Highlight      *bool      `json:"highlight,string"`
+	HasNecessities *bool      `json:"has_necessities,string"`
+	Tag            *string    `json:"tag"`
+	Necessities    *string    `json:"necessities"`
+	Exclude        *string    `json:"exclude"`
+	Recent         *string    `json:"recent"`
+	Weight         *string    `json:"weight"`
+	Seed           *int64     `json:"seed,string"`
+}
+
+// randomPick holds everything a pick depends on besides the candidates.
+type randomPick struct {
+	// Necessities are the normalized names of the available necessities, nil when any are fine.
+	Necessities map[string]bool
+	Exclude     map[uuid.UUID]bool
+
+	// Recent are the recently played games, the most recent first.
+	Recent  []uuid.UUID
+	Weights []string
+	Seed    int64
+}
+
+// weightFunc returns how likely the game is picked compared to a game with weight 1.
+type weightFunc func(game *gameModel.Game, pick *randomPick) float64
+
+// randomWeights are the weightings which can be asked for by name.
+var randomWeights = map[string]weightFunc{
+	"highlight": weightHighlight,
+	"recency":   weightRecency,
+	"rating":    weightRating,
+}
+
+// loadRandomCandidates fetches the games which match the filter together with their necessities.
+func loadRandomCandidates(filter gameDao.GameFilter) (
+	[]*gameModel.Game, map[uuid.UUID][]*gameModel.GameNecessity, error) {
+
+	games, err := gameDao.GetGamesFiltered(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	necessities, err := gameDao.GetNecessitiesByGames(games)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return games, necessities, nil
+}
+
+// weightHighlight favours highlighted games.
+func weightHighlight(game *gameModel.Game, pick *randomPick) float64 {
+	if game.Highlight != nil && *game.Highlight {
+		return highlightWeight
+	}
+
+	return 1
+}
+
+// weightRecency makes recently played games less likely, the more recent the less likely.
+func weightRecency(game *gameModel.Game, pick *randomPick) float64 {
+	for i, id := range pick.Recent {
+		if id == *game.ID {
+			return float64(i+1) / float64(len(pick.Recent)+1)
+		}
+	}
+
+	return 1
+}
+
+// weightRating favours games with a better average rating.
+func weightRating(game *gameModel.Game, pick *randomPick) float64 {
+	if game.RatingCount == nil || *game.RatingCount == 0 || game.RatingAverage == nil {
+		return unratedWeight
+	}
+
+	return *game.RatingAverage
+}
+
+// splitList splits a comma separated query parameter, leaving out empty items.
+func splitList(list *string) []string {
+	items := []string{}
+	if list == nil {
+		return items
+	}
+
+	for _, item := range strings.Split(*list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseUUIDList parses a comma separated list of UUIDs.
+func parseUUIDList(list *string, key string) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+
+	for _, item := range splitList(list) {
+		if !validator.IsUUIDV4(item) {
+			return nil, &validator.ErrInvalidContent{Cause: key}
+		}
+
+		id, err := uuid.FromString(item)
+		if err != nil {
+			return nil, &validator.ErrInvalidContent{Cause: key}
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// validateRandomGameQuery checks if the query is valid and splits it in the filter for the database
+// and the pick which happens in Go.
+func validateRandomGameQuery(r *server.Request) (*gameDao.GameFilter, *randomPick, error) {
+	v := validator.V{
+		"category":        validator.IsOptUUIDV4,
+		"min_players":     validator.IsOptInt,
+		"max_players":     validator.IsOptInt,
+		"highlight":       isOptBoolString,
+		"has_necessities": isOptBoolString,
+		"tag":             validator.IsOptString,
+		"necessities":     validator.IsOptString,
+		"exclude":         validator.IsOptString,
+		"recent":          validator.IsOptString,
+		"weight":          validator.IsOptString,
+		"seed":            validator.IsOptInt,
+	}
+
+	query := RandomGameQuery{}
+
+	err := v.ValidateAndMarshalQuery(r, &query)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, nil, err
+	}
+
+	if query.MinPlayers != nil && query.MaxPlayers != nil && *query.MinPlayers > *query.MaxPlayers {
+		return nil, nil, &validator.ErrInvalidContent{Cause: "min_players, max_players"}
+	}
+
+	pick := randomPick{
+		Exclude: map[uuid.UUID]bool{},
+		Weights: splitList(query.Weight),
+		Seed:    time.Now().UnixNano(),
+	}
+
+	if query.Seed != nil {
+		pick.Seed = *query.Seed
+	}
+
+	for _, weight := range pick.Weights {
+		if _, ok := randomWeights[weight]; !ok {
+			return nil, nil, &validator.ErrInvalidContent{Cause: "weight"}
+		}
+	}
+
+	if query.Necessities != nil {
+		pick.Necessities = map[string]bool{}
+
+		for _, name := range splitList(query.Necessities) {
+			pick.Necessities[normalizeText(name)] = true
+		}
+	}
+
+	exclude, err := parseUUIDList(query.Exclude, "exclude")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, id := range exclude {
+		pick.Exclude[id] = true
+	}
+
+	pick.Recent, err = parseUUIDList(query.Recent, "recent")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tags, err := parseUUIDList(query.Tag, "tag")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Fetch all the candidates in a stable order, so the same seed picks the same game
+	filter := gameDao.GameFilter{
+		Category:       query.Category,
+		Tags:           tags,
+		MinPlayers:     query.MinPlayers,
+		MaxPlayers:     query.MaxPlayers,
+		Highlight:      query.Highlight,
+		HasNecessities: query.HasNecessities,
+		Sort:           "order",
+	}
+
+	return &filter, &pick, nil
+}
+
+// hasNecessities checks if all the necessities of a game are available. A necessity is available
+// when its name in any language is.
+func hasNecessities(necessities []*gameModel.GameNecessity, available map[string]bool) bool {
+	for _, necessity := range necessities {
+		found := false
+
+		if necessity.Name != nil {
+			for _, name := range *necessity.Name {
+				if available[normalizeText(name)] {
+					found = true
+					break
+				}
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pickGame picks one of the games at random according to the weights of the pick. Nil is returned
+// when none of the games can be picked.
+func pickGame(games []*gameModel.Game, necessities map[uuid.UUID][]*gameModel.GameNecessity,
+	pick *randomPick) *gameModel.Game {
+
+	candidates := []*gameModel.Game{}
+	weights := []float64{}
+	total := 0.0
+
+	for _, game := range games {
+		if pick.Exclude[*game.ID] {
+			continue
+		}
+
+		if pick.Necessities != nil && !hasNecessities(necessities[*game.ID], pick.Necessities) {
+			continue
+		}
+
+		weight := 1.0
+		for _, name := range pick.Weights {
+			weight *= randomWeights[name](game, pick)
+		}
+
+		if weight <= 0 {
+			continue
+		}
+
+		candidates = append(candidates, game)
+		weights = append(weights, weight)
+		total += weight
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	target := rand.New(rand.NewSource(pick.Seed)).Float64() * total
+
+	for i, weight := range weights {
+		target -= weight
+		if target < 0 {
+			return candidates[i]
+		}
+	}
+
+	return candidates[len(candidates)-1]
+}
+
+// GetRandomGame picks a random game which matches the filters. The seed of the pick is returned in
+// a header, passing it back picks the same game as long as the catalog didn't change.
+func GetRandomGame(rw server.ResponseWriter, r *server.Request) {
+	filter, pick, err := validateRandomGameQuery(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	games, necessities, err := loadRandomCandidates(*filter)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	game := pickGame(games, necessities, pick)
+	if game == nil {
+		rw.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	if includes(r, "necessities") {
+		gameNecessities := necessities[*game.ID]
+		if gameNecessities == nil {
+			gameNecessities = []*gameModel.GameNecessity{}
+		}
+
+		game.Necessities = &gameNecessities
+	}
+
+	err = loadFavorites(r, []*gameModel.Game{game})
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.W.Header().Set(SeedHeader, strconv.FormatInt(pick.Seed, 10))
+	writeLocalized(rw, r, game)
+}
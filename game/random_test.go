@@ -0,0 +1,128 @@
+package game
+
+import (
+	"testing"
+
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// randomGames creates games to pick from, the first one is highlighted.
+func randomGames(count int) []*gameModel.Game {
+	games := []*gameModel.Game{}
+
+	for i := 0; i < count; i++ {
+		games = append(games, &gameModel.Game{
+			ID:        types.Ptr(uuid.UUIDv4()),
+			Highlight: types.Ptr(i == 0),
+		})
+	}
+
+	return games
+}
+
+func TestPickGameSeed(t *testing.T) {
+	games := randomGames(20)
+	pick := randomPick{Exclude: map[uuid.UUID]bool{}, Seed: 42}
+
+	first := pickGame(games, nil, &pick)
+	for i := 0; i < 10; i++ {
+		if pickGame(games, nil, &pick) != first {
+			t.Fatal("expected the same seed to pick the same game")
+		}
+	}
+}
+
+func TestPickGameExclude(t *testing.T) {
+	games := randomGames(2)
+	pick := randomPick{Exclude: map[uuid.UUID]bool{*games[0].ID: true}}
+
+	for seed := int64(0); seed < 20; seed++ {
+		pick.Seed = seed
+		if pickGame(games, nil, &pick) != games[1] {
+			t.Fatal("expected the excluded game to be skipped")
+		}
+	}
+
+	pick.Exclude[*games[1].ID] = true
+	if pickGame(games, nil, &pick) != nil {
+		t.Fatal("expected no game when all of them are excluded")
+	}
+}
+
+func TestPickGameNecessities(t *testing.T) {
+	games := randomGames(2)
+	necessities := map[uuid.UUID][]*gameModel.GameNecessity{
+		*games[0].ID: {{Name: &map[string]string{"nl": "Speelkaarten", "en": "Playing cards"}}},
+		*games[1].ID: {{Name: &map[string]string{"nl": "Dobbelstenen", "en": "Dice"}}},
+	}
+
+	pick := randomPick{
+		Exclude:     map[uuid.UUID]bool{},
+		Necessities: map[string]bool{normalizeText("speelkaarten"): true},
+	}
+
+	for seed := int64(0); seed < 20; seed++ {
+		pick.Seed = seed
+		if pickGame(games, necessities, &pick) != games[0] {
+			t.Fatal("expected only the game with available necessities to be picked")
+		}
+	}
+}
+
+func TestPickGameWeights(t *testing.T) {
+	games := randomGames(2)
+	pick := randomPick{Exclude: map[uuid.UUID]bool{}, Weights: []string{"highlight"}}
+
+	highlighted := 0
+	for seed := int64(0); seed < 1000; seed++ {
+		pick.Seed = seed
+		if pickGame(games, nil, &pick) == games[0] {
+			highlighted++
+		}
+	}
+
+	// The highlighted game has a weight of 3 against 1, so it should be picked about 750 times
+	if highlighted < 650 || highlighted > 850 {
+		t.Fatalf("expected the highlighted game about 750 times, got %d", highlighted)
+	}
+
+	pick.Recent = []uuid.UUID{*games[1].ID, *games[0].ID}
+	if weightRecency(games[1], &pick) >= weightRecency(games[0], &pick) {
+		t.Fatal("expected the most recent game to weigh the least")
+	}
+
+	if weightRecency(&gameModel.Game{ID: types.Ptr(uuid.UUIDv4())}, &pick) != 1 {
+		t.Fatal("expected games which weren't played to weigh 1")
+	}
+}
+
+func TestValidateRandomGameQuery(t *testing.T) {
+	id := uuid.UUIDv4().String()
+
+	filter, pick, err := validateRandomGameQuery(listRequest(
+		"min_players=2&necessities=Dice,%20Cards&exclude=" + id + "&weight=highlight,recency&seed=7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *filter.MinPlayers != 2 || filter.Limit != 0 || pick.Seed != 7 || len(pick.Exclude) != 1 ||
+		!pick.Necessities["cards"] || len(pick.Weights) != 2 {
+		t.Fatalf("unexpected query %+v %+v", filter, pick)
+	}
+
+	invalid := []string{
+		"weight=popularity",
+		"exclude=nope",
+		"recent=" + id + ",nope",
+		"seed=abc",
+	}
+
+	for _, query := range invalid {
+		_, _, err := validateRandomGameQuery(listRequest(query))
+		if err == nil {
+			t.Fatalf("expected %s to be rejected", query)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package game
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+type ReorderBody struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// validateReorderBody checks if the body is valid. An ID can only be in the list once.
+func validateReorderBody(requestBody io.Reader) (*ReorderBody, error) {
+	v := validator.V{
+		"ids": validator.IsStringSlice,
+	}
+
+	body := ReorderBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, id := range body.IDs {
+		if seen[id] {
+			return nil, &validator.ErrInvalidContent{Cause: "ids"}
+		}
+
+		seen[id] = true
+	}
+
+	return &body, nil
+}
+
+// sameIDs checks if the ordered IDs are exactly the current IDs. When they aren't, the list was
+// made from an outdated view.
+func sameIDs(ids []uuid.UUID, current []uuid.UUID) bool {
+	if len(ids) != len(current) {
+		return false
+	}
+
+	existing := map[uuid.UUID]bool{}
+	for _, id := range current {
+		existing[id] = true
+	}
+
+	for _, id := range ids {
+		if !existing[id] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// positions maps the IDs to their order, starting from 1.
+func positions(ids []uuid.UUID) map[uuid.UUID]int32 {
+	orders := map[uuid.UUID]int32{}
+	for i, id := range ids {
+		orders[id] = int32(i + 1)
+	}
+
+	return orders
+}
+
+// ReorderCategories rewrites the order of all the categories at once. The body has to list every
+// category exactly once.
+func ReorderCategories(rw server.ResponseWriter, r *server.Request) {
+	// Validate reorder body
+	body, err := validateReorderBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Get categories
+	categories, err := gameDao.GetCategories()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	current := []uuid.UUID{}
+	for _, category := range categories {
+		current = append(current, *category.ID)
+	}
+
+	if !sameIDs(body.IDs, current) {
+		log.Error("The categories to reorder don't match the current categories")
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Update the changed orders, unless the categories changed since they were read
+	orders := positions(body.IDs)
+	tx := cdb.NewTx()
+
+	err = gameDao.GuardCategoryIDs(tx, body.IDs)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	for _, category := range categories {
+		order := orders[*category.ID]
+		if category.Order != nil && *category.Order == order {
+			continue
+		}
+
+		before := *category
+		category.Order = types.Ptr(order)
+
+		selectors := map[string]interface{}{
+			"ID": category.ID,
+		}
+
+		err = gameDao.UpdateCategory(tx, &gameModel.GameCategory{Order: category.Order}, selectors)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+
+		err = audit.Record(tx, r, auditModel.ActionUpdate, audit.EntityCategory, category.ID, before, category)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+
+		if dao.IsGuardFailed(err) {
+			rw.JSON(http.StatusConflict, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	ordered := make([]*gameModel.GameCategory, len(categories))
+	for _, category := range categories {
+		ordered[*category.Order-1] = category
+	}
+
+	rw.JSON(http.StatusOK, ordered)
+}
+
+// ReorderGames rewrites the order of all the games of a category at once, the games keep their
+// place in their other categories. The body has to list every game of the category exactly once.
+func ReorderGames(rw server.ResponseWriter, r *server.Request) {
+	// Validate category URL
+	url, err := validateCategoryURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Validate reorder body
+	body, err := validateReorderBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Get category
+	category := gameModel.GameCategory{
+		ID: &url.ID,
+	}
+
+	err = gameDao.GetCategory(&category)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	// Get games
+	games, err := gameDao.GetGames(category.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	current := []uuid.UUID{}
+	for _, game := range games {
+		current = append(current, *game.ID)
+	}
+
+	if !sameIDs(body.IDs, current) {
+		log.Error("The games to reorder don't match the games of category %s", category.ID)
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Update the changed orders, unless the games changed since they were read
+	orders := positions(body.IDs)
+	tx := cdb.NewTx()
+
+	err = gameDao.GuardCategoryGames(tx, &category, body.IDs)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	for _, game := range games {
+		order := orders[*game.ID]
+		if game.Order != nil && *game.Order == order {
+			continue
+		}
+
+		before := gameModel.GameCategoryLink{Game: game.ID, Category: category.ID, Order: game.Order}
+		game.Order = types.Ptr(order)
+		link := gameModel.GameCategoryLink{Game: game.ID, Category: category.ID, Order: game.Order}
+
+		err = gameDao.UpdateCategoryLink(tx, &link)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+
+		err = audit.Record(tx, r, auditModel.ActionUpdate, audit.EntityGameCategory, game.ID, before, link)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+
+		if dao.IsGuardFailed(err) {
+			rw.JSON(http.StatusConflict, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	ordered := make([]*gameModel.Game, len(games))
+	for _, game := range games {
+		ordered[*game.Order-1] = game
+	}
+
+	rw.JSON(http.StatusOK, ordered)
+}
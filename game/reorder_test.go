@@ -0,0 +1,52 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+func TestValidateReorderBody(t *testing.T) {
+	first, second := uuid.UUIDv4().String(), uuid.UUIDv4().String()
+
+	body, err := validateReorderBody(strings.NewReader(`{"ids": ["` + first + `", "` + second + `"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.IDs) != 2 || body.IDs[1].String() != second {
+		t.Fatalf("unexpected body %+v", body)
+	}
+
+	invalid := []string{
+		`{"ids": ["` + first + `", "` + first + `"]}`,
+		`{"ids": ["nope"]}`,
+		`{"ids": "` + first + `"}`,
+		`{}`,
+	}
+
+	for _, raw := range invalid {
+		_, err := validateReorderBody(strings.NewReader(raw))
+		if err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestSameIDs(t *testing.T) {
+	a, b, c := uuid.UUIDv4(), uuid.UUIDv4(), uuid.UUIDv4()
+
+	if !sameIDs([]uuid.UUID{b, a}, []uuid.UUID{a, b}) {
+		t.Fatal("expected a reordering of the same IDs to match")
+	}
+
+	if sameIDs([]uuid.UUID{a}, []uuid.UUID{a, b}) || sameIDs([]uuid.UUID{a, c}, []uuid.UUID{a, b}) {
+		t.Fatal("expected missing or unknown IDs not to match")
+	}
+
+	orders := positions([]uuid.UUID{b, a})
+	if orders[b] != 1 || orders[a] != 2 {
+		t.Fatalf("unexpected positions %v", orders)
+	}
+}
@@ -0,0 +1,478 @@
+package game
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
+	auditModel "github.com/marvindeckmyn/drankspelletjes-server/model/audit"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// maxReviewLength is the largest number of characters of a review text, in every language.
+const maxReviewLength = 500
+
+// maxReportReasonLength is the largest number of characters of the reason of a report.
+const maxReportReasonLength = 500
+
+// defaultReviewLimit is the page size of review listings when none was given.
+const defaultReviewLimit = 20
+
+// maxReviewLimit is the largest page size of review listings.
+const maxReviewLimit = 100
+
+type ReviewBody struct {
+	Rating int32              `json:"rating"`
+	Text   *map[string]string `json:"text"`
+}
+
+type ReviewURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type ReviewListQuery struct {
+	Cursor *string `json:"cursor"`
+	Limit  int32   `json:"limit,string"`
+}
+
+type ReviewReportBody struct {
+	Reason *string `json:"reason"`
+}
+
+// reviewCursor is the position after the last review of a page.
+type reviewCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// validateReviewBody checks if the body is valid. Blank translations are left out of the text.
+func validateReviewBody(requestBody io.Reader) (*ReviewBody, error) {
+	v := validator.V{
+		"rating": validator.IsInt,
+		"text":   validator.IsOptMapStrStr,
+	}
+
+	body := ReviewBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	if body.Rating < gameModel.MinRating || body.Rating > gameModel.MaxRating {
+		return nil, &validator.ErrInvalidContent{Cause: "rating"}
+	}
+
+	text := map[string]string{}
+
+	if body.Text != nil {
+		for lang, value := range *body.Text {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+
+			if utf8.RuneCountInString(value) > maxReviewLength {
+				return nil, &validator.ErrInvalidContent{Cause: "text"}
+			}
+
+			text[strings.ToLower(lang)] = value
+		}
+	}
+
+	body.Text = &text
+
+	return &body, nil
+}
+
+// validateReviewURL checks if the review URL is valid.
+func validateReviewURL(r *server.Request) (*ReviewURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := ReviewURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// validateReviewReportBody checks if the body is valid.
+func validateReviewReportBody(requestBody io.Reader) (*ReviewReportBody, error) {
+	v := validator.V{
+		"reason": validator.IsOptString,
+	}
+
+	body := ReviewReportBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	reason := ""
+	if body.Reason != nil {
+		reason = strings.TrimSpace(*body.Reason)
+	}
+
+	if utf8.RuneCountInString(reason) > maxReportReasonLength {
+		return nil, &validator.ErrInvalidContent{Cause: "reason"}
+	}
+
+	body.Reason = &reason
+
+	return &body, nil
+}
+
+// encodeReviewCursor creates the cursor which starts the page after the given review.
+func encodeReviewCursor(review *gameModel.GameReview) string {
+	// Marshal a pointer, as the UUID only marshals to a string when it's addressable
+	encoded, _ := json.Marshal(&reviewCursor{
+		CreatedAt: *review.CreatedAt,
+		ID:        *review.ID,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeReviewCursor parses a cursor from encodeReviewCursor.
+func decodeReviewCursor(cursor string) (*time.Time, *uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, nil, &validator.ErrInvalidContent{Cause: "cursor"}
+	}
+
+	decoded := reviewCursor{}
+
+	err = json.Unmarshal(raw, &decoded)
+	if err != nil {
+		return nil, nil, &validator.ErrInvalidContent{Cause: "cursor"}
+	}
+
+	return &decoded.CreatedAt, &decoded.ID, nil
+}
+
+// validateReviewListQuery checks if the query is valid.
+func validateReviewListQuery(r *server.Request) (*ReviewListQuery, error) {
+	v := validator.V{
+		"cursor": validator.IsOptString,
+		"limit":  validator.IsOptInt,
+	}
+
+	query := ReviewListQuery{}
+
+	err := v.ValidateAndMarshalQuery(r, &query)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultReviewLimit
+	}
+
+	if query.Limit < 0 || query.Limit > maxReviewLimit {
+		return nil, &validator.ErrInvalidContent{Cause: "limit"}
+	}
+
+	return &query, nil
+}
+
+// getReview fetches the review from the URL. The status to respond with is returned when it fails.
+func getReview(r *server.Request) (*gameModel.GameReview, int, error) {
+	url, err := validateReviewURL(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	review := gameModel.GameReview{
+		ID: &url.ID,
+	}
+
+	err = gameDao.GetReview(&review)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, http.StatusNotFound, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return &review, http.StatusOK, nil
+}
+
+// isAdmin checks whether the account is an admin.
+func isAdmin(acc *accountModel.Account) bool {
+	return acc.Role != nil && *acc.Role == accountModel.RoleAdmin
+}
+
+// GetGameReviews to retrieve the reviews of a game, the newest first. The reviews are returned in
+// pages, the cursor of the next page is in X-Next-Cursor.
+func GetGameReviews(rw server.ResponseWriter, r *server.Request) {
+	// Get game
+	game, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	query, err := validateReviewListQuery(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	var afterTime *time.Time
+	var afterID *uuid.UUID
+
+	if query.Cursor != nil {
+		afterTime, afterID, err = decodeReviewCursor(*query.Cursor)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusBadRequest, nil)
+			return
+		}
+	}
+
+	// Get reviews
+	reviews, err := gameDao.GetReviewsByGame(game, afterTime, afterID, query.Limit)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if int32(len(reviews)) == query.Limit {
+		rw.W.Header().Set(NextCursorHeader, encodeReviewCursor(reviews[len(reviews)-1]))
+	}
+
+	writeLocalized(rw, r, reviews)
+}
+
+// PutGameReview creates the review of the caller for a game or edits it when it already exists.
+func PutGameReview(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	// Get game
+	game, status, err := getGame(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate review body
+	body, err := validateReviewBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Add the review, or edit it when the account already reviewed the game
+	now := time.Now().UTC()
+
+	review := gameModel.GameReview{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Game:      game.ID,
+		Account:   acc.ID,
+		Rating:    &body.Rating,
+		Text:      body.Text,
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.LockGame(tx, game.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = gameDao.UpsertReview(tx, &review)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = gameDao.UpdateRatingAggregates(tx, game.ID)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	// An edited review keeps its ID and creation time
+	stored := gameModel.GameReview{
+		Game:    game.ID,
+		Account: acc.ID,
+	}
+
+	err = gameDao.GetReview(&stored)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, stored)
+}
+
+// DeleteGameReview deletes a review. Accounts can delete their own reviews, admins can delete every
+// review, which is audited.
+func DeleteGameReview(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	// Get review
+	review, status, err := getReview(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	own := review.Account != nil && *review.Account == *acc.ID
+	if !own && !isAdmin(acc) {
+		rw.JSON(http.StatusForbidden, nil)
+		return
+	}
+
+	// Delete review
+	tx := cdb.NewTx()
+
+	err = gameDao.LockGame(tx, review.Game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = gameDao.DeleteReview(tx, review)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = gameDao.UpdateRatingAggregates(tx, review.Game)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if !own {
+		err = audit.Record(tx, r, auditModel.ActionDelete, audit.EntityReview, review.ID, review, nil)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// PostReviewReport reports an abusive review. Every account can report a review once, reporting it
+// again changes nothing.
+func PostReviewReport(rw server.ResponseWriter, r *server.Request) {
+	acc := r.Account()
+
+	// Get review
+	review, status, err := getReview(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	if review.Account != nil && *review.Account == *acc.ID {
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Validate report body
+	body, err := validateReviewReportBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Add report
+	report := gameModel.GameReviewReport{
+		Review:  review.ID,
+		Account: acc.ID,
+		Reason:  body.Reason,
+	}
+
+	tx := cdb.NewTx()
+
+	err = gameDao.InsertReviewReport(tx, &report)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// GetReportedReviews to retrieve the reviews which were reported, the most reported first.
+func GetReportedReviews(rw server.ResponseWriter, r *server.Request) {
+	reviews, err := gameDao.GetReportedReviews()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	writeLocalized(rw, r, reviews)
+}
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+func TestValidateReviewBody(t *testing.T) {
+	body, err := validateReviewBody(strings.NewReader(
+		`{"rating": 4, "text": {"NL": "  Leuk spel ", "en": " "}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Blank translations are left out, locales are lower case
+	if body.Rating != 4 || len(*body.Text) != 1 || (*body.Text)["nl"] != "Leuk spel" {
+		t.Fatalf("unexpected body %+v", body)
+	}
+
+	body, err = validateReviewBody(strings.NewReader(`{"rating": 1}`))
+	if err != nil || len(*body.Text) != 0 {
+		t.Fatalf("expected a rating without text to be valid, got %v", err)
+	}
+
+	invalid := []string{
+		`{"rating": 0}`,
+		`{"rating": 6}`,
+		`{"text": {"en": "No rating"}}`,
+		`{"rating": 3, "text": {"en": "` + strings.Repeat("a", maxReviewLength+1) + `"}}`,
+	}
+
+	for _, raw := range invalid {
+		_, err := validateReviewBody(strings.NewReader(raw))
+		if err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestReviewCursor(t *testing.T) {
+	review := gameModel.GameReview{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		CreatedAt: types.Ptr(time.Date(2024, 5, 1, 12, 0, 0, 42, time.UTC)),
+	}
+
+	createdAt, id, err := decodeReviewCursor(encodeReviewCursor(&review))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !createdAt.Equal(*review.CreatedAt) || *id != *review.ID {
+		t.Fatalf("unexpected cursor %v %v", createdAt, id)
+	}
+
+	_, _, err = decodeReviewCursor("garbage")
+	if err == nil {
+		t.Fatal("expected an invalid cursor to be rejected")
+	}
+}
+
+func TestValidateReviewListQuery(t *testing.T) {
+	query, err := validateReviewListQuery(listRequest(""))
+	if err != nil || query.Limit != defaultReviewLimit {
+		t.Fatalf("unexpected default query %+v", query)
+	}
+
+	_, err = validateReviewListQuery(listRequest("limit=1000"))
+	if err == nil {
+		t.Fatal("expected a too large limit to be rejected")
+	}
+}
+
+func TestWeightRating(t *testing.T) {
+	unrated := gameModel.Game{
+		RatingCount:   types.Ptr(int32(0)),
+		RatingAverage: types.Ptr(0.0),
+	}
+
+	rated := gameModel.Game{
+		RatingCount:   types.Ptr(int32(2)),
+		RatingAverage: types.Ptr(4.5),
+	}
+
+	if weightRating(&unrated, nil) != unratedWeight || weightRating(&rated, nil) != 4.5 {
+		t.Fatal("unexpected rating weights")
+	}
+}
@@ -0,0 +1,199 @@
+package game
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+	"golang.org/x/text/unicode/norm"
+)
+
+// similarityThreshold is the lowest word similarity which counts as a match, like the default of
+// pg_trgm.
+const similarityThreshold = 0.3
+
+// defaultSearchLimit is the amount of results when no limit was given.
+const defaultSearchLimit = 20
+
+// maxSearchLimit is the largest amount of results.
+const maxSearchLimit = 100
+
+// minSubstringLength is the shortest normalized query which scores a literal match. Shorter queries
+// are found in nearly every text.
+const minSubstringLength = 3
+
+// Weights of the fields, a match in the name counts more than one in the description.
+const (
+	nameWeight        = 3.0
+	aliasWeight       = 2.0
+	descriptionWeight = 1.0
+)
+
+// searchWeights are the weights by the name of the field.
+var searchWeights = map[string]float64{
+	"name":        nameWeight,
+	"alias":       aliasWeight,
+	"description": descriptionWeight,
+}
+
+type SearchQuery struct {
+	Q        string     `json:"q"`
+	Locale   *string    `json:"locale"`
+	Category *uuid.UUID `json:"category"`
+	Limit    int32      `json:"limit,string"`
+}
+
+// SearchResult is a game together with how well it matched the query.
+type SearchResult struct {
+	Game  *gameModel.Game `json:"game"`
+	Score float64         `json:"score"`
+}
+
+// normalizeText lowercases the text and strips its accents, so "Kingscüp" matches "kingscup".
+func normalizeText(text string) string {
+	builder := strings.Builder{}
+
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune(' ')
+		}
+	}
+
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+// substringPattern returns the like pattern which literally matches the normalized query, nil when
+// the query is too short. Normalized text has no wildcards to escape.
+func substringPattern(query string) *string {
+	if len([]rune(query)) < minSubstringLength {
+		return nil
+	}
+
+	pattern := "%" + query + "%"
+	return &pattern
+}
+
+// searchTexts returns the normalized texts of the game which are searched.
+func searchTexts(game *gameModel.Game) []*gameModel.GameSearchText {
+	texts := []*gameModel.GameSearchText{}
+
+	fields := []struct {
+		name         string
+		translations *map[string]string
+	}{
+		{"name", game.Name},
+		{"alias", game.Alias},
+		{"description", game.Description},
+	}
+
+	for _, field := range fields {
+		if field.translations == nil {
+			continue
+		}
+
+		for lang, text := range *field.translations {
+			text = normalizeText(text)
+			if text == "" {
+				continue
+			}
+
+			texts = append(texts, &gameModel.GameSearchText{
+				Game:   game.ID,
+				Locale: types.Ptr(lang),
+				Field:  types.Ptr(field.name),
+				Text:   types.Ptr(text),
+			})
+		}
+	}
+
+	return texts
+}
+
+// validateSearchQuery checks if the search query is valid.
+func validateSearchQuery(r *server.Request) (*SearchQuery, error) {
+	v := validator.V{
+		"q":        validator.IsString,
+		"locale":   validator.IsOptString,
+		"category": validator.IsOptUUIDV4,
+		"limit":    validator.IsOptInt,
+	}
+
+	query := SearchQuery{}
+
+	err := v.ValidateAndMarshalQuery(r, &query)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	if normalizeText(query.Q) == "" {
+		return nil, &validator.ErrInvalidContent{Cause: "q"}
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultSearchLimit
+	}
+
+	if query.Limit < 0 || query.Limit > maxSearchLimit {
+		return nil, &validator.ErrInvalidContent{Cause: "limit"}
+	}
+
+	return &query, nil
+}
+
+// SearchGames searches the names, aliases and descriptions of the games. Results are ranked by
+// trigram similarity and tolerate typos.
+func SearchGames(rw server.ResponseWriter, r *server.Request) {
+	query, err := validateSearchQuery(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	q := normalizeText(query.Q)
+
+	matches, err := gameDao.SearchGames(gameDao.GameSearch{
+		Query:     q,
+		Pattern:   substringPattern(q),
+		Threshold: similarityThreshold,
+		Locale:    query.Locale,
+		Category:  query.Category,
+		Weights:   searchWeights,
+		Limit:     query.Limit,
+	})
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	results := []*SearchResult{}
+	found := []*gameModel.Game{}
+	for _, match := range matches {
+		results = append(results, &SearchResult{Game: match.Game, Score: match.Score})
+		found = append(found, match.Game)
+	}
+
+	err = loadFavorites(r, found)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	writeLocalized(rw, r, results)
+}
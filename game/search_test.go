@@ -0,0 +1,66 @@
+package game
+
+import (
+	"testing"
+
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// searchGames is a small catalog of games to search.
+func searchGames() []*gameModel.Game {
+	game := func(name map[string]string, alias map[string]string, description map[string]string) *gameModel.Game {
+		return &gameModel.Game{
+			ID:          types.Ptr(uuid.UUIDv4()),
+			Name:        &name,
+			Alias:       &alias,
+			Description: &description,
+		}
+	}
+
+	return []*gameModel.Game{
+		game(map[string]string{"nl": "Koningsbeker", "en": "Kings Cup"},
+			map[string]string{"en": "Ring of Fire"},
+			map[string]string{"en": "Draw cards from a circle around a cup."}),
+		game(map[string]string{"nl": "Bussen", "en": "Ride the Bus"},
+			map[string]string{},
+			map[string]string{"en": "Guess the colour of the cards."}),
+		game(map[string]string{"nl": "Piramide", "en": "Pyramid"},
+			map[string]string{},
+			map[string]string{"nl": "Een piramide van kaarten, de beker in het midden."}),
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	if normalizeText("  Kingscüp!  Ré-vanche ") != "kingscup re vanche" {
+		t.Fatalf("unexpected normalized text %q", normalizeText("  Kingscüp!  Ré-vanche "))
+	}
+}
+
+func TestSubstringPattern(t *testing.T) {
+	// Two letters are found in nearly every text, so they don't score a literal match
+	if substringPattern("ca") != nil {
+		t.Fatal("expected no pattern for a short query")
+	}
+
+	pattern := substringPattern("cards")
+	if pattern == nil || *pattern != "%cards%" {
+		t.Fatalf("unexpected pattern %v", pattern)
+	}
+}
+
+func TestSearchTexts(t *testing.T) {
+	game := searchGames()[1]
+
+	texts := searchTexts(game)
+	if len(texts) != 3 {
+		t.Fatalf("expected a text per translation, got %d", len(texts))
+	}
+
+	for _, text := range texts {
+		if *text.Text != normalizeText(*text.Text) {
+			t.Fatalf("expected a normalized text, got %q", *text.Text)
+		}
+	}
+}
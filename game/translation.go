@@ -0,0 +1,377 @@
+package game
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/locale"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+type TranslationQuery struct {
+	Locales *string `json:"locales"`
+	Format  *string `json:"format"`
+}
+
+// LocaleCompleteness is how many values of a field are translated in a locale.
+type LocaleCompleteness struct {
+	Translated int     `json:"translated"`
+	Missing    int     `json:"missing"`
+	Percentage float64 `json:"percentage"`
+}
+
+// FieldCompleteness is how complete the translations of a field are, per locale.
+type FieldCompleteness struct {
+	Total   int                            `json:"total"`
+	Locales map[string]*LocaleCompleteness `json:"locales"`
+}
+
+// MissingTranslation is a field of an entity which lacks translations. The translations it does
+// have are included as a reference for translators.
+type MissingTranslation struct {
+	Entity       string            `json:"entity"`
+	ID           uuid.UUID         `json:"id"`
+	Field        string            `json:"field"`
+	Locales      []string          `json:"locales"`
+	Translations map[string]string `json:"translations"`
+}
+
+// EntityTranslations is how complete the translations of an entity type are.
+type EntityTranslations struct {
+	Total   int                           `json:"total"`
+	Fields  map[string]*FieldCompleteness `json:"fields"`
+	Missing []*MissingTranslation         `json:"missing"`
+}
+
+// TranslationReport is how complete the translations of the catalog are in the supported locales.
+type TranslationReport struct {
+	Locales  []string                       `json:"locales"`
+	Entities map[string]*EntityTranslations `json:"entities"`
+}
+
+// localizedField is a localized field of an entity. Optional fields without any translation aren't
+// reported, as there is nothing to translate.
+type localizedField struct {
+	entity   string
+	id       *uuid.UUID
+	field    string
+	values   *map[string]string
+	optional bool
+}
+
+// translatedEntities are all the entities which have translations.
+type translatedEntities struct {
+	Categories  []*gameModel.GameCategory
+	Games       []*gameModel.Game
+	Necessities map[uuid.UUID][]*gameModel.GameNecessity
+	Tags        []*gameModel.GameTag
+	Rules       []*gameModel.GameRule
+	Variations  []*gameModel.GameVariation
+}
+
+// loadTranslationEntities fetches everything which is translated.
+func loadTranslationEntities() (*translatedEntities, error) {
+	categories, err := gameDao.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	games, err := gameDao.GetGames(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	necessities, err := gameDao.GetNecessitiesByGames(games)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := gameDao.GetTags()
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := gameDao.GetRules()
+	if err != nil {
+		return nil, err
+	}
+
+	variations, err := gameDao.GetVariations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &translatedEntities{categories, games, necessities, tags, rules, variations}, nil
+}
+
+// localizedFields lists the localized fields of the entities, grouped by entity.
+func localizedFields(entities *translatedEntities) []localizedField {
+	fields := []localizedField{}
+
+	for _, category := range entities.Categories {
+		fields = append(fields, localizedField{audit.EntityCategory, category.ID, "name", category.Name, false})
+	}
+
+	for _, game := range entities.Games {
+		fields = append(fields,
+			localizedField{audit.EntityGame, game.ID, "name", game.Name, false},
+			localizedField{audit.EntityGame, game.ID, "alias", game.Alias, true},
+			localizedField{audit.EntityGame, game.ID, "description", game.Description, false},
+		)
+	}
+
+	for _, game := range entities.Games {
+		for _, necessity := range entities.Necessities[*game.ID] {
+			fields = append(fields,
+				localizedField{audit.EntityNecessity, necessity.ID, "name", necessity.Name, false})
+		}
+	}
+
+	for _, tag := range entities.Tags {
+		fields = append(fields, localizedField{audit.EntityTag, tag.ID, "name", tag.Name, false})
+	}
+
+	for _, rule := range entities.Rules {
+		fields = append(fields, localizedField{audit.EntityRule, rule.ID, "text", rule.Text, false})
+	}
+
+	for _, variation := range entities.Variations {
+		fields = append(fields,
+			localizedField{audit.EntityVariation, variation.ID, "name", variation.Name, false},
+			localizedField{audit.EntityVariation, variation.ID, "description", variation.Description, true},
+		)
+	}
+
+	return fields
+}
+
+// translations returns the non blank translations of a field, keyed by their lower case locale.
+func translations(values *map[string]string) map[string]string {
+	translated := map[string]string{}
+	if values == nil {
+		return translated
+	}
+
+	for locale, value := range *values {
+		if strings.TrimSpace(value) != "" {
+			translated[strings.ToLower(locale)] = value
+		}
+	}
+
+	return translated
+}
+
+// buildTranslationReport reports how complete the translations of the fields are in the locales.
+func buildTranslationReport(locales []string, fields []localizedField) *TranslationReport {
+	report := TranslationReport{
+		Locales:  locales,
+		Entities: map[string]*EntityTranslations{},
+	}
+
+	entityTypes := []string{
+		audit.EntityCategory,
+		audit.EntityGame,
+		audit.EntityNecessity,
+		audit.EntityTag,
+		audit.EntityRule,
+		audit.EntityVariation,
+	}
+
+	for _, entity := range entityTypes {
+		report.Entities[entity] = &EntityTranslations{
+			Fields:  map[string]*FieldCompleteness{},
+			Missing: []*MissingTranslation{},
+		}
+	}
+
+	counted := map[uuid.UUID]bool{}
+
+	for _, field := range fields {
+		entity := report.Entities[field.entity]
+		if !counted[*field.id] {
+			counted[*field.id] = true
+			entity.Total++
+		}
+
+		translated := translations(field.values)
+		if field.optional && len(translated) == 0 {
+			continue
+		}
+
+		completeness := entity.Fields[field.field]
+		if completeness == nil {
+			completeness = &FieldCompleteness{Locales: map[string]*LocaleCompleteness{}}
+			for _, locale := range locales {
+				completeness.Locales[locale] = &LocaleCompleteness{}
+			}
+
+			entity.Fields[field.field] = completeness
+		}
+
+		completeness.Total++
+		missing := []string{}
+
+		for _, locale := range locales {
+			if _, ok := translated[locale]; ok {
+				completeness.Locales[locale].Translated++
+			} else {
+				completeness.Locales[locale].Missing++
+				missing = append(missing, locale)
+			}
+		}
+
+		if len(missing) > 0 {
+			entity.Missing = append(entity.Missing, &MissingTranslation{
+				Entity:       field.entity,
+				ID:           *field.id,
+				Field:        field.field,
+				Locales:      missing,
+				Translations: translated,
+			})
+		}
+	}
+
+	for _, entity := range report.Entities {
+		for _, completeness := range entity.Fields {
+			for _, locale := range completeness.Locales {
+				locale.Percentage = float64(locale.Translated) / float64(completeness.Total) * 100
+			}
+		}
+	}
+
+	return &report
+}
+
+// writeTranslationCSV writes a row for every missing translation, with a column per supported
+// locale holding the existing translations and an empty column for the translator to fill in.
+func writeTranslationCSV(rw server.ResponseWriter, report *TranslationReport) error {
+	rw.W.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	rw.W.Header().Set("Content-Disposition", `attachment; filename="missing-translations.csv"`)
+	rw.W.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(rw.W)
+
+	header := []string{"entity", "id", "field", "locale"}
+	header = append(header, report.Locales...)
+	header = append(header, "translation")
+
+	err := w.Write(header)
+	if err != nil {
+		return err
+	}
+
+	entities := []string{}
+	for entity := range report.Entities {
+		entities = append(entities, entity)
+	}
+
+	sort.Strings(entities)
+
+	for _, entity := range entities {
+		for _, missing := range report.Entities[entity].Missing {
+			for _, missingLocale := range missing.Locales {
+				row := []string{missing.Entity, missing.ID.String(), missing.Field, missingLocale}
+				for _, locale := range report.Locales {
+					row = append(row, missing.Translations[locale])
+				}
+
+				err = w.Write(append(row, ""))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// validateTranslationQuery checks if the query is valid and returns the locales to report on. The
+// configured supported locales are used when none are given.
+func validateTranslationQuery(r *server.Request) (*TranslationQuery, []string, error) {
+	v := validator.V{
+		"locales": validator.IsOptString,
+		"format":  validator.IsOptString,
+	}
+
+	query := TranslationQuery{}
+
+	err := v.ValidateAndMarshalQuery(r, &query)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, nil, err
+	}
+
+	if query.Format != nil && *query.Format != "json" && *query.Format != "csv" {
+		return nil, nil, &validator.ErrInvalidContent{Cause: "format"}
+	}
+
+	locales := locale.SupportedLocales()
+
+	if query.Locales != nil {
+		locales = []string{}
+		seen := map[string]bool{}
+
+		for _, item := range splitList(query.Locales) {
+			item = strings.ToLower(item)
+			if !seen[item] {
+				seen[item] = true
+				locales = append(locales, item)
+			}
+		}
+
+		if len(locales) == 0 {
+			return nil, nil, &validator.ErrInvalidContent{Cause: "locales"}
+		}
+	}
+
+	return &query, locales, nil
+}
+
+// GetTranslationReport reports how complete the translations of the categories, games, necessities,
+// tags, rules and variations are. With format=csv the missing translations are exported for
+// translators.
+func GetTranslationReport(rw server.ResponseWriter, r *server.Request) {
+	reportTranslations(rw, r, loadTranslationEntities)
+}
+
+// reportTranslations writes the translation report of the entities which are loaded by load.
+func reportTranslations(rw server.ResponseWriter, r *server.Request,
+	load func() (*translatedEntities, error)) {
+
+	query, locales, err := validateTranslationQuery(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	entities, err := load()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	report := buildTranslationReport(locales, localizedFields(entities))
+
+	if query.Format != nil && *query.Format == "csv" {
+		err = writeTranslationCSV(rw, report)
+		if err != nil {
+			log.Error(err.Error())
+		}
+
+		return
+	}
+
+	rw.JSON(http.StatusOK, report)
+}
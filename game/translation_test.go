@@ -0,0 +1,113 @@
+package game
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// translationCatalog creates a catalog where the game lacks a French description.
+func translationCatalog() *translatedEntities {
+	categories := []*gameModel.GameCategory{{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: &map[string]string{"nl": "Kaartspelen", "fr": "Jeux de cartes"},
+	}}
+
+	games := []*gameModel.Game{{
+		ID:          types.Ptr(uuid.UUIDv4()),
+		Name:        &map[string]string{"nl": "Koningen", "FR": "Rois"},
+		Alias:       &map[string]string{},
+		Description: &map[string]string{"nl": "Trek een kaart", "fr": " "},
+	}}
+
+	necessities := map[uuid.UUID][]*gameModel.GameNecessity{
+		*games[0].ID: {{ID: types.Ptr(uuid.UUIDv4()), Name: &map[string]string{"nl": "Kaarten", "fr": "Cartes"}}},
+	}
+
+	tags := []*gameModel.GameTag{{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: &map[string]string{"nl": "Klassiekers"},
+	}}
+
+	return &translatedEntities{categories, games, necessities, tags, nil, nil}
+}
+
+func TestBuildTranslationReport(t *testing.T) {
+	report := buildTranslationReport([]string{"nl", "fr"}, localizedFields(translationCatalog()))
+
+	games := report.Entities[audit.EntityGame]
+	if games.Total != 1 {
+		t.Fatalf("expected 1 game, got %d", games.Total)
+	}
+
+	if _, ok := games.Fields["alias"]; ok {
+		t.Fatal("expected aliases without translations to be left out")
+	}
+
+	description := games.Fields["description"].Locales
+	if description["nl"].Percentage != 100 || description["fr"].Missing != 1 {
+		t.Fatalf("unexpected description completeness %+v %+v", description["nl"], description["fr"])
+	}
+
+	if games.Fields["name"].Locales["fr"].Translated != 1 {
+		t.Fatal("expected locales to be compared case insensitively")
+	}
+
+	if len(games.Missing) != 1 || games.Missing[0].Field != "description" || games.Missing[0].Locales[0] != "fr" {
+		t.Fatalf("unexpected missing translations %+v", games.Missing)
+	}
+
+	if len(report.Entities[audit.EntityTag].Missing) != 1 {
+		t.Fatal("expected the tag to miss its French name")
+	}
+
+	if len(report.Entities[audit.EntityCategory].Missing) != 0 ||
+		len(report.Entities[audit.EntityNecessity].Missing) != 0 {
+		t.Fatal("expected the category and necessity to be complete")
+	}
+}
+
+func TestGetTranslationReportCSV(t *testing.T) {
+	load := func() (*translatedEntities, error) {
+		return translationCatalog(), nil
+	}
+
+	req := httptest.NewRequest("GET", "/api/admin/translations?locales=nl,fr,de&format=csv", nil)
+	rec := httptest.NewRecorder()
+
+	reportTranslations(server.ResponseWriter{W: rec}, &server.Request{R: req, QueryParams: req.URL.Query()}, load)
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected a CSV export, got %s", rec.Header().Get("Content-Type"))
+	}
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(rows[0], ",") != "entity,id,field,locale,nl,fr,de,translation" {
+		t.Fatalf("unexpected header %v", rows[0])
+	}
+
+	// Every entity misses German, the game also misses a French description and the tag a French name
+	if len(rows) != 1+7 {
+		t.Fatalf("expected 7 missing translations, got %d", len(rows)-1)
+	}
+
+	req = httptest.NewRequest("GET", "/api/admin/translations?format=xml", nil)
+	rec = httptest.NewRecorder()
+
+	reportTranslations(server.ResponseWriter{W: rec}, &server.Request{R: req, QueryParams: req.URL.Query()}, load)
+
+	if rec.Code != 400 {
+		t.Fatalf("expected an unknown format to be rejected, got %d", rec.Code)
+	}
+}
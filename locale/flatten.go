@@ -0,0 +1,161 @@
+package locale
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// translationsType is the type of the localized fields of the models.
+var translationsType = reflect.TypeOf(map[string]string{})
+
+// marshalerType is the type of values which marshal themselves, they are kept as they are.
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// field is a key and value of a flattened object.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// object is a flattened struct, it keeps the order of the struct fields.
+type object []field
+
+// MarshalJSON marshals the fields of the object in order.
+func (o object) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Flatten returns the content with every map of translations resolved to a single string. The
+// result marshals like the content otherwise, so new fields of the models show up without changes.
+func (l *Localizer) Flatten(content interface{}) interface{} {
+	return l.flatten(reflect.ValueOf(content))
+}
+
+// flatten resolves the translations in the value.
+func (l *Localizer) flatten(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	t := v.Type()
+
+	if t == translationsType {
+		values := v.Interface().(map[string]string)
+		return l.String(&values)
+	}
+
+	if t.Implements(marshalerType) {
+		return v.Interface()
+	}
+
+	if reflect.PtrTo(t).Implements(marshalerType) {
+		p := reflect.New(t)
+		p.Elem().Set(v)
+		return p.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return l.flatten(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = l.flatten(v.Index(i))
+		}
+
+		return items
+	case reflect.Map:
+		if v.IsNil() || t.Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+
+		items := map[string]interface{}{}
+		for _, key := range v.MapKeys() {
+			items[key.String()] = l.flatten(v.MapIndex(key))
+		}
+
+		return items
+	case reflect.Struct:
+		return l.flattenStruct(v)
+	}
+
+	return v.Interface()
+}
+
+// flattenStruct flattens the exported fields of the struct, following their json tags.
+func (l *Localizer) flattenStruct(v reflect.Value) object {
+	o := object{}
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+
+		value := v.Field(i)
+		if strings.Contains(","+options+",", ",omitempty,") && isEmpty(value) {
+			continue
+		}
+
+		o = append(o, field{key: name, value: l.flatten(value)})
+	}
+
+	return o
+}
+
+// isEmpty checks if the value is left out by omitempty.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false
+	}
+
+	return v.IsZero()
+}
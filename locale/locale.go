@@ -0,0 +1,207 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+)
+
+// DefaultLocale is the last locale of every fallback chain.
+const DefaultLocale = "en"
+
+// ContentLanguageHeader is the header which reports the locales a response was localized in.
+const ContentLanguageHeader = "Content-Language"
+
+// supportedLocales are the locales the catalog should be translated in.
+var supportedLocales = []string{"nl", DefaultLocale}
+
+// SetSupportedLocales configures the locales the catalog should be translated in. Empty locales are
+// left out and the configuration is kept when none remain.
+func SetSupportedLocales(locales []string) {
+	supported := []string{}
+
+	for _, locale := range locales {
+		locale = strings.ToLower(strings.TrimSpace(locale))
+		if locale != "" {
+			supported = append(supported, locale)
+		}
+	}
+
+	if len(supported) > 0 {
+		supportedLocales = supported
+	}
+}
+
+// SupportedLocales returns the locales the catalog should be translated in.
+func SupportedLocales() []string {
+	return append([]string{}, supportedLocales...)
+}
+
+// Localizer resolves localized maps to a single string and keeps track of the locales it used.
+type Localizer struct {
+	// Chain are the locales to try in order, in lower case.
+	Chain []string
+
+	used []string
+}
+
+// weightedTag is a language tag from an Accept-Language header with its quality.
+type weightedTag struct {
+	tag     string
+	quality float64
+}
+
+// ParseAcceptLanguage parses an Accept-Language header into its language tags, the preferred ones
+// first. Wildcards and tags with a quality of 0 are left out.
+func ParseAcceptLanguage(header string) []string {
+	weighted := []weightedTag{}
+
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if !strings.HasPrefix(field, "q=") {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(field[2:], 64)
+			if err == nil {
+				quality = q
+			}
+		}
+
+		if quality <= 0 {
+			continue
+		}
+
+		weighted = append(weighted, weightedTag{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].quality > weighted[j].quality
+	})
+
+	tags := []string{}
+	for _, w := range weighted {
+		tags = append(tags, w.tag)
+	}
+
+	return tags
+}
+
+// FallbackChain expands the tags into the locales to try in order. Every tag is followed by its
+// less specific forms, so "nl-BE" becomes "nl-be", "nl", and the chain ends with DefaultLocale.
+func FallbackChain(tags []string) []string {
+	chain := []string{}
+	seen := map[string]bool{}
+
+	add := func(locale string) {
+		if locale != "" && !seen[locale] {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+	}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+
+		for tag != "" {
+			add(tag)
+
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+
+			tag = tag[:i]
+		}
+	}
+
+	add(DefaultLocale)
+	return chain
+}
+
+// FromRequest creates a localizer for the request. The lang query parameter takes precedence over
+// the Accept-Language header.
+func FromRequest(r *server.Request) *Localizer {
+	if lang := r.QueryParams["lang"]; len(lang) > 0 && lang[0] != "" {
+		return &Localizer{Chain: FallbackChain(strings.Split(lang[0], ","))}
+	}
+
+	return &Localizer{Chain: FallbackChain(ParseAcceptLanguage(r.R.Header.Get("Accept-Language")))}
+}
+
+// IsLocalized checks if the request asks for flattened strings instead of the localized maps, by
+// choosing a language with lang or with localize=true.
+func IsLocalized(r *server.Request) bool {
+	if lang := r.QueryParams["lang"]; len(lang) > 0 && lang[0] != "" {
+		return true
+	}
+
+	localize := r.QueryParams["localize"]
+	return len(localize) > 0 && localize[0] == "true"
+}
+
+// String resolves the localized map to the value of the first locale of the chain it has. When it
+// has none of them, the value of its first locale in alphabetical order is used, so a translation
+// which is only available in another language still shows up.
+func (l *Localizer) String(values *map[string]string) *string {
+	if values == nil || len(*values) == 0 {
+		return nil
+	}
+
+	lower := map[string]string{}
+	for locale := range *values {
+		lower[strings.ToLower(locale)] = locale
+	}
+
+	for _, locale := range l.Chain {
+		if key, ok := lower[locale]; ok {
+			value := (*values)[key]
+			l.use(key)
+			return &value
+		}
+	}
+
+	keys := []string{}
+	for key := range *values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	value := (*values)[keys[0]]
+	l.use(keys[0])
+	return &value
+}
+
+// use records that the locale was used.
+func (l *Localizer) use(locale string) {
+	for _, used := range l.used {
+		if used == locale {
+			return
+		}
+	}
+
+	l.used = append(l.used, locale)
+}
+
+// Used returns the locales which were used, in the order they were first used.
+func (l *Localizer) Used() []string {
+	return l.used
+}
+
+// SetHeader reports the used locales in the Content-Language header of the response.
+func (l *Localizer) SetHeader(rw server.ResponseWriter) {
+	if len(l.used) > 0 {
+		rw.W.Header().Set(ContentLanguageHeader, strings.Join(l.used, ", "))
+	}
+}
@@ -0,0 +1,115 @@
+package locale
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tags := ParseAcceptLanguage("fr;q=0.5, nl-BE, *;q=0.1, en;q=0.8, de;q=0")
+
+	expected := []string{"nl-be", "en", "fr"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected %v, got %v", expected, tags)
+	}
+
+	if len(ParseAcceptLanguage("")) != 0 {
+		t.Fatal("expected no tags for an empty header")
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	chain := FallbackChain([]string{"nl-BE", "fr", "nl"})
+
+	expected := []string{"nl-be", "nl", "fr", "en"}
+	if !reflect.DeepEqual(chain, expected) {
+		t.Fatalf("expected %v, got %v", expected, chain)
+	}
+}
+
+func TestString(t *testing.T) {
+	l := Localizer{Chain: FallbackChain([]string{"nl-BE"})}
+
+	name := l.String(&map[string]string{"en": "Kings", "nl": "Koningen"})
+	if *name != "Koningen" {
+		t.Fatalf("expected the Dutch name, got %s", *name)
+	}
+
+	name = l.String(&map[string]string{"en": "Dice"})
+	if *name != "Dice" {
+		t.Fatalf("expected the default locale, got %s", *name)
+	}
+
+	// Without a locale of the chain the first one in alphabetical order is used
+	name = l.String(&map[string]string{"fr": "Dés", "de": "Würfel"})
+	if *name != "Würfel" {
+		t.Fatalf("expected the first locale, got %s", *name)
+	}
+
+	if l.String(nil) != nil || l.String(&map[string]string{}) != nil {
+		t.Fatal("expected nil for missing translations")
+	}
+
+	expected := []string{"nl", "en", "de"}
+	if !reflect.DeepEqual(l.Used(), expected) {
+		t.Fatalf("expected %v to be used, got %v", expected, l.Used())
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/category?lang=fr", nil)
+	req.Header.Set("Accept-Language", "nl-BE")
+
+	l := FromRequest(&server.Request{R: req, QueryParams: req.URL.Query()})
+	if !reflect.DeepEqual(l.Chain, []string{"fr", "en"}) {
+		t.Fatalf("expected the lang parameter to take precedence, got %v", l.Chain)
+	}
+
+	req = httptest.NewRequest("GET", "/api/category?localize=true", nil)
+	req.Header.Set("Accept-Language", "nl-BE")
+
+	r := &server.Request{R: req, QueryParams: req.URL.Query()}
+	if !IsLocalized(r) {
+		t.Fatal("expected the request to be localized")
+	}
+
+	l = FromRequest(r)
+	if !reflect.DeepEqual(l.Chain, []string{"nl-be", "nl", "en"}) {
+		t.Fatalf("unexpected chain %v", l.Chain)
+	}
+}
+
+func TestIsLocalized(t *testing.T) {
+	for query, expected := range map[string]bool{"": false, "lang=nl": true, "localize=true": true, "localize=false": false} {
+		req := httptest.NewRequest("GET", "/api/category?"+query, nil)
+
+		if IsLocalized(&server.Request{R: req, QueryParams: req.URL.Query()}) != expected {
+			t.Fatalf("expected %q to be localized: %v", query, expected)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	type item struct {
+		Name   *map[string]string `json:"name"`
+		Note   *string            `json:"note,omitempty"`
+		Hidden string             `json:"-"`
+	}
+
+	l := &Localizer{Chain: FallbackChain([]string{"nl"})}
+
+	content := []item{{Name: &map[string]string{"nl": "Koningen", "en": "Kings"}, Hidden: "secret"}}
+
+	flat, err := json.Marshal(l.Flatten(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(flat) != `[{"name":"Koningen"}]` {
+		t.Fatalf("unexpected flattened content %s", flat)
+	}
+}
@@ -0,0 +1,21 @@
+package mail
+
+// ErrNil is thrown when a function was executed on a nil pointer.
+type ErrNil struct{}
+
+func (e *ErrNil) Error() string {
+	return "cannot execute function on a nil pointer"
+}
+
+// ErrSend is thrown when a message could not be delivered.
+type ErrSend struct {
+	Cause error
+}
+
+func (e *ErrSend) Error() string {
+	if e.Cause != nil {
+		return "could not send mail: " + e.Cause.Error()
+	}
+
+	return "could not send mail"
+}
@@ -0,0 +1,77 @@
+// The mail package sends e-mails to users through a configurable mailer. By default mails are
+// written to a local outbox directory so they can be inspected during development.
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message represents an e-mail which is sent to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is implemented by everything which is able to deliver a message.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Outbox is a mailer which writes every message as a file to a local directory.
+type Outbox struct {
+	Dir string
+}
+
+// NewOutbox creates an outbox which writes its messages to the given directory.
+func NewOutbox(dir string) *Outbox {
+	return &Outbox{
+		Dir: dir,
+	}
+}
+
+// Send writes the message to the outbox directory.
+func (o *Outbox) Send(msg Message) error {
+	if o == nil {
+		return &ErrNil{}
+	}
+
+	err := os.MkdirAll(o.Dir, 0755)
+	if err != nil {
+		return &ErrSend{Cause: err}
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_").Replace(msg.To)
+	filename := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), recipient)
+
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n",
+		msg.To, msg.Subject, time.Now().UTC().Format(time.RFC1123Z), msg.Body)
+
+	err = os.WriteFile(filepath.Join(o.Dir, filename), []byte(content), 0600)
+	if err != nil {
+		return &ErrSend{Cause: err}
+	}
+
+	return nil
+}
+
+// mailer is the package level mailer which is used by Send.
+var mailer Mailer = NewOutbox("outbox")
+
+// SetMailer selects the mailer which is used to deliver messages.
+func SetMailer(m Mailer) {
+	mailer = m
+}
+
+// Send delivers the message through the configured mailer.
+func Send(msg Message) error {
+	if mailer == nil {
+		return &ErrNil{}
+	}
+
+	return mailer.Send(msg)
+}
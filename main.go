@@ -1,11 +1,20 @@
 package main
 
 import (
+	"os"
+	"strings"
+	"time"
+
 	"github.com/marvindeckmyn/drankspelletjes-server/account"
+	"github.com/marvindeckmyn/drankspelletjes-server/audit"
 	"github.com/marvindeckmyn/drankspelletjes-server/auth"
 	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/export"
 	"github.com/marvindeckmyn/drankspelletjes-server/game"
+	"github.com/marvindeckmyn/drankspelletjes-server/locale"
 	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	"github.com/marvindeckmyn/drankspelletjes-server/party"
+	"github.com/marvindeckmyn/drankspelletjes-server/room"
 	"github.com/marvindeckmyn/drankspelletjes-server/server"
 )
 
@@ -18,30 +27,175 @@ func initDB() {
 	}
 }
 
+// initOIDC enables logging in through an OpenID Connect provider when one is configured.
+func initOIDC() {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return
+	}
+
+	auth.SetOIDCConfig(auth.OIDCConfig{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		PostLoginURL: os.Getenv("OIDC_POST_LOGIN_URL"),
+	})
+}
+
 // main executes the main function.
 func main() {
 	s := server.New()
+	s.AddMiddleware(server.RequestID)
+	s.AddMiddleware(server.CSRF)
 	initDB()
+	initOIDC()
+
+	if locales := os.Getenv("SUPPORTED_LOCALES"); locales != "" {
+		locale.SetSupportedLocales(strings.Split(locales, ","))
+	}
+
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		err := server.SetTrustedProxies(strings.Split(proxies, ","))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	auth.SetRequireAdminTwoFactor(os.Getenv("REQUIRE_ADMIN_2FA") == "true")
+	room.SetJoinURL(os.Getenv("ROOM_JOIN_URL"))
+
+	err := room.RestoreRooms()
+	if err != nil {
+		log.Warning("The open rooms weren't restored: %s", err.Error())
+	}
+
+	err = auth.LoadBreachedPasswords("breached_passwords.txt")
+	if err != nil {
+		log.Warning("No breached password list loaded: %s", err.Error())
+	}
 
-	s.Get("/api/auth/account", account.Get)
+	s.Get("/api/auth/account", account.Get, auth.RequireScope(auth.ScopeAccountRead))
+	s.Put("/api/account", account.Update, auth.RequireAccount)
+	s.Delete("/api/account", account.Delete, auth.RequireAccount)
+	s.Post("/api/account/email", account.ChangeEmail, auth.RequireAccount)
+	s.Post("/api/account/email/confirm", account.ConfirmEmail)
+	s.Post("/api/account/password", auth.ChangePassword, auth.RequireAccount)
+	s.Post("/api/account/export", export.PostExport, auth.RequireAccount)
+	s.Get("/api/account/export/{id}", export.GetExport, auth.RequireAccount)
+	s.Get("/api/account/export/{id}/download", export.DownloadExport)
 
 	//s.Post("/api/auth/register", auth.Register)
 	s.Post("/api/auth/login", auth.Login)
-	s.Post("/api/auth/logout", auth.Logout)
+	s.Post("/api/auth/login/2fa", auth.LoginTwoFactor)
+	s.Post("/api/auth/logout", auth.Logout, auth.RequireAccount)
+	s.Get("/api/auth/oidc/login", auth.OIDCLogin)
+	s.Get("/api/auth/oidc/callback", auth.OIDCCallback)
+	s.Post("/api/auth/password/forgot", auth.ForgotPassword)
+	s.Post("/api/auth/password/reset", auth.ResetPassword)
+	s.Get("/api/auth/token", auth.GetApiTokens, auth.RequireAccount)
+	s.Post("/api/auth/token", auth.PostApiToken, auth.RequireAccount)
+	s.Delete("/api/auth/token/{id}", auth.DeleteApiToken, auth.RequireAccount)
+	s.Post("/api/auth/2fa/enroll", auth.EnrollTwoFactor, auth.RequireAccount)
+	s.Post("/api/auth/2fa/confirm", auth.ConfirmTwoFactor, auth.RequireAccount)
+	s.Delete("/api/auth/2fa", auth.DisableTwoFactor, auth.RequireAccount)
+	s.Post("/api/admin/account/{id}/unlock", auth.UnlockAccount, auth.RequireAdmin)
+	s.Get("/api/admin/audit", audit.GetEntries, auth.RequireAdmin)
+	s.Get("/api/admin/translations", game.GetTranslationReport, auth.RequireAdmin)
+	s.Get("/api/admin/review/reported", game.GetReportedReviews, auth.RequireAdmin)
 
 	s.Get("/api/category", game.GetCategories)
 	s.Get("/api/category/{id}", game.GetCategoryById)
-	s.Post("/api/category", game.PostCategory)
-	s.Put("/api/category/{id}", game.UpdateCategory)
-	s.Delete("/api/category/{id}", game.DeleteCategory)
+	s.Post("/api/category", game.PostCategory, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Put("/api/category/order", game.ReorderCategories, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Put("/api/category/{id}", game.UpdateCategory, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Put("/api/category/{id}/games/order", game.ReorderGames, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Delete("/api/category/{id}", game.DeleteCategory, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+
+	s.Get("/api/game/category/{id}", game.GetGamesByCategory, auth.OptionalAccount)
+	s.Post("/api/game", game.PostGame, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Get("/api/game/search", game.SearchGames, auth.OptionalAccount)
+	s.Get("/api/game/random", game.GetRandomGame, auth.OptionalAccount)
+	s.Get("/api/game/{id}", game.GetGame, auth.OptionalAccount)
+	s.Put("/api/game/{id}", game.UpdateGame, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Patch("/api/game/{id}", game.PatchGame, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Delete("/api/game/{id}", game.DeleteGame, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+
+	s.Get("/api/game/{id}/necessity", game.GetGameNecessities)
+	s.Post("/api/game/necessity", game.PostGameNecessity, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Put("/api/game/necessity/{id}", game.UpdateGameNecessity, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Delete("/api/game/necessity/{id}", game.DeleteGameNecessity, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+
+	s.Get("/api/tag", game.GetTags)
+	s.Get("/api/tag/{id}", game.GetTag)
+	s.Post("/api/tag", game.PostTag, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Put("/api/tag/{id}", game.UpdateTag, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Delete("/api/tag/{id}", game.DeleteTag, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Put("/api/game/{id}/tag/{tag}", game.PutGameTag, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Delete("/api/game/{id}/tag/{tag}", game.DeleteGameTag, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Put("/api/game/{id}/category/{category}", game.PutGameCategory, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Delete("/api/game/{id}/category/{category}", game.DeleteGameCategory, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+
+	s.Get("/api/game/{id}/rules", game.GetGameRules)
+	s.Post("/api/game/rule", game.PostGameRule, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Put("/api/game/rule/{id}", game.UpdateGameRule, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Delete("/api/game/rule/{id}", game.DeleteGameRule, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Get("/api/game/{id}/variation", game.GetGameVariations)
+	s.Post("/api/game/variation", game.PostGameVariation, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Put("/api/game/variation/{id}", game.UpdateGameVariation, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+	s.Delete("/api/game/variation/{id}", game.DeleteGameVariation, auth.RequireAdminScope(auth.ScopeCatalogWrite))
+
+	s.Get("/api/game/{id}/review", game.GetGameReviews)
+	s.Put("/api/game/{id}/review", game.PutGameReview, auth.RequireAccount)
+	s.Delete("/api/game/review/{id}", game.DeleteGameReview, auth.RequireAccount)
+	s.Post("/api/game/review/{id}/report", game.PostReviewReport, auth.RequireAccount)
+
+	s.Get("/api/favorite", game.GetFavorites, auth.RequireAccount)
+	s.Put("/api/favorite/{id}", game.PutFavorite, auth.RequireAccount)
+	s.Delete("/api/favorite/{id}", game.DeleteFavorite, auth.RequireAccount)
+
+	s.Get("/api/collection", game.GetCollections, auth.RequireAccount)
+	s.Get("/api/collection/shared/{token}", game.GetSharedCollection, auth.OptionalAccount)
+	s.Get("/api/collection/{id}", game.GetCollection, auth.RequireAccount)
+	s.Post("/api/collection", game.PostCollection, auth.RequireAccount)
+	s.Put("/api/collection/{id}", game.UpdateCollection, auth.RequireAccount)
+	s.Delete("/api/collection/{id}", game.DeleteCollection, auth.RequireAccount)
+	s.Post("/api/collection/{id}/share", game.ShareCollection, auth.RequireAccount)
+	s.Put("/api/collection/{id}/order", game.ReorderCollection, auth.RequireAccount)
+	s.Put("/api/collection/{id}/game/{game}", game.PutCollectionGame, auth.RequireAccount)
+	s.Delete("/api/collection/{id}/game/{game}", game.DeleteCollectionGame, auth.RequireAccount)
+
+	s.Get("/api/party", party.GetSessions, auth.RequireAccount)
+	s.Post("/api/party", party.PostSession, auth.RequireAccount)
+	s.Get("/api/party/{id}", party.GetSession, auth.RequireAccount)
+	s.Put("/api/party/{id}", party.UpdateSession, auth.RequireAccount)
+	s.Delete("/api/party/{id}", party.DeleteSession, auth.RequireAccount)
+	s.Post("/api/party/{id}/pause", party.PauseSession, auth.RequireAccount)
+	s.Post("/api/party/{id}/resume", party.ResumeSession, auth.RequireAccount)
+	s.Post("/api/party/{id}/close", party.CloseSession, auth.RequireAccount)
+	s.Get("/api/party/{id}/summary", party.GetSessionSummary, auth.RequireAccount)
+	s.Post("/api/party/{id}/player", party.PostPlayer, auth.RequireAccount)
+	s.Put("/api/party/player/{id}", party.UpdatePlayer, auth.RequireAccount)
+	s.Delete("/api/party/player/{id}", party.DeletePlayer, auth.RequireAccount)
+	s.Post("/api/party/{id}/round", party.PostRound, auth.RequireAccount)
+	s.Post("/api/party/{id}/round/end", party.EndRound, auth.RequireAccount)
+	s.Post("/api/party/{id}/event", party.PostEvent, auth.RequireAccount)
+	s.Delete("/api/party/event/{id}", party.DeleteEvent, auth.RequireAccount)
 
-	s.Get("/api/game/category/{id}", game.GetGamesByCategory)
-	s.Post("/api/game", game.PostGame)
+	s.Post("/api/room", room.PostRoom, auth.RequireAccount)
+	s.Post("/api/room/join", room.JoinRoom)
+	s.Get("/api/room/{code}", room.GetRoom)
+	s.Delete("/api/room/{code}", room.CloseRoom)
+	s.Get("/api/room/{code}/stream", room.StreamRoom)
+	s.Post("/api/room/{code}/state", room.PostState)
+	s.Post("/api/room/{code}/leave", room.LeaveRoom)
+	s.Post("/api/room/{code}/host", room.HandOverHost)
 
-	s.Post("/api/game/necessity", game.PostGameNecessity)
+	account.StartPurge(time.Hour)
+	export.StartCleanup(time.Hour)
 
 	log.Info("Starting on 1337")
-	err := s.ListenAndServe(1337)
+	err = s.ListenAndServe(1337)
 	if err != nil {
 		panic(err)
 	}
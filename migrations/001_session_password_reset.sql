@@ -0,0 +1,21 @@
+-- Sessions which back the drnkngg-token cookie, so they can be revoked.
+create table if not exists session (
+	id uuid primary key,
+	account uuid not null references account (id) on delete cascade,
+	ip text,
+	user_agent text,
+	created_at timestamptz not null default now(),
+	expires_at timestamptz not null
+);
+
+create index if not exists session_account_idx on session (account);
+
+-- Single-use password reset tokens, only the SHA-256 hash of a token is stored.
+create table if not exists password_reset (
+	id uuid primary key,
+	account uuid not null references account (id) on delete cascade,
+	token_hash text not null unique,
+	created_at timestamptz not null default now(),
+	expires_at timestamptz not null,
+	used_at timestamptz
+);
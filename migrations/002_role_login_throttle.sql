@@ -0,0 +1,10 @@
+-- Accounts get a role so admin-only endpoints can be protected.
+alter table account add column if not exists role text not null default 'user';
+
+-- Failed logins per account ("account:<id>") and per IP ("ip:<address>").
+create table if not exists login_throttle (
+	key text primary key,
+	failures int not null default 0,
+	last_failure timestamptz,
+	locked_until timestamptz
+);
@@ -0,0 +1,13 @@
+-- Personal access tokens, only the SHA-256 hash of a token is stored.
+create table if not exists api_token (
+	id uuid primary key,
+	account uuid not null references account (id) on delete cascade,
+	name text not null,
+	token_hash text not null unique,
+	scopes jsonb not null default '[]',
+	created_at timestamptz not null default now(),
+	expires_at timestamptz not null,
+	last_used_at timestamptz
+);
+
+create index if not exists api_token_account_idx on api_token (account);
@@ -0,0 +1,13 @@
+-- Accounts created through OpenID Connect don't have a password.
+alter table account alter column password drop not null;
+
+-- Links between accounts and the subjects of external OpenID Connect providers.
+create table if not exists account_identity (
+	id uuid primary key,
+	account uuid not null references account (id) on delete cascade,
+	issuer text not null,
+	subject text not null,
+	email text,
+	created_at timestamptz not null default now(),
+	unique (issuer, subject)
+);
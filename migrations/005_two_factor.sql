@@ -0,0 +1,15 @@
+-- TOTP two-factor authentication.
+alter table account add column if not exists totp_secret text;
+alter table account add column if not exists totp_enabled boolean not null default false;
+alter table account add column if not exists totp_last_step bigint;
+
+-- Single-use recovery codes, only the SHA-256 hash of a code is stored.
+create table if not exists recovery_code (
+	id uuid primary key,
+	account uuid not null references account (id) on delete cascade,
+	code_hash text not null,
+	created_at timestamptz not null default now(),
+	used_at timestamptz
+);
+
+create index if not exists recovery_code_account_idx on recovery_code (account);
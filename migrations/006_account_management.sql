@@ -0,0 +1,18 @@
+-- Accounts which asked to be deleted are kept for a grace period before they are purged.
+alter table account add column if not exists deleted_at timestamptz;
+alter table account add column if not exists delete_after timestamptz;
+
+-- Games remember who created them, the author is cleared when the account is purged.
+alter table game add column if not exists created_by uuid references account (id) on delete set null;
+
+-- Pending email changes, only the SHA-256 hash of a token is stored.
+create table if not exists email_change (
+	id uuid primary key,
+	account uuid not null references account (id) on delete cascade,
+	email text not null,
+	token_hash text not null unique,
+	created_at timestamptz not null default now(),
+	expires_at timestamptz not null
+);
+
+create index if not exists email_change_account_idx on email_change (account);
@@ -0,0 +1,12 @@
+-- Archives with all the personal data of an account, built in the background.
+create table if not exists data_export (
+	id uuid primary key,
+	account uuid not null references account (id) on delete cascade,
+	status text not null default 'pending',
+	file text,
+	created_at timestamptz not null default now(),
+	completed_at timestamptz,
+	expires_at timestamptz not null
+);
+
+create index if not exists data_export_account_idx on data_export (account);
@@ -0,0 +1,30 @@
+-- Append-only trail of administrative changes. Actors are kept as plain IDs, so entries survive
+-- the deletion of the account.
+create table if not exists audit_entry (
+	id uuid primary key,
+	actor uuid,
+	action text not null,
+	entity_type text not null,
+	entity_id uuid,
+	before jsonb,
+	after jsonb,
+	ip text,
+	request_id text,
+	created_at timestamptz not null default now()
+);
+
+create index if not exists audit_entry_created_at_idx on audit_entry (created_at desc, id desc);
+create index if not exists audit_entry_entity_idx on audit_entry (entity_type, entity_id);
+create index if not exists audit_entry_actor_idx on audit_entry (actor);
+
+-- Entries can't be changed or removed once written.
+create or replace function audit_entry_append_only() returns trigger as $$
+begin
+	raise exception 'audit entries are append-only';
+end;
+$$ language plpgsql;
+
+drop trigger if exists audit_entry_append_only on audit_entry;
+create trigger audit_entry_append_only
+	before update or delete on audit_entry
+	for each row execute function audit_entry_append_only();
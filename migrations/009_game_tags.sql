@@ -0,0 +1,27 @@
+-- Tags with localized names which can be put on any number of games.
+create table if not exists game_tag (
+	id uuid primary key,
+	name jsonb not null
+);
+
+create table if not exists game_tag_link (
+	game uuid not null references game (id) on delete cascade,
+	tag uuid not null references game_tag (id) on delete cascade,
+	primary key (game, tag)
+);
+
+create index if not exists game_tag_link_tag_idx on game_tag_link (tag);
+
+-- Games can be in several categories. The category of the game itself stays its primary category
+-- and is always one of its links.
+create table if not exists game_category_link (
+	game uuid not null references game (id) on delete cascade,
+	category uuid not null references game_category (id) on delete cascade,
+	primary key (game, category)
+);
+
+create index if not exists game_category_link_category_idx on game_category_link (category);
+
+insert into game_category_link (game, category)
+	select id, game_category from game where game_category is not null
+	on conflict do nothing;
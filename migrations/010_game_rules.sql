@@ -0,0 +1,23 @@
+-- Named variations of the rules of a game.
+create table if not exists game_variation (
+	id uuid primary key,
+	game uuid not null references game (id) on delete cascade,
+	name jsonb not null,
+	description jsonb not null default '{}',
+	"order" int not null default 0
+);
+
+create index if not exists game_variation_game_idx on game_variation (game);
+
+-- Ordered steps of the rules of a game. Rules without a variation are the base rules, a variation
+-- replaces the base rules of the kinds it has rules for.
+create table if not exists game_rule (
+	id uuid primary key,
+	game uuid not null references game (id) on delete cascade,
+	variation uuid references game_variation (id) on delete cascade,
+	kind text not null check (kind in ('setup', 'turn', 'win', 'lose')),
+	"order" int not null default 0,
+	text jsonb not null
+);
+
+create index if not exists game_rule_game_idx on game_rule (game);
@@ -0,0 +1,30 @@
+-- Aggregates of the reviews, kept up to date whenever a review changes.
+alter table game add column if not exists rating_count int not null default 0;
+alter table game add column if not exists rating_average double precision not null default 0;
+
+create index if not exists game_rating_average_idx on game (rating_average, id);
+
+-- Reviews of games, an account can review every game once. The reviews of a deleted account are
+-- kept without an author, so the ratings of the games don't change.
+create table if not exists game_review (
+	id uuid primary key,
+	game uuid not null references game (id) on delete cascade,
+	account uuid references account (id) on delete set null,
+	rating int not null check (rating between 1 and 5),
+	text jsonb not null default '{}',
+	created_at timestamptz not null default now(),
+	updated_at timestamptz not null default now(),
+	unique (game, account)
+);
+
+create index if not exists game_review_game_idx on game_review (game, created_at, id);
+create index if not exists game_review_account_idx on game_review (account);
+
+-- Reports of abusive reviews, an account can report every review once.
+create table if not exists game_review_report (
+	review uuid not null references game_review (id) on delete cascade,
+	account uuid not null references account (id) on delete cascade,
+	reason text not null default '',
+	created_at timestamptz not null default now(),
+	primary key (review, account)
+);
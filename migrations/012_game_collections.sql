@@ -0,0 +1,27 @@
+-- Games accounts marked as favorite.
+create table if not exists game_favorite (
+	account uuid not null references account (id) on delete cascade,
+	game uuid not null references game (id) on delete cascade,
+	created_at timestamptz not null default now(),
+	primary key (account, game)
+);
+
+-- Named lists of games of an account, which can be read by everyone with the share token.
+create table if not exists game_collection (
+	id uuid primary key,
+	account uuid not null references account (id) on delete cascade,
+	name text not null,
+	share_token text not null unique,
+	created_at timestamptz not null default now(),
+	updated_at timestamptz not null default now()
+);
+
+create index if not exists game_collection_account_idx on game_collection (account);
+
+-- The games of a collection in the order of the account.
+create table if not exists game_collection_item (
+	collection uuid not null references game_collection (id) on delete cascade,
+	game uuid not null references game (id) on delete cascade,
+	"order" int not null default 0,
+	primary key (collection, game)
+);
@@ -0,0 +1,48 @@
+-- Party sessions which are hosted by an account. Only active sessions are played in.
+create table if not exists party_session (
+	id uuid primary key,
+	host uuid not null references account (id) on delete cascade,
+	name text not null,
+	status text not null default 'active' check (status in ('active', 'paused', 'closed')),
+	created_at timestamptz not null default now(),
+	updated_at timestamptz not null default now(),
+	closed_at timestamptz
+);
+
+create index if not exists party_session_host_idx on party_session (host, updated_at desc);
+
+-- Players of a session, they don't need an account.
+create table if not exists party_player (
+	id uuid primary key,
+	session uuid not null references party_session (id) on delete cascade,
+	name text not null,
+	"order" int not null default 0,
+	created_at timestamptz not null default now(),
+	unique (session, name)
+);
+
+-- Rounds of a session, every round plays a game of the catalog. A round without an end is being
+-- played.
+create table if not exists party_round (
+	id uuid primary key,
+	session uuid not null references party_session (id) on delete cascade,
+	game uuid references game (id) on delete set null,
+	number int not null,
+	started_at timestamptz not null default now(),
+	ended_at timestamptz,
+	unique (session, number)
+);
+
+-- What happened to the players during a round.
+create table if not exists party_event (
+	id uuid primary key,
+	session uuid not null references party_session (id) on delete cascade,
+	round uuid not null references party_round (id) on delete cascade,
+	player uuid not null references party_player (id) on delete cascade,
+	kind text not null check (kind in ('turn', 'penalty', 'points')),
+	amount int not null default 0,
+	note text not null default '',
+	created_at timestamptz not null default now()
+);
+
+create index if not exists party_event_session_idx on party_event (session, created_at);
@@ -0,0 +1,37 @@
+-- Rooms which phones join with a short code. A room can be linked to a party session of its host.
+create table if not exists room (
+	id uuid primary key,
+	code text not null,
+	host_account uuid not null references account (id) on delete cascade,
+	session uuid references party_session (id) on delete set null,
+	status text not null default 'open' check (status in ('open', 'closed')),
+	created_at timestamptz not null default now(),
+	closed_at timestamptz
+);
+
+-- Codes are only reused once the room with the code was closed.
+create unique index if not exists room_code_open_idx on room (code) where status = 'open';
+
+create index if not exists room_host_account_idx on room (host_account);
+
+-- Participants of a room, they don't need an account. Only the hash of their token is stored.
+create table if not exists room_participant (
+	id uuid primary key,
+	room uuid not null references room (id) on delete cascade,
+	name text not null,
+	token_hash text not null unique,
+	joined_at timestamptz not null default now()
+);
+
+create index if not exists room_participant_room_idx on room_participant (room);
+
+-- Everything that happened in a room in order. The state of a room is rebuilt by replaying them.
+create table if not exists room_event (
+	room uuid not null references room (id) on delete cascade,
+	seq bigint not null,
+	kind text not null,
+	participant uuid references room_participant (id) on delete cascade,
+	data jsonb not null default '{}',
+	created_at timestamptz not null default now(),
+	primary key (room, seq)
+);
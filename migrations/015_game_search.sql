@@ -0,0 +1,31 @@
+-- Trigram search over the names, aliases and descriptions of the games.
+create extension if not exists pg_trgm;
+create extension if not exists unaccent;
+
+-- The searched texts of the games, lowercased and without accents. There is a row per locale and
+-- field, which is replaced whenever the game is saved.
+create table if not exists game_search (
+	game uuid not null references game (id) on delete cascade,
+	locale text not null,
+	field text not null check (field in ('name', 'alias', 'description')),
+	text text not null,
+	primary key (game, locale, field)
+);
+
+create index if not exists game_search_text_idx on game_search using gin (text gin_trgm_ops);
+
+insert into game_search (game, locale, field, text)
+	select game, locale, field, text
+	from (
+		select g.id as game, t.key as locale, f.field,
+			trim(regexp_replace(lower(unaccent(t.value)), '[^[:alnum:]]+', ' ', 'g')) as text
+		from game g
+		cross join lateral (values
+			('name', g.name),
+			('alias', g.alias),
+			('description', g.description)
+		) as f (field, value)
+		cross join lateral jsonb_each_text(f.value) as t
+	) texts
+	where text <> ''
+	on conflict do nothing;
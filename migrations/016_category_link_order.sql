@@ -0,0 +1,14 @@
+-- Games are ordered per category, a game can have another place in every category it is in.
+alter table game_category_link add column if not exists "order" int not null default 0;
+
+update game_category_link gcl
+	set "order" = ranked.position
+	from (
+		select l.game, l.category,
+			row_number() over (partition by l.category order by g."order", g.id) as position
+		from game_category_link l
+		join game g on g.id = l.game
+	) ranked
+	where ranked.game = gcl.game and ranked.category = gcl.category;
+
+create index if not exists game_category_link_order_idx on game_category_link (category, "order", game);
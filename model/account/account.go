@@ -1,10 +1,28 @@
 package accountModel
 
-import "github.com/marvindeckmyn/drankspelletjes-server/uuid"
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
 
 type Account struct {
 	ID       *uuid.UUID `json:"id"`
 	Name     *string    `json:"name"`
 	Email    *string    `json:"email"`
 	Password *string    `json:"password"`
+	Role     *string    `json:"role"`
+
+	TotpSecret   *string `json:"-"`
+	TotpEnabled  *bool   `json:"totp_enabled"`
+	TotpLastStep *int64  `json:"-"`
+
+	DeletedAt   *time.Time `json:"deleted_at"`
+	DeleteAfter *time.Time `json:"delete_after"`
 }
+
+// Account roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
@@ -0,0 +1,18 @@
+package accountModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+type ApiToken struct {
+	ID         *uuid.UUID `json:"id"`
+	Account    *uuid.UUID `json:"account"`
+	Name       *string    `json:"name"`
+	TokenHash  *string    `json:"-"`
+	Scopes     *[]string  `json:"scopes"`
+	CreatedAt  *time.Time `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
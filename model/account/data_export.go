@@ -0,0 +1,24 @@
+package accountModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+type DataExport struct {
+	ID          *uuid.UUID `json:"id"`
+	Account     *uuid.UUID `json:"account"`
+	Status      *string    `json:"status"`
+	File        *string    `json:"-"`
+	CreatedAt   *time.Time `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// Data export statuses
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
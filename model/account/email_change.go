@@ -0,0 +1,16 @@
+package accountModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+type EmailChange struct {
+	ID        *uuid.UUID `json:"id"`
+	Account   *uuid.UUID `json:"account"`
+	Email     *string    `json:"email"`
+	TokenHash *string    `json:"-"`
+	CreatedAt *time.Time `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
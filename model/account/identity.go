@@ -0,0 +1,16 @@
+package accountModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+type Identity struct {
+	ID        *uuid.UUID `json:"id"`
+	Account   *uuid.UUID `json:"account"`
+	Issuer    *string    `json:"issuer"`
+	Subject   *string    `json:"subject"`
+	Email     *string    `json:"email"`
+	CreatedAt *time.Time `json:"created_at"`
+}
@@ -0,0 +1,10 @@
+package accountModel
+
+import "time"
+
+type LoginThrottle struct {
+	Key         *string    `json:"key"`
+	Failures    *int32     `json:"failures"`
+	LastFailure *time.Time `json:"last_failure"`
+	LockedUntil *time.Time `json:"locked_until"`
+}
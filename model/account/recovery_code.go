@@ -0,0 +1,15 @@
+package accountModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+type RecoveryCode struct {
+	ID        *uuid.UUID `json:"id"`
+	Account   *uuid.UUID `json:"account"`
+	CodeHash  *string    `json:"-"`
+	CreatedAt *time.Time `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
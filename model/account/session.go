@@ -0,0 +1,16 @@
+package accountModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+type Session struct {
+	ID        *uuid.UUID `json:"id"`
+	Account   *uuid.UUID `json:"account"`
+	IP        *string    `json:"ip"`
+	UserAgent *string    `json:"user_agent"`
+	CreatedAt *time.Time `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
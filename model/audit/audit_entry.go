@@ -0,0 +1,28 @@
+package auditModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+type AuditEntry struct {
+	ID         *uuid.UUID              `json:"id"`
+	Actor      *uuid.UUID              `json:"actor"`
+	Action     *string                 `json:"action"`
+	EntityType *string                 `json:"entity_type"`
+	EntityID   *uuid.UUID              `json:"entity_id"`
+	Before     *map[string]interface{} `json:"before"`
+	After      *map[string]interface{} `json:"after"`
+	IP         *string                 `json:"ip"`
+	RequestID  *string                 `json:"request_id"`
+	CreatedAt  *time.Time              `json:"created_at"`
+}
+
+// Audited actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionUnlock = "unlock"
+)
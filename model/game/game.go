@@ -16,5 +16,27 @@ type Game struct {
 	Description  *map[string]string `json:"description"`
 	Highlight    *bool              `json:"highlight"`
 	Order        *int32             `json:"order"`
+	CreatedBy    *uuid.UUID         `json:"created_by"`
 	CreatedAt    *time.Time         `json:"created_at"`
+
+	// The aggregates of the reviews, they are only changed by reviewing the game.
+	RatingCount   *int32   `json:"rating_count"`
+	RatingAverage *float64 `json:"rating_average"`
+
+	// IsFavorite is only set when the game is listed for a logged-in account.
+	IsFavorite *bool `json:"is_favorite,omitempty"`
+
+	// Relations are only loaded when they are included in the request.
+	Necessities *[]*GameNecessity `json:"necessities,omitempty"`
+	Categories  *[]uuid.UUID      `json:"categories,omitempty"`
+	Tags        *[]*GameTag       `json:"tags,omitempty"`
+}
+
+// GameSearchText is a normalized text of a game which is searched, there is one per locale and
+// field.
+type GameSearchText struct {
+	Game   *uuid.UUID `json:"game"`
+	Locale *string    `json:"locale"`
+	Field  *string    `json:"field"`
+	Text   *string    `json:"text"`
 }
@@ -0,0 +1,36 @@
+package gameModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// GameFavorite marks a game as a favorite of an account.
+type GameFavorite struct {
+	Account   *uuid.UUID `json:"account"`
+	Game      *uuid.UUID `json:"game"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// GameCollection is a named list of games of an account. Everyone with the share token can read it,
+// without seeing the account and the token.
+type GameCollection struct {
+	ID         *uuid.UUID `json:"id"`
+	Account    *uuid.UUID `json:"account,omitempty"`
+	Name       *string    `json:"name"`
+	ShareToken *string    `json:"share_token,omitempty"`
+	CreatedAt  *time.Time `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+
+	// GameCount and Games are only loaded by the listings.
+	GameCount *int32   `json:"game_count,omitempty"`
+	Games     *[]*Game `json:"games,omitempty"`
+}
+
+// GameCollectionItem puts a game in a collection.
+type GameCollectionItem struct {
+	Collection *uuid.UUID `json:"collection"`
+	Game       *uuid.UUID `json:"game"`
+	Order      *int32     `json:"order"`
+}
@@ -0,0 +1,36 @@
+package gameModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// Bounds of the rating of a review.
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// GameReview is the rating an account gave a game, optionally with a short text.
+type GameReview struct {
+	ID        *uuid.UUID         `json:"id"`
+	Game      *uuid.UUID         `json:"game"`
+	Account   *uuid.UUID         `json:"account"`
+	Rating    *int32             `json:"rating"`
+	Text      *map[string]string `json:"text"`
+	CreatedAt *time.Time         `json:"created_at"`
+	UpdatedAt *time.Time         `json:"updated_at"`
+
+	// AccountName and Reports are only loaded by the listings.
+	AccountName *string `json:"account_name,omitempty"`
+	Reports     *int32  `json:"reports,omitempty"`
+}
+
+// GameReviewReport is a report of an abusive review.
+type GameReviewReport struct {
+	Review    *uuid.UUID `json:"review"`
+	Account   *uuid.UUID `json:"account"`
+	Reason    *string    `json:"reason"`
+	CreatedAt *time.Time `json:"created_at"`
+}
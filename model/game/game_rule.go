@@ -0,0 +1,45 @@
+package gameModel
+
+import "github.com/marvindeckmyn/drankspelletjes-server/uuid"
+
+// Kinds of rules
+const (
+	RuleSetup = "setup"
+	RuleTurn  = "turn"
+	RuleWin   = "win"
+	RuleLose  = "lose"
+)
+
+// RuleKinds are all the kinds of rules, in the order they are played.
+var RuleKinds = []string{RuleSetup, RuleTurn, RuleWin, RuleLose}
+
+// GameRule is a step of the rules of a game. Rules without a variation are the base rules.
+type GameRule struct {
+	ID        *uuid.UUID         `json:"id"`
+	Game      *uuid.UUID         `json:"game"`
+	Variation *uuid.UUID         `json:"variation"`
+	Kind      *string            `json:"kind"`
+	Order     *int32             `json:"order"`
+	Text      *map[string]string `json:"text"`
+}
+
+// GameVariation is a named variation of the rules of a game.
+type GameVariation struct {
+	ID          *uuid.UUID         `json:"id"`
+	Game        *uuid.UUID         `json:"game"`
+	Name        *map[string]string `json:"name"`
+	Description *map[string]string `json:"description"`
+	Order       *int32             `json:"order"`
+}
+
+// GameRules are the rules of a game grouped by kind, as they are played in a variation. The base
+// rules are played when the variation is nil.
+type GameRules struct {
+	Game       *uuid.UUID       `json:"game"`
+	Variation  *uuid.UUID       `json:"variation"`
+	Setup      []*GameRule      `json:"setup"`
+	Turn       []*GameRule      `json:"turn"`
+	Win        []*GameRule      `json:"win"`
+	Lose       []*GameRule      `json:"lose"`
+	Variations []*GameVariation `json:"variations"`
+}
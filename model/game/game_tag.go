@@ -0,0 +1,21 @@
+package gameModel
+
+import "github.com/marvindeckmyn/drankspelletjes-server/uuid"
+
+type GameTag struct {
+	ID   *uuid.UUID         `json:"id"`
+	Name *map[string]string `json:"name"`
+}
+
+// GameTagLink puts a tag on a game.
+type GameTagLink struct {
+	Game *uuid.UUID `json:"game"`
+	Tag  *uuid.UUID `json:"tag"`
+}
+
+// GameCategoryLink makes a game part of a category, at the given place in the category.
+type GameCategoryLink struct {
+	Game     *uuid.UUID `json:"game"`
+	Category *uuid.UUID `json:"category"`
+	Order    *int32     `json:"order"`
+}
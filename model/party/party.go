@@ -0,0 +1,105 @@
+package partyModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// Statuses of a session. Only active sessions can be played in, closed sessions can't change
+// anymore.
+const (
+	StatusActive = "active"
+	StatusPaused = "paused"
+	StatusClosed = "closed"
+)
+
+// Kinds of events. A turn is played by a player, a penalty is an amount of sips and points are
+// added to the score of the player.
+const (
+	EventTurn    = "turn"
+	EventPenalty = "penalty"
+	EventPoints  = "points"
+)
+
+// EventKinds are all the kinds of events.
+var EventKinds = []string{EventTurn, EventPenalty, EventPoints}
+
+// Session is a party hosted by an account.
+type Session struct {
+	ID        *uuid.UUID `json:"id"`
+	Host      *uuid.UUID `json:"host"`
+	Name      *string    `json:"name"`
+	Status    *string    `json:"status"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+}
+
+// Player plays in a session, a player doesn't need an account.
+type Player struct {
+	ID        *uuid.UUID `json:"id"`
+	Session   *uuid.UUID `json:"session"`
+	Name      *string    `json:"name"`
+	Order     *int32     `json:"order"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// Round plays a game of the catalog. A round without an end is being played.
+type Round struct {
+	ID        *uuid.UUID `json:"id"`
+	Session   *uuid.UUID `json:"session"`
+	Game      *uuid.UUID `json:"game"`
+	Number    *int32     `json:"number"`
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+}
+
+// Event is what happened to a player during a round.
+type Event struct {
+	ID        *uuid.UUID `json:"id"`
+	Session   *uuid.UUID `json:"session"`
+	Round     *uuid.UUID `json:"round"`
+	Player    *uuid.UUID `json:"player"`
+	Kind      *string    `json:"kind"`
+	Amount    *int32     `json:"amount"`
+	Note      *string    `json:"note"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// PlayerScore is the tally of a player, players with the same points share their rank.
+type PlayerScore struct {
+	Player    uuid.UUID `json:"player"`
+	Name      string    `json:"name"`
+	Turns     int32     `json:"turns"`
+	Penalties int32     `json:"penalties"`
+	Points    int32     `json:"points"`
+	Rank      int32     `json:"rank"`
+}
+
+// SessionState is everything needed to resume a session.
+type SessionState struct {
+	Session      *Session       `json:"session"`
+	Players      []*Player      `json:"players"`
+	Rounds       []*Round       `json:"rounds"`
+	CurrentRound *Round         `json:"current_round"`
+	Events       []*Event       `json:"events"`
+	Scores       []*PlayerScore `json:"scores"`
+}
+
+// GameSummary is how often a game was played in a session.
+type GameSummary struct {
+	Game   uuid.UUID `json:"game"`
+	Name   *string   `json:"name"`
+	Rounds int32     `json:"rounds"`
+}
+
+// Summary is the outcome of a session.
+type Summary struct {
+	Session  *Session       `json:"session"`
+	Duration int64          `json:"duration"`
+	Rounds   int32          `json:"rounds"`
+	Games    []*GameSummary `json:"games"`
+	Scores   []*PlayerScore `json:"scores"`
+	Winners  []uuid.UUID    `json:"winners"`
+}
@@ -0,0 +1,87 @@
+package roomModel
+
+import (
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// Statuses of a room. Closed rooms can't be joined anymore.
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+// Kinds of events. The state of a room is rebuilt by replaying its events in order.
+const (
+	EventCreated = "created"
+	EventJoined  = "joined"
+	EventLeft    = "left"
+	EventOnline  = "online"
+	EventOffline = "offline"
+	EventHost    = "host"
+	EventState   = "state"
+	EventClosed  = "closed"
+)
+
+// Room is joined by phones with its code. The host account created the room.
+type Room struct {
+	ID          *uuid.UUID `json:"id"`
+	Code        *string    `json:"code"`
+	HostAccount *uuid.UUID `json:"host_account"`
+	Session     *uuid.UUID `json:"session"`
+	Status      *string    `json:"status"`
+	CreatedAt   *time.Time `json:"created_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+}
+
+// Participant takes part in a room with a token, a participant doesn't need an account.
+type Participant struct {
+	ID        *uuid.UUID `json:"id"`
+	Room      *uuid.UUID `json:"room"`
+	Name      *string    `json:"name"`
+	TokenHash *string    `json:"-"`
+	JoinedAt  *time.Time `json:"joined_at"`
+}
+
+// Event is what happened in a room. Events are numbered per room without gaps.
+type Event struct {
+	Room        *uuid.UUID              `json:"room"`
+	Seq         *int64                  `json:"seq"`
+	Kind        *string                 `json:"kind"`
+	Participant *uuid.UUID              `json:"participant"`
+	Data        *map[string]interface{} `json:"data"`
+	CreatedAt   *time.Time              `json:"created_at"`
+}
+
+// ParticipantState is a participant as known to the other participants.
+type ParticipantState struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Online   bool      `json:"online"`
+	Left     bool      `json:"left"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// State is a room after replaying its events. The version goes up with every change to the shared
+// game state, seq is the last event which was replayed.
+type State struct {
+	Room         uuid.UUID              `json:"room"`
+	Code         string                 `json:"code"`
+	Session      *uuid.UUID             `json:"session"`
+	Status       string                 `json:"status"`
+	Host         *uuid.UUID             `json:"host"`
+	Participants []*ParticipantState    `json:"participants"`
+	State        map[string]interface{} `json:"state"`
+	Version      int64                  `json:"version"`
+	Seq          int64                  `json:"seq"`
+}
+
+// Membership is handed to a participant which created or joined a room. The token authenticates the
+// participant, it can't be retrieved again.
+type Membership struct {
+	Room        *State    `json:"room"`
+	Participant uuid.UUID `json:"participant"`
+	Token       string    `json:"token"`
+	JoinURL     string    `json:"join_url"`
+}
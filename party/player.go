@@ -0,0 +1,292 @@
+package party
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	partyDao "github.com/marvindeckmyn/drankspelletjes-server/dao/party"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	partyModel "github.com/marvindeckmyn/drankspelletjes-server/model/party"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// maxPlayers is the largest number of players of a session.
+const maxPlayers = 50
+
+type PlayerBody struct {
+	Name string `json:"name"`
+}
+
+type PlayerURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// validatePlayerBody checks if the body is valid.
+func validatePlayerBody(requestBody io.Reader) (*PlayerBody, error) {
+	v := validator.V{
+		"name": validator.IsString,
+	}
+
+	body := PlayerBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	body.Name, err = validateName(body.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validatePlayerURL checks if the player URL is valid.
+func validatePlayerURL(r *server.Request) (*PlayerURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := PlayerURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// nameTaken checks whether another player of the session already has the name, ignoring case.
+func nameTaken(players []*partyModel.Player, name string, except *uuid.UUID) bool {
+	for _, player := range players {
+		if except != nil && *player.ID == *except {
+			continue
+		}
+
+		if strings.EqualFold(*player.Name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getPlayer fetches the player from the URL together with its session, which the caller has to
+// host. The status to respond with is returned when it fails.
+func getPlayer(r *server.Request) (*partyModel.Player, *partyModel.Session, int, error) {
+	url, err := validatePlayerURL(r)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+
+	player := partyModel.Player{
+		ID: &url.ID,
+	}
+
+	err = partyDao.GetPlayer(&player)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, nil, http.StatusNotFound, err
+		}
+
+		return nil, nil, http.StatusInternalServerError, err
+	}
+
+	session, status, err := getOwnSession(r, *player.Session)
+	if err != nil {
+		return nil, nil, status, err
+	}
+
+	return &player, session, http.StatusOK, nil
+}
+
+// PostPlayer adds a player to a session of the caller.
+func PostPlayer(rw server.ResponseWriter, r *server.Request) {
+	session, status, err := getSession(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate player body
+	body, err := validatePlayerBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if isClosed(session) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	players, err := partyDao.GetPlayers(session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if len(players) >= maxPlayers || nameTaken(players, body.Name, nil) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Add player
+	player := partyModel.Player{
+		ID:      types.Ptr(uuid.UUIDv4()),
+		Session: session.ID,
+		Name:    &body.Name,
+		Order:   types.Ptr(int32(len(players) + 1)),
+	}
+
+	tx := cdb.NewTx()
+
+	err = partyDao.InsertPlayer(tx, &player)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = touchSession(tx, session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, player)
+}
+
+// UpdatePlayer renames a player of a session of the caller.
+func UpdatePlayer(rw server.ResponseWriter, r *server.Request) {
+	player, session, status, err := getPlayer(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate player body
+	body, err := validatePlayerBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if isClosed(session) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	players, err := partyDao.GetPlayers(session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if nameTaken(players, body.Name, player.ID) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Update player
+	player.Name = &body.Name
+
+	selectors := map[string]interface{}{
+		"ID": player.ID,
+	}
+
+	changes := partyModel.Player{
+		Name: player.Name,
+	}
+
+	tx := cdb.NewTx()
+
+	err = partyDao.UpdatePlayer(tx, &changes, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = touchSession(tx, session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, player)
+}
+
+// DeletePlayer removes a player from a session of the caller, together with what was recorded for
+// the player.
+func DeletePlayer(rw server.ResponseWriter, r *server.Request) {
+	player, session, status, err := getPlayer(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	if isClosed(session) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Delete player
+	tx := cdb.NewTx()
+
+	err = partyDao.DeletePlayer(tx, player)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = touchSession(tx, session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,451 @@
+package party
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	partyDao "github.com/marvindeckmyn/drankspelletjes-server/dao/party"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	partyModel "github.com/marvindeckmyn/drankspelletjes-server/model/party"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// maxNoteLength is the largest number of characters of the note of an event.
+const maxNoteLength = 200
+
+type RoundBody struct {
+	Game uuid.UUID `json:"game"`
+}
+
+type EventBody struct {
+	Player uuid.UUID `json:"player"`
+	Kind   string    `json:"kind"`
+	Amount *int32    `json:"amount"`
+	Note   *string   `json:"note"`
+}
+
+type EventURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// validateRoundBody checks if the body is valid.
+func validateRoundBody(requestBody io.Reader) (*RoundBody, error) {
+	v := validator.V{
+		"game": validator.IsUUIDV4,
+	}
+
+	body := RoundBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// isEventKind checks if the item is a known kind of event.
+func isEventKind(item interface{}) bool {
+	for _, kind := range partyModel.EventKinds {
+		if item == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateEventBody checks if the body is valid. A turn has no amount, a penalty is at least one
+// sip and defaults to one, points can be taken away but have to change the score.
+func validateEventBody(requestBody io.Reader) (*EventBody, error) {
+	v := validator.V{
+		"player": validator.IsUUIDV4,
+		"kind":   isEventKind,
+		"amount": validator.IsOptInt,
+		"note":   validator.IsOptString,
+	}
+
+	body := EventBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	switch body.Kind {
+	case partyModel.EventTurn:
+		if body.Amount != nil && *body.Amount != 0 {
+			return nil, &validator.ErrInvalidContent{Cause: "amount"}
+		}
+
+		body.Amount = types.Ptr(int32(0))
+
+	case partyModel.EventPenalty:
+		if body.Amount == nil {
+			body.Amount = types.Ptr(int32(1))
+		}
+
+		if *body.Amount < 1 {
+			return nil, &validator.ErrInvalidContent{Cause: "amount"}
+		}
+
+	case partyModel.EventPoints:
+		if body.Amount == nil || *body.Amount == 0 {
+			return nil, &validator.ErrInvalidContent{Cause: "amount"}
+		}
+	}
+
+	note := ""
+	if body.Note != nil {
+		note = strings.TrimSpace(*body.Note)
+	}
+
+	if utf8.RuneCountInString(note) > maxNoteLength {
+		return nil, &validator.ErrInvalidContent{Cause: "note"}
+	}
+
+	body.Note = &note
+
+	return &body, nil
+}
+
+// validateEventURL checks if the event URL is valid.
+func validateEventURL(r *server.Request) (*EventURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := EventURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// isActive checks whether the session is being played, only then rounds and events can be
+// recorded.
+func isActive(session *partyModel.Session) bool {
+	return *session.Status == partyModel.StatusActive
+}
+
+// PostRound starts a round of a session of the caller which plays a game of the catalog. The round
+// which is being played is ended.
+func PostRound(rw server.ResponseWriter, r *server.Request) {
+	session, status, err := getSession(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate round body
+	body, err := validateRoundBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if !isActive(session) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	game := gameModel.Game{
+		ID: &body.Game,
+	}
+
+	err = gameDao.GetGame(&game)
+	if err != nil {
+		log.Error(err.Error())
+
+		if dao.IsMissingResult(err) {
+			rw.JSON(http.StatusNotFound, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rounds, err := partyDao.GetRounds(session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	// Add round
+	now := time.Now().UTC()
+
+	round := partyModel.Round{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Session:   session.ID,
+		Game:      game.ID,
+		Number:    types.Ptr(int32(len(rounds) + 1)),
+		StartedAt: &now,
+	}
+
+	tx := cdb.NewTx()
+
+	err = partyDao.EndRounds(tx, session, now)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = partyDao.InsertRound(tx, &round)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = touchSession(tx, session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, round)
+}
+
+// EndRound ends the round of a session of the caller which is being played.
+func EndRound(rw server.ResponseWriter, r *server.Request) {
+	session, status, err := getSession(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	if !isActive(session) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	rounds, err := partyDao.GetRounds(session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	round := currentRound(rounds)
+	if round == nil {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// End round
+	now := time.Now().UTC()
+	round.EndedAt = &now
+
+	tx := cdb.NewTx()
+
+	err = partyDao.EndRounds(tx, session, now)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = touchSession(tx, session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, round)
+}
+
+// PostEvent records a turn, penalty or points of a player in the round of a session of the caller
+// which is being played.
+func PostEvent(rw server.ResponseWriter, r *server.Request) {
+	session, status, err := getSession(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate event body
+	body, err := validateEventBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if !isActive(session) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	player := partyModel.Player{
+		ID:      &body.Player,
+		Session: session.ID,
+	}
+
+	err = partyDao.GetPlayer(&player)
+	if err != nil {
+		log.Error(err.Error())
+
+		if dao.IsMissingResult(err) {
+			rw.JSON(http.StatusBadRequest, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rounds, err := partyDao.GetRounds(session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	round := currentRound(rounds)
+	if round == nil {
+		log.Error("No round of the session is being played")
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Add event
+	event := partyModel.Event{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Session:   session.ID,
+		Round:     round.ID,
+		Player:    player.ID,
+		Kind:      &body.Kind,
+		Amount:    body.Amount,
+		Note:      body.Note,
+		CreatedAt: types.Ptr(time.Now().UTC()),
+	}
+
+	tx := cdb.NewTx()
+
+	err = partyDao.InsertEvent(tx, &event)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = touchSession(tx, session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, event)
+}
+
+// DeleteEvent takes back an event of a session of the caller which wasn't closed.
+func DeleteEvent(rw server.ResponseWriter, r *server.Request) {
+	url, err := validateEventURL(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	event := partyModel.Event{
+		ID: &url.ID,
+	}
+
+	err = partyDao.GetEvent(&event)
+	if err != nil {
+		log.Error(err.Error())
+
+		if dao.IsMissingResult(err) {
+			rw.JSON(http.StatusNotFound, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	session, status, err := getOwnSession(r, *event.Session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	if isClosed(session) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Delete event
+	tx := cdb.NewTx()
+
+	err = partyDao.DeleteEvent(tx, &event)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = touchSession(tx, session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
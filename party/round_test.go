@@ -0,0 +1,43 @@
+package party
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+func TestValidateEventBody(t *testing.T) {
+	player := uuid.UUIDv4().String()
+
+	body, err := validateEventBody(strings.NewReader(
+		`{"player": "` + player + `", "kind": "penalty", "note": " Spilled "}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A penalty defaults to a single sip
+	if *body.Amount != 1 || *body.Note != "Spilled" {
+		t.Fatalf("unexpected body %+v", body)
+	}
+
+	body, err = validateEventBody(strings.NewReader(`{"player": "` + player + `", "kind": "turn"}`))
+	if err != nil || *body.Amount != 0 {
+		t.Fatalf("unexpected turn %+v %v", body, err)
+	}
+
+	invalid := []string{
+		`{"player": "` + player + `", "kind": "cheer"}`,
+		`{"player": "` + player + `", "kind": "turn", "amount": 2}`,
+		`{"player": "` + player + `", "kind": "penalty", "amount": 0}`,
+		`{"player": "` + player + `", "kind": "points"}`,
+		`{"player": "nope", "kind": "turn"}`,
+	}
+
+	for _, raw := range invalid {
+		_, err := validateEventBody(strings.NewReader(raw))
+		if err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
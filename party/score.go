@@ -0,0 +1,120 @@
+package party
+
+import (
+	"sort"
+	"time"
+
+	partyModel "github.com/marvindeckmyn/drankspelletjes-server/model/party"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// buildScores tallies the events per player. The players are ranked on their points, players with
+// the same points share their rank and keep the order of the session.
+func buildScores(players []*partyModel.Player, events []*partyModel.Event) []*partyModel.PlayerScore {
+	scores := []*partyModel.PlayerScore{}
+	byPlayer := map[uuid.UUID]*partyModel.PlayerScore{}
+
+	for _, player := range players {
+		score := partyModel.PlayerScore{
+			Player: *player.ID,
+			Name:   *player.Name,
+		}
+
+		scores = append(scores, &score)
+		byPlayer[*player.ID] = &score
+	}
+
+	for _, event := range events {
+		score := byPlayer[*event.Player]
+		if score == nil {
+			continue
+		}
+
+		switch *event.Kind {
+		case partyModel.EventTurn:
+			score.Turns++
+		case partyModel.EventPenalty:
+			score.Penalties += *event.Amount
+		case partyModel.EventPoints:
+			score.Points += *event.Amount
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Points > scores[j].Points
+	})
+
+	for i, score := range scores {
+		if i > 0 && score.Points == scores[i-1].Points {
+			score.Rank = scores[i-1].Rank
+			continue
+		}
+
+		score.Rank = int32(i + 1)
+	}
+
+	return scores
+}
+
+// currentRound returns the round which is being played, nil when there is none.
+func currentRound(rounds []*partyModel.Round) *partyModel.Round {
+	for _, round := range rounds {
+		if round.EndedAt == nil {
+			return round
+		}
+	}
+
+	return nil
+}
+
+// buildSummary sums up the session. The games are listed in the order they were first played with
+// the names from the given map. A session which isn't closed yet lasts until now.
+func buildSummary(session *partyModel.Session, players []*partyModel.Player,
+	rounds []*partyModel.Round, events []*partyModel.Event, names map[uuid.UUID]*string,
+	now time.Time) *partyModel.Summary {
+
+	end := now
+	if session.ClosedAt != nil {
+		end = *session.ClosedAt
+	}
+
+	summary := partyModel.Summary{
+		Session:  session,
+		Duration: int64(end.Sub(*session.CreatedAt).Seconds()),
+		Rounds:   int32(len(rounds)),
+		Games:    []*partyModel.GameSummary{},
+		Scores:   buildScores(players, events),
+		Winners:  []uuid.UUID{},
+	}
+
+	games := map[uuid.UUID]*partyModel.GameSummary{}
+
+	for _, round := range rounds {
+		// The game was removed from the catalog
+		if round.Game == nil {
+			continue
+		}
+
+		game := games[*round.Game]
+		if game == nil {
+			game = &partyModel.GameSummary{
+				Game: *round.Game,
+				Name: names[*round.Game],
+			}
+
+			games[*round.Game] = game
+			summary.Games = append(summary.Games, game)
+		}
+
+		game.Rounds++
+	}
+
+	// Nobody wins a session without points
+	for _, score := range summary.Scores {
+		if score.Rank == 1 && score.Points > 0 {
+			summary.Winners = append(summary.Winners, score.Player)
+		}
+	}
+
+	return &summary
+}
@@ -0,0 +1,116 @@
+package party
+
+import (
+	"testing"
+	"time"
+
+	partyModel "github.com/marvindeckmyn/drankspelletjes-server/model/party"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// player creates a player with the given name.
+func player(name string) *partyModel.Player {
+	return &partyModel.Player{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Name: types.Ptr(name),
+	}
+}
+
+// event creates an event of the player.
+func event(player *partyModel.Player, kind string, amount int32) *partyModel.Event {
+	return &partyModel.Event{
+		ID:     types.Ptr(uuid.UUIDv4()),
+		Player: player.ID,
+		Kind:   types.Ptr(kind),
+		Amount: types.Ptr(amount),
+	}
+}
+
+func TestBuildScores(t *testing.T) {
+	anna, bert, cas := player("Anna"), player("Bert"), player("Cas")
+
+	events := []*partyModel.Event{
+		event(anna, partyModel.EventTurn, 0),
+		event(anna, partyModel.EventPoints, 3),
+		event(bert, partyModel.EventTurn, 0),
+		event(bert, partyModel.EventPenalty, 2),
+		event(bert, partyModel.EventPenalty, 1),
+		event(cas, partyModel.EventPoints, 5),
+		event(cas, partyModel.EventPoints, -2),
+	}
+
+	scores := buildScores([]*partyModel.Player{anna, bert, cas}, events)
+
+	// Anna and Cas share the first place, Anna stays first as she joined first
+	if scores[0].Player != *anna.ID || scores[1].Player != *cas.ID || scores[2].Player != *bert.ID {
+		t.Fatalf("unexpected order %+v %+v %+v", scores[0], scores[1], scores[2])
+	}
+
+	if scores[0].Rank != 1 || scores[1].Rank != 1 || scores[2].Rank != 3 {
+		t.Fatal("expected players with the same points to share their rank")
+	}
+
+	if scores[0].Turns != 1 || scores[2].Penalties != 3 || scores[1].Points != 3 {
+		t.Fatal("unexpected tallies")
+	}
+}
+
+func TestBuildSummary(t *testing.T) {
+	start := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+
+	session := partyModel.Session{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		CreatedAt: &start,
+		ClosedAt:  types.Ptr(start.Add(2 * time.Hour)),
+	}
+
+	kings, cups := uuid.UUIDv4(), uuid.UUIDv4()
+
+	rounds := []*partyModel.Round{
+		{Number: types.Ptr(int32(1)), Game: &kings, EndedAt: &start},
+		{Number: types.Ptr(int32(2)), Game: &cups, EndedAt: &start},
+		{Number: types.Ptr(int32(3)), Game: &kings, EndedAt: &start},
+		{Number: types.Ptr(int32(4)), Game: nil, EndedAt: &start},
+	}
+
+	names := map[uuid.UUID]*string{kings: types.Ptr("Koningen")}
+
+	anna, bert := player("Anna"), player("Bert")
+	events := []*partyModel.Event{event(bert, partyModel.EventPoints, 1)}
+
+	summary := buildSummary(&session, []*partyModel.Player{anna, bert}, rounds, events, names,
+		start.Add(5*time.Hour))
+
+	if summary.Duration != 7200 || summary.Rounds != 4 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	if len(summary.Games) != 2 || summary.Games[0].Game != kings || summary.Games[0].Rounds != 2 ||
+		*summary.Games[0].Name != "Koningen" || summary.Games[1].Name != nil {
+		t.Fatalf("unexpected games %+v", summary.Games)
+	}
+
+	if len(summary.Winners) != 1 || summary.Winners[0] != *bert.ID {
+		t.Fatalf("unexpected winners %v", summary.Winners)
+	}
+
+	// Without points nobody wins
+	summary = buildSummary(&session, []*partyModel.Player{anna, bert}, rounds, nil, names, start)
+	if len(summary.Winners) != 0 {
+		t.Fatal("expected no winners without points")
+	}
+}
+
+func TestCurrentRound(t *testing.T) {
+	ended := time.Now()
+	open := &partyModel.Round{Number: types.Ptr(int32(2))}
+
+	if currentRound([]*partyModel.Round{{EndedAt: &ended}, open}) != open {
+		t.Fatal("expected the round without an end")
+	}
+
+	if currentRound([]*partyModel.Round{{EndedAt: &ended}}) != nil {
+		t.Fatal("expected no round to be played")
+	}
+}
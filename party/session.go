@@ -0,0 +1,478 @@
+package party
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	gameDao "github.com/marvindeckmyn/drankspelletjes-server/dao/game"
+	partyDao "github.com/marvindeckmyn/drankspelletjes-server/dao/party"
+	"github.com/marvindeckmyn/drankspelletjes-server/locale"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	gameModel "github.com/marvindeckmyn/drankspelletjes-server/model/game"
+	partyModel "github.com/marvindeckmyn/drankspelletjes-server/model/party"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// maxNameLength is the largest number of characters of the name of a session or a player.
+const maxNameLength = 100
+
+type SessionBody struct {
+	Name string `json:"name"`
+}
+
+type SessionURL struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// validateName trims the name and checks that it isn't blank or too long.
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return "", &validator.ErrInvalidContent{Cause: "name"}
+	}
+
+	return name, nil
+}
+
+// validateSessionBody checks if the body is valid.
+func validateSessionBody(requestBody io.Reader) (*SessionBody, error) {
+	v := validator.V{
+		"name": validator.IsString,
+	}
+
+	body := SessionBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	body.Name, err = validateName(body.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validateSessionURL checks if the session URL is valid.
+func validateSessionURL(r *server.Request) (*SessionURL, error) {
+	v := validator.V{
+		"id": validator.IsUUIDV4,
+	}
+
+	url := SessionURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// getOwnSession fetches the session with the given ID which the caller hosts. Sessions of other
+// hosts are reported as missing. The status to respond with is returned when it fails.
+func getOwnSession(r *server.Request, id uuid.UUID) (*partyModel.Session, int, error) {
+	session := partyModel.Session{
+		ID:   &id,
+		Host: r.Account().ID,
+	}
+
+	err := partyDao.GetSession(&session)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, http.StatusNotFound, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return &session, http.StatusOK, nil
+}
+
+// getSession fetches the session from the URL which the caller hosts. The status to respond with is
+// returned when it fails.
+func getSession(r *server.Request) (*partyModel.Session, int, error) {
+	url, err := validateSessionURL(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	return getOwnSession(r, url.ID)
+}
+
+// isClosed checks whether the session was closed, closed sessions can't change anymore.
+func isClosed(session *partyModel.Session) bool {
+	return *session.Status == partyModel.StatusClosed
+}
+
+// touchSession marks the session as changed.
+func touchSession(tx *cdb.Transaction, session *partyModel.Session) error {
+	session.UpdatedAt = types.Ptr(time.Now().UTC())
+
+	selectors := map[string]interface{}{
+		"ID": session.ID,
+	}
+
+	changes := partyModel.Session{
+		UpdatedAt: session.UpdatedAt,
+	}
+
+	return partyDao.UpdateSession(tx, &changes, selectors)
+}
+
+// loadState fetches everything which is needed to resume the session.
+func loadState(session *partyModel.Session) (*partyModel.SessionState, error) {
+	players, err := partyDao.GetPlayers(session)
+	if err != nil {
+		return nil, err
+	}
+
+	rounds, err := partyDao.GetRounds(session)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := partyDao.GetEvents(session)
+	if err != nil {
+		return nil, err
+	}
+
+	state := partyModel.SessionState{
+		Session:      session,
+		Players:      players,
+		Rounds:       rounds,
+		CurrentRound: currentRound(rounds),
+		Events:       events,
+		Scores:       buildScores(players, events),
+	}
+
+	return &state, nil
+}
+
+// loadSummary sums up the session with the names of the games in the language of the request.
+func loadSummary(r *server.Request, session *partyModel.Session) (*partyModel.Summary,
+	*locale.Localizer, error) {
+
+	state, err := loadState(session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l := locale.FromRequest(r)
+	names := map[uuid.UUID]*string{}
+
+	for _, round := range state.Rounds {
+		if round.Game == nil {
+			continue
+		}
+
+		if _, ok := names[*round.Game]; ok {
+			continue
+		}
+
+		game := gameModel.Game{
+			ID: round.Game,
+		}
+
+		err = gameDao.GetGame(&game)
+		if err != nil {
+			if dao.IsMissingResult(err) {
+				names[*round.Game] = nil
+				continue
+			}
+
+			return nil, nil, err
+		}
+
+		names[*round.Game] = l.String(game.Name)
+	}
+
+	summary := buildSummary(session, state.Players, state.Rounds, state.Events, names, time.Now().UTC())
+	return summary, l, nil
+}
+
+// writeSummary responds with the summary of the session.
+func writeSummary(rw server.ResponseWriter, r *server.Request, session *partyModel.Session) {
+	summary, l, err := loadSummary(r, session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	// Caches have to keep the languages apart
+	rw.W.Header().Add("Vary", "Accept-Language")
+	l.SetHeader(rw)
+	rw.JSON(http.StatusOK, summary)
+}
+
+// setStatus changes the status of the session, when it currently has one of the given statuses.
+// Closing the session ends the round which is being played.
+func setStatus(rw server.ResponseWriter, r *server.Request, status string, from ...string) {
+	session, code, err := getSession(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(code, nil)
+		return
+	}
+
+	allowed := false
+	for _, current := range from {
+		if *session.Status == current {
+			allowed = true
+		}
+	}
+
+	if !allowed {
+		log.Error("The session can't become %s while it is %s", status, *session.Status)
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Update status
+	now := time.Now().UTC()
+	session.Status = &status
+	session.UpdatedAt = &now
+
+	changes := partyModel.Session{
+		Status:    session.Status,
+		UpdatedAt: session.UpdatedAt,
+	}
+
+	tx := cdb.NewTx()
+
+	if status == partyModel.StatusClosed {
+		session.ClosedAt = &now
+		changes.ClosedAt = &now
+
+		err = partyDao.EndRounds(tx, session, now)
+		if err != nil {
+			log.Error(err.Error())
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	selectors := map[string]interface{}{
+		"ID": session.ID,
+	}
+
+	err = partyDao.UpdateSession(tx, &changes, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	if status == partyModel.StatusClosed {
+		writeSummary(rw, r, session)
+		return
+	}
+
+	rw.JSON(http.StatusOK, session)
+}
+
+// GetSessions to retrieve the sessions the caller hosts, the ones which weren't closed first.
+func GetSessions(rw server.ResponseWriter, r *server.Request) {
+	sessions, err := partyDao.GetSessionsByHost(r.Account())
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, sessions)
+}
+
+// GetSession to retrieve everything needed to resume a session: its players, rounds, events and
+// the scores so far.
+func GetSession(rw server.ResponseWriter, r *server.Request) {
+	session, status, err := getSession(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	state, err := loadState(session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, state)
+}
+
+// GetSessionSummary to retrieve the summary of a session. A session which isn't closed yet is
+// summed up so far.
+func GetSessionSummary(rw server.ResponseWriter, r *server.Request) {
+	session, status, err := getSession(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	writeSummary(rw, r, session)
+}
+
+// PostSession starts a session hosted by the caller.
+func PostSession(rw server.ResponseWriter, r *server.Request) {
+	// Validate session body
+	body, err := validateSessionBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	// Add session
+	now := time.Now().UTC()
+
+	session := partyModel.Session{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Host:      r.Account().ID,
+		Name:      &body.Name,
+		Status:    types.Ptr(partyModel.StatusActive),
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+
+	tx := cdb.NewTx()
+
+	err = partyDao.InsertSession(tx, &session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, session)
+}
+
+// UpdateSession renames a session of the caller which wasn't closed.
+func UpdateSession(rw server.ResponseWriter, r *server.Request) {
+	session, status, err := getSession(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate session body
+	body, err := validateSessionBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	if isClosed(session) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Update session
+	session.Name = &body.Name
+	session.UpdatedAt = types.Ptr(time.Now().UTC())
+
+	selectors := map[string]interface{}{
+		"ID": session.ID,
+	}
+
+	changes := partyModel.Session{
+		Name:      session.Name,
+		UpdatedAt: session.UpdatedAt,
+	}
+
+	tx := cdb.NewTx()
+
+	err = partyDao.UpdateSession(tx, &changes, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, session)
+}
+
+// PauseSession pauses an active session of the caller, nothing can be recorded until it is resumed.
+func PauseSession(rw server.ResponseWriter, r *server.Request) {
+	setStatus(rw, r, partyModel.StatusPaused, partyModel.StatusActive)
+}
+
+// ResumeSession resumes a paused session of the caller.
+func ResumeSession(rw server.ResponseWriter, r *server.Request) {
+	setStatus(rw, r, partyModel.StatusActive, partyModel.StatusPaused)
+}
+
+// CloseSession closes a session of the caller and responds with its summary. A closed session
+// can't change anymore.
+func CloseSession(rw server.ResponseWriter, r *server.Request) {
+	setStatus(rw, r, partyModel.StatusClosed, partyModel.StatusActive, partyModel.StatusPaused)
+}
+
+// DeleteSession deletes a session of the caller together with everything that was recorded.
+func DeleteSession(rw server.ResponseWriter, r *server.Request) {
+	session, status, err := getSession(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Delete session
+	tx := cdb.NewTx()
+
+	err = partyDao.DeleteSession(tx, session)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = tx.Exec()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
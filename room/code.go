@@ -0,0 +1,74 @@
+package room
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+// codeAlphabet are the characters of a join code. Characters which look alike are left out so the
+// code can be read out loud and typed over.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// codeLength is the number of characters of a join code.
+const codeLength = 6
+
+// joinBaseURL is the link which is shown as QR code to join a room, without the code.
+var joinBaseURL = "https://drankspelletjes.local/join"
+
+// generateCode creates a random join code.
+func generateCode() (string, error) {
+	code := make([]byte, codeLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		code[i] = codeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+// normalizeCode makes typed codes match: case and spaces don't matter.
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
+
+// isCode checks if the item is a join code.
+func isCode(item interface{}) bool {
+	code, ok := item.(string)
+	if !ok {
+		return false
+	}
+
+	code = normalizeCode(code)
+	if len(code) != codeLength {
+		return false
+	}
+
+	for _, c := range code {
+		if !strings.ContainsRune(codeAlphabet, c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SetJoinURL configures the link to join a room, the code is appended to it. The default is kept
+// when the URL is empty.
+func SetJoinURL(url string) {
+	url = strings.TrimSuffix(strings.TrimSpace(url), "/")
+	if url != "" {
+		joinBaseURL = url
+	}
+}
+
+// joinURL returns the link to join the room with the code.
+func joinURL(code string) string {
+	return joinBaseURL + "/" + code
+}
@@ -0,0 +1,29 @@
+package room
+
+// ErrNoParticipant is thrown when the request isn't made by a participant of the room.
+type ErrNoParticipant struct{}
+
+func (e *ErrNoParticipant) Error() string {
+	return "the request isn't made by a participant of the room"
+}
+
+// ErrNotHost is thrown when a participant which doesn't host the room tries to change it.
+type ErrNotHost struct{}
+
+func (e *ErrNotHost) Error() string {
+	return "the participant doesn't host the room"
+}
+
+// ErrRoomClosed is thrown when the room was closed.
+type ErrRoomClosed struct{}
+
+func (e *ErrRoomClosed) Error() string {
+	return "the room was closed"
+}
+
+// ErrNoCode is thrown when no free join code was found.
+type ErrNoCode struct{}
+
+func (e *ErrNoCode) Error() string {
+	return "no free join code was found"
+}
@@ -0,0 +1,401 @@
+package room
+
+import (
+	"sync"
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	roomDao "github.com/marvindeckmyn/drankspelletjes-server/dao/room"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	roomModel "github.com/marvindeckmyn/drankspelletjes-server/model/room"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// presenceGrace is how long a participant without a connection stays online. Phones which lose
+// their connection for a moment, or reconnect after a restart, don't go offline.
+var presenceGrace = 15 * time.Second
+
+// idleTimeout is how long a room stays open while nobody is online in it.
+var idleTimeout = 30 * time.Minute
+
+// subscriberBuffer is the number of events which are queued for a connection. Connections which
+// fall further behind are dropped and have to reconnect.
+const subscriberBuffer = 64
+
+// liveRoom is a room which is loaded in memory. All changes to a room go through its lock, so its
+// events are numbered without gaps.
+type liveRoom struct {
+	mu          sync.Mutex
+	state       *roomModel.State
+	subscribers map[chan *roomModel.Event]struct{}
+	streams     map[uuid.UUID]int
+	timers      map[uuid.UUID]*time.Timer
+	idle        *time.Timer
+
+	// save stores the events of a commit, see saveEvents.
+	save func(tx *cdb.Transaction, events []*roomModel.Event) error
+}
+
+var (
+	roomsMu sync.Mutex
+	rooms   = map[string]*liveRoom{}
+)
+
+// saveEvents stores the events in the transaction and executes it.
+func saveEvents(tx *cdb.Transaction, events []*roomModel.Event) error {
+	for _, event := range events {
+		err := roomDao.InsertEvent(tx, event)
+		if err != nil {
+			log.Error(err.Error())
+			return err
+		}
+	}
+
+	return tx.Exec()
+}
+
+// newLiveRoom wraps the state of a room, its events are stored with the given function.
+func newLiveRoom(state *roomModel.State,
+	save func(tx *cdb.Transaction, events []*roomModel.Event) error) *liveRoom {
+
+	return &liveRoom{
+		state:       state,
+		subscribers: map[chan *roomModel.Event]struct{}{},
+		streams:     map[uuid.UUID]int{},
+		timers:      map[uuid.UUID]*time.Timer{},
+		save:        save,
+	}
+}
+
+// newEvent creates an event of the given kind, it is numbered when it is committed.
+func newEvent(kind string, participant *uuid.UUID, data map[string]interface{}) *roomModel.Event {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	return &roomModel.Event{
+		Kind:        &kind,
+		Participant: participant,
+		Data:        &data,
+		CreatedAt:   types.Ptr(time.Now().UTC()),
+	}
+}
+
+// getLiveRoom returns the open room with the code. A room which isn't in memory, for example after
+// a restart, is rebuilt by replaying its events.
+func getLiveRoom(code string) (*liveRoom, error) {
+	roomsMu.Lock()
+	live := rooms[code]
+	roomsMu.Unlock()
+
+	if live != nil {
+		return live, nil
+	}
+
+	// The room is loaded without the lock, so other rooms aren't held up by the database
+	room := roomModel.Room{
+		Code:   &code,
+		Status: types.Ptr(roomModel.StatusOpen),
+	}
+
+	err := roomDao.GetRoom(&room)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := roomDao.GetEvents(&room, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	// Another request loaded the room in the meantime
+	if rooms[code] != nil {
+		return rooms[code], nil
+	}
+
+	live = newLiveRoom(replay(&room, events), saveEvents)
+
+	// Participants which were online get the time to reconnect before they go offline
+	live.mu.Lock()
+
+	for _, participant := range live.state.Participants {
+		if isPresent(participant) {
+			live.schedulePresence(participant.ID)
+		}
+	}
+
+	live.scheduleIdle()
+	live.mu.Unlock()
+
+	rooms[code] = live
+	return live, nil
+}
+
+// RestoreRooms loads the open rooms in memory after a restart, so the rooms which nobody comes back
+// to are closed once they are idle.
+func RestoreRooms() error {
+	open, err := roomDao.GetOpenRooms()
+	if err != nil {
+		return err
+	}
+
+	for _, room := range open {
+		_, err = getLiveRoom(*room.Code)
+		if err != nil && !dao.IsMissingResult(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addLiveRoom keeps the room in memory.
+func addLiveRoom(live *liveRoom) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	rooms[live.state.Code] = live
+}
+
+// removeLiveRoom forgets the room with the code.
+func removeLiveRoom(code string) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	delete(rooms, code)
+}
+
+// commit numbers and stores the events together with the rest of the transaction, then applies them
+// to the room and pushes them to its connections. The lock of the room has to be held.
+func (l *liveRoom) commit(tx *cdb.Transaction, events ...*roomModel.Event) error {
+	seq := l.state.Seq
+
+	for _, event := range events {
+		seq++
+
+		room := l.state.Room
+		event.Room = &room
+		event.Seq = types.Ptr(seq)
+	}
+
+	err := l.save(tx, events)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		applyEvent(l.state, event)
+		l.broadcast(event)
+	}
+
+	l.scheduleIdle()
+	return nil
+}
+
+// broadcast pushes the event to the connections of the room. The lock of the room has to be held.
+func (l *liveRoom) broadcast(event *roomModel.Event) {
+	for ch := range l.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// The connection can't keep up, it has to reconnect and catch up
+			close(ch)
+			delete(l.subscribers, ch)
+		}
+	}
+}
+
+// subscribe adds a connection to the room. The lock of the room has to be held.
+func (l *liveRoom) subscribe() chan *roomModel.Event {
+	ch := make(chan *roomModel.Event, subscriberBuffer)
+	l.subscribers[ch] = struct{}{}
+
+	return ch
+}
+
+// unsubscribe removes a connection from the room, unless it was already dropped. The lock of the
+// room has to be held.
+func (l *liveRoom) unsubscribe(ch chan *roomModel.Event) {
+	if _, ok := l.subscribers[ch]; ok {
+		close(ch)
+		delete(l.subscribers, ch)
+	}
+}
+
+// ensureHost hands the room to the next participant when the host isn't online anymore. A host
+// which left is let go even when nobody can take over. The lock of the room has to be held.
+func (l *liveRoom) ensureHost() error {
+	if l.state.Status != roomModel.StatusOpen || !needsHost(l.state) {
+		return nil
+	}
+
+	next := nextHost(l.state)
+
+	if next == nil {
+		if l.state.Host == nil {
+			return nil
+		}
+
+		host := findParticipant(l.state, *l.state.Host)
+		if host != nil && !host.Left {
+			return nil
+		}
+	}
+
+	return l.commit(cdb.NewTx(), newEvent(roomModel.EventHost, next, nil))
+}
+
+// schedulePresence takes the participant offline when it doesn't connect within the grace period.
+// The lock of the room has to be held.
+func (l *liveRoom) schedulePresence(id uuid.UUID) {
+	l.stopPresence(id)
+
+	var timer *time.Timer
+
+	timer = time.AfterFunc(presenceGrace, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		// The timer was replaced or stopped in the meantime
+		if l.timers[id] != timer {
+			return
+		}
+
+		delete(l.timers, id)
+
+		err := l.expirePresence(id)
+		if err != nil {
+			log.Error(err.Error())
+		}
+	})
+
+	l.timers[id] = timer
+}
+
+// scheduleIdle closes the room when nobody is online in it for idleTimeout. The timer is stopped as
+// soon as somebody is. The lock of the room has to be held.
+func (l *liveRoom) scheduleIdle() {
+	if l.state.Status != roomModel.StatusOpen || nextHost(l.state) != nil {
+		l.stopIdle()
+		return
+	}
+
+	if l.idle != nil {
+		return
+	}
+
+	var timer *time.Timer
+
+	timer = time.AfterFunc(idleTimeout, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		// The timer was stopped in the meantime
+		if l.idle != timer {
+			return
+		}
+
+		l.idle = nil
+
+		err := l.close()
+		if err != nil {
+			log.Error(err.Error())
+		}
+	})
+
+	l.idle = timer
+}
+
+// stopIdle stops the idle timer of the room. The lock of the room has to be held.
+func (l *liveRoom) stopIdle() {
+	if l.idle == nil {
+		return
+	}
+
+	l.idle.Stop()
+	l.idle = nil
+}
+
+// stopPresence stops the timer of the participant. The lock of the room has to be held.
+func (l *liveRoom) stopPresence(id uuid.UUID) {
+	timer := l.timers[id]
+	if timer == nil {
+		return
+	}
+
+	timer.Stop()
+	delete(l.timers, id)
+}
+
+// expirePresence takes the participant offline when it has no connections left. The lock of the
+// room has to be held.
+func (l *liveRoom) expirePresence(id uuid.UUID) error {
+	if l.state.Status != roomModel.StatusOpen || l.streams[id] > 0 {
+		return nil
+	}
+
+	if !isPresent(findParticipant(l.state, id)) {
+		return nil
+	}
+
+	err := l.commit(cdb.NewTx(), newEvent(roomModel.EventOffline, &id, nil))
+	if err != nil {
+		return err
+	}
+
+	return l.ensureHost()
+}
+
+// connect registers a connection of the participant, which brings it online. The lock of the room
+// has to be held.
+func (l *liveRoom) connect(id uuid.UUID) error {
+	l.streams[id]++
+	l.stopPresence(id)
+
+	participant := findParticipant(l.state, id)
+	if participant == nil || participant.Left || participant.Online {
+		return l.ensureHost()
+	}
+
+	err := l.commit(cdb.NewTx(), newEvent(roomModel.EventOnline, &id, nil))
+	if err != nil {
+		return err
+	}
+
+	return l.ensureHost()
+}
+
+// disconnect unregisters a connection of the participant. The participant goes offline when it
+// doesn't reconnect in time. The lock of the room has to be held.
+func (l *liveRoom) disconnect(id uuid.UUID) {
+	l.streams[id]--
+
+	if l.streams[id] > 0 {
+		return
+	}
+
+	delete(l.streams, id)
+
+	if l.state.Status == roomModel.StatusOpen && isPresent(findParticipant(l.state, id)) {
+		l.schedulePresence(id)
+	}
+}
+
+// shutdown drops the connections and timers of a closed room. The lock of the room has to be held.
+func (l *liveRoom) shutdown() {
+	for ch := range l.subscribers {
+		close(ch)
+		delete(l.subscribers, ch)
+	}
+
+	for id := range l.timers {
+		l.stopPresence(id)
+	}
+
+	l.stopIdle()
+}
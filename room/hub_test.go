@@ -0,0 +1,197 @@
+package room
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	roomModel "github.com/marvindeckmyn/drankspelletjes-server/model/room"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// memoryEvents returns a function which keeps the events in memory instead of storing them,
+// together with the kept events.
+func memoryEvents() (*[]*roomModel.Event, func(tx *cdb.Transaction, events []*roomModel.Event) error) {
+	saved := []*roomModel.Event{}
+
+	save := func(tx *cdb.Transaction, events []*roomModel.Event) error {
+		saved = append(saved, events...)
+		return nil
+	}
+
+	return &saved, save
+}
+
+// openRoom opens a room which is hosted by the first participant. Its events are kept in memory.
+func openRoom(t *testing.T, names ...string) (*liveRoom, *[]*roomModel.Event, []uuid.UUID) {
+	saved, save := memoryEvents()
+	live := newLiveRoom(newState(testRoom("ABC234")), save)
+	ids := []uuid.UUID{}
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	for _, name := range names {
+		id := uuid.UUIDv4()
+		ids = append(ids, id)
+
+		err := live.commit(cdb.NewTx(),
+			newEvent(roomModel.EventJoined, &id, map[string]interface{}{"name": name}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := live.ensureHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return live, saved, ids
+}
+
+func TestHostMigration(t *testing.T) {
+	live, saved, ids := openRoom(t, "Anna", "Bert")
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	ch := live.subscribe()
+
+	// The host drops its stream and doesn't come back in time
+	err := live.connect(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live.disconnect(ids[0])
+
+	if live.timers[ids[0]] == nil {
+		t.Fatal("expected the presence of the host to be timed")
+	}
+
+	err = live.expirePresence(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *live.state.Host != ids[1] {
+		t.Fatal("expected Bert to host the room")
+	}
+
+	offline, host := <-ch, <-ch
+	if *offline.Kind != roomModel.EventOffline || *host.Kind != roomModel.EventHost {
+		t.Fatal("expected the changes to be pushed to the stream")
+	}
+
+	// The state is the same after a restart
+	recovered := replay(testRoom("ABC234"), *saved)
+	if recovered.Seq != live.state.Seq || *recovered.Host != ids[1] ||
+		recovered.Participants[0].Online {
+		t.Fatalf("unexpected recovered state %+v", recovered)
+	}
+
+	live.shutdown()
+
+	if _, ok := <-ch; ok {
+		t.Fatal("expected the stream to be dropped")
+	}
+}
+
+func TestPresenceGrace(t *testing.T) {
+	original := presenceGrace
+	presenceGrace = 10 * time.Millisecond
+
+	t.Cleanup(func() {
+		presenceGrace = original
+	})
+
+	live, _, ids := openRoom(t, "Anna", "Bert")
+
+	live.mu.Lock()
+
+	err := live.connect(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live.schedulePresence(ids[0])
+
+	live.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	// Bert has a stream and stays online, Anna went offline and handed the room over
+	if !isPresent(findParticipant(live.state, ids[1])) || isPresent(findParticipant(live.state, ids[0])) {
+		t.Fatalf("unexpected participants %+v %+v", live.state.Participants[0], live.state.Participants[1])
+	}
+
+	if *live.state.Host != ids[1] {
+		t.Fatal("expected Bert to host the room")
+	}
+}
+
+func TestIdleRoomCloses(t *testing.T) {
+	original := idleTimeout
+	idleTimeout = 10 * time.Millisecond
+
+	t.Cleanup(func() {
+		idleTimeout = original
+	})
+
+	live, _, ids := openRoom(t, "Anna")
+	addLiveRoom(live)
+
+	live.mu.Lock()
+
+	if live.idle != nil {
+		t.Fatal("expected a room with somebody online not to be idle")
+	}
+
+	// Anna goes offline, a reconnect stops the timer again
+	err := live.expirePresence(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if live.idle == nil {
+		t.Fatal("expected the idle room to be timed")
+	}
+
+	err = live.connect(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if live.idle != nil {
+		t.Fatal("expected the timer to stop when somebody comes online")
+	}
+
+	live.disconnect(ids[0])
+
+	err = live.expirePresence(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	if live.state.Status != roomModel.StatusClosed {
+		t.Fatalf("expected the idle room to be closed, got %s", live.state.Status)
+	}
+
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	if rooms[live.state.Code] != nil {
+		t.Fatal("expected the closed room to be forgotten")
+	}
+}
@@ -0,0 +1,716 @@
+package room
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/marvindeckmyn/drankspelletjes-server/auth"
+	"github.com/marvindeckmyn/drankspelletjes-server/cdb"
+	"github.com/marvindeckmyn/drankspelletjes-server/dao"
+	partyDao "github.com/marvindeckmyn/drankspelletjes-server/dao/party"
+	roomDao "github.com/marvindeckmyn/drankspelletjes-server/dao/room"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	partyModel "github.com/marvindeckmyn/drankspelletjes-server/model/party"
+	roomModel "github.com/marvindeckmyn/drankspelletjes-server/model/room"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+	"github.com/marvindeckmyn/drankspelletjes-server/validator"
+)
+
+// maxNameLength is the largest number of characters of the name of a participant.
+const maxNameLength = 30
+
+// maxParticipants is the largest number of participants in a room.
+const maxParticipants = 50
+
+// maxStateSize is the largest size of the shared game state in bytes.
+const maxStateSize = 64 * 1024
+
+// maxCodeAttempts is the number of codes which are tried before creating a room is given up.
+const maxCodeAttempts = 5
+
+type RoomBody struct {
+	Name    string     `json:"name"`
+	Session *uuid.UUID `json:"session"`
+}
+
+type JoinBody struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type StateBody struct {
+	Version int64                  `json:"version"`
+	State   map[string]interface{} `json:"state"`
+}
+
+type HostBody struct {
+	Participant uuid.UUID `json:"participant"`
+}
+
+type RoomURL struct {
+	Code string `json:"code"`
+}
+
+// validateName trims the name and checks that it isn't blank or too long.
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return "", &validator.ErrInvalidContent{Cause: "name"}
+	}
+
+	return name, nil
+}
+
+// validateRoomBody checks if the body is valid.
+func validateRoomBody(requestBody io.Reader) (*RoomBody, error) {
+	v := validator.V{
+		"name":    validator.IsString,
+		"session": validator.IsOptUUIDV4,
+	}
+
+	body := RoomBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	body.Name, err = validateName(body.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validateJoinBody checks if the body is valid.
+func validateJoinBody(requestBody io.Reader) (*JoinBody, error) {
+	v := validator.V{
+		"code": isCode,
+		"name": validator.IsString,
+	}
+
+	body := JoinBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	body.Code = normalizeCode(body.Code)
+
+	body.Name, err = validateName(body.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validateStateBody checks if the body is valid.
+func validateStateBody(requestBody io.Reader) (*StateBody, error) {
+	v := validator.V{
+		"version": validator.IsInt,
+		"state":   validator.IsMapStrInterface,
+	}
+
+	body := StateBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	data, err := json.Marshal(body.State)
+	if err != nil || len(data) > maxStateSize {
+		return nil, &validator.ErrInvalidContent{Cause: "state"}
+	}
+
+	return &body, nil
+}
+
+// validateHostBody checks if the body is valid.
+func validateHostBody(requestBody io.Reader) (*HostBody, error) {
+	v := validator.V{
+		"participant": validator.IsUUIDV4,
+	}
+
+	body := HostBody{}
+
+	err := v.ValidateAndMarshalBody(requestBody, &body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// validateRoomURL checks if the room URL is valid.
+func validateRoomURL(r *server.Request) (*RoomURL, error) {
+	v := validator.V{
+		"code": isCode,
+	}
+
+	url := RoomURL{}
+
+	err := v.ValidateAndMarshalURL(r, &url)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	url.Code = normalizeCode(url.Code)
+
+	return &url, nil
+}
+
+// participantToken returns the token of the participant from the Authorization header. Browsers
+// can't set headers on an event stream, so the token can be passed as query parameter as well.
+func participantToken(r *server.Request) string {
+	header := r.R.Header.Get("Authorization")
+	if len(header) >= 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+
+	return r.R.URL.Query().Get("token")
+}
+
+// getRoom fetches the open room from the URL. The status to respond with is returned when it fails.
+func getRoom(r *server.Request) (*liveRoom, int, error) {
+	url, err := validateRoomURL(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	live, err := getLiveRoom(url.Code)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, http.StatusNotFound, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return live, http.StatusOK, nil
+}
+
+// getParticipant fetches the open room from the URL and the participant of the room the request is
+// authenticated as. The status to respond with is returned when it fails.
+func getParticipant(r *server.Request) (*liveRoom, uuid.UUID, int, error) {
+	live, status, err := getRoom(r)
+	if err != nil {
+		return nil, uuid.UUID{}, status, err
+	}
+
+	token := participantToken(r)
+	if token == "" {
+		return nil, uuid.UUID{}, http.StatusUnauthorized, &ErrNoParticipant{}
+	}
+
+	participant := roomModel.Participant{
+		Room:      &live.state.Room,
+		TokenHash: types.Ptr(auth.HashToken(token)),
+	}
+
+	err = roomDao.GetParticipant(&participant)
+	if err != nil {
+		if dao.IsMissingResult(err) {
+			return nil, uuid.UUID{}, http.StatusUnauthorized, &ErrNoParticipant{}
+		}
+
+		return nil, uuid.UUID{}, http.StatusInternalServerError, err
+	}
+
+	return live, *participant.ID, http.StatusOK, nil
+}
+
+// checkParticipant checks whether the room is still open and the participant didn't leave it. The
+// lock of the room has to be held. The status to respond with is returned when it fails.
+func (l *liveRoom) checkParticipant(id uuid.UUID) (int, error) {
+	if l.state.Status != roomModel.StatusOpen {
+		return http.StatusNotFound, &ErrRoomClosed{}
+	}
+
+	participant := findParticipant(l.state, id)
+	if participant == nil || participant.Left {
+		return http.StatusForbidden, &ErrNoParticipant{}
+	}
+
+	return http.StatusOK, nil
+}
+
+// checkHost checks whether the participant hosts the room. The lock of the room has to be held. The
+// status to respond with is returned when it fails.
+func (l *liveRoom) checkHost(id uuid.UUID) (int, error) {
+	status, err := l.checkParticipant(id)
+	if err != nil {
+		return status, err
+	}
+
+	if l.state.Host == nil || *l.state.Host != id {
+		return http.StatusForbidden, &ErrNotHost{}
+	}
+
+	return http.StatusOK, nil
+}
+
+// nameTaken checks whether a participant which didn't leave the room already has the name, ignoring
+// case.
+func nameTaken(state *roomModel.State, name string) bool {
+	for _, participant := range state.Participants {
+		if !participant.Left && strings.EqualFold(participant.Name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newParticipant creates a participant of the room with a new token.
+func newParticipant(room uuid.UUID, name string) (*roomModel.Participant, string, error) {
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	participant := roomModel.Participant{
+		ID:        types.Ptr(uuid.UUIDv4()),
+		Room:      &room,
+		Name:      &name,
+		TokenHash: types.Ptr(auth.HashToken(token)),
+		JoinedAt:  types.Ptr(time.Now().UTC()),
+	}
+
+	return &participant, token, nil
+}
+
+// newCode generates a join code which no open room uses.
+func newCode() (string, error) {
+	for i := 0; i < maxCodeAttempts; i++ {
+		code, err := generateCode()
+		if err != nil {
+			return "", err
+		}
+
+		room := roomModel.Room{
+			Code:   &code,
+			Status: types.Ptr(roomModel.StatusOpen),
+		}
+
+		err = roomDao.GetRoom(&room)
+		if dao.IsMissingResult(err) {
+			return code, nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "", &ErrNoCode{}
+}
+
+// membership is handed to the participant which created or joined the room. The lock of the room
+// has to be held.
+func (l *liveRoom) membership(participant *roomModel.Participant, token string) *roomModel.Membership {
+	return &roomModel.Membership{
+		Room:        copyState(l.state),
+		Participant: *participant.ID,
+		Token:       token,
+		JoinURL:     joinURL(l.state.Code),
+	}
+}
+
+// close closes the room for good. Its connections are dropped and it can't be joined anymore. The
+// lock of the room has to be held.
+func (l *liveRoom) close() error {
+	now := time.Now().UTC()
+
+	selectors := map[string]interface{}{
+		"ID": l.state.Room,
+	}
+
+	changes := roomModel.Room{
+		Status:   types.Ptr(roomModel.StatusClosed),
+		ClosedAt: &now,
+	}
+
+	tx := cdb.NewTx()
+
+	err := roomDao.UpdateRoom(tx, &changes, selectors)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	err = l.commit(tx, newEvent(roomModel.EventClosed, nil, nil))
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	l.shutdown()
+	removeLiveRoom(l.state.Code)
+
+	return nil
+}
+
+// PostRoom opens a room which the caller hosts. The caller takes part in the room as its first
+// participant and gets the code and link for the others to join.
+func PostRoom(rw server.ResponseWriter, r *server.Request) {
+	// Validate room body
+	body, err := validateRoomBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	acc := r.Account()
+
+	// A room can be linked to a party session of the caller
+	if body.Session != nil {
+		session := partyModel.Session{
+			ID:   body.Session,
+			Host: acc.ID,
+		}
+
+		err = partyDao.GetSession(&session)
+		if err != nil {
+			log.Error(err.Error())
+
+			if dao.IsMissingResult(err) {
+				rw.JSON(http.StatusNotFound, nil)
+				return
+			}
+
+			rw.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	code, err := newCode()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	// Add room
+	room := roomModel.Room{
+		ID:          types.Ptr(uuid.UUIDv4()),
+		Code:        &code,
+		HostAccount: acc.ID,
+		Session:     body.Session,
+		Status:      types.Ptr(roomModel.StatusOpen),
+		CreatedAt:   types.Ptr(time.Now().UTC()),
+	}
+
+	participant, token, err := newParticipant(*room.ID, body.Name)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	tx := cdb.NewTx()
+
+	err = roomDao.InsertRoom(tx, &room)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = roomDao.InsertParticipant(tx, participant)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	live := newLiveRoom(newState(&room), saveEvents)
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	err = live.commit(tx,
+		newEvent(roomModel.EventCreated, nil, nil),
+		newEvent(roomModel.EventJoined, participant.ID, map[string]interface{}{"name": body.Name}),
+		newEvent(roomModel.EventHost, participant.ID, nil),
+	)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	live.schedulePresence(*participant.ID)
+	addLiveRoom(live)
+
+	rw.JSON(http.StatusOK, live.membership(participant, token))
+}
+
+// JoinRoom adds a participant to the open room with the code. No account is needed, the token which
+// is handed out authenticates the participant.
+func JoinRoom(rw server.ResponseWriter, r *server.Request) {
+	// Validate join body
+	body, err := validateJoinBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	live, err := getLiveRoom(body.Code)
+	if err != nil {
+		log.Error(err.Error())
+
+		if dao.IsMissingResult(err) {
+			rw.JSON(http.StatusNotFound, nil)
+			return
+		}
+
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	if live.state.Status != roomModel.StatusOpen {
+		rw.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	if activeParticipants(live.state) >= maxParticipants || nameTaken(live.state, body.Name) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	// Add participant
+	participant, token, err := newParticipant(live.state.Room, body.Name)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	tx := cdb.NewTx()
+
+	err = roomDao.InsertParticipant(tx, participant)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	err = live.commit(tx,
+		newEvent(roomModel.EventJoined, participant.ID, map[string]interface{}{"name": body.Name}))
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	live.schedulePresence(*participant.ID)
+
+	// The room may have been without a host
+	err = live.ensureHost()
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	rw.JSON(http.StatusOK, live.membership(participant, token))
+}
+
+// GetRoom fetches the open room with the code, so it can be shown before joining.
+func GetRoom(rw server.ResponseWriter, r *server.Request) {
+	live, status, err := getRoom(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	if live.state.Status != roomModel.StatusOpen {
+		rw.JSON(http.StatusNotFound, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, copyState(live.state))
+}
+
+// PostState replaces the shared game state of the room. Only the host changes the state, and only
+// from the version it last saw.
+func PostState(rw server.ResponseWriter, r *server.Request) {
+	live, id, status, err := getParticipant(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate state body
+	body, err := validateStateBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	status, err = live.checkHost(id)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	if body.Version != live.state.Version {
+		rw.JSON(http.StatusConflict, copyState(live.state))
+		return
+	}
+
+	err = live.commit(cdb.NewTx(),
+		newEvent(roomModel.EventState, &id, map[string]interface{}{"state": body.State}))
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, copyState(live.state))
+}
+
+// LeaveRoom takes the participant out of the room. A host which leaves hands the room over, the
+// room is closed when the last participant leaves.
+func LeaveRoom(rw server.ResponseWriter, r *server.Request) {
+	live, id, status, err := getParticipant(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	status, err = live.checkParticipant(id)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	err = live.commit(cdb.NewTx(), newEvent(roomModel.EventLeft, &id, nil))
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	live.stopPresence(id)
+
+	if activeParticipants(live.state) == 0 {
+		err = live.close()
+	} else {
+		err = live.ensureHost()
+	}
+
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
+
+// HandOverHost makes another participant which is online the host of the room. Only the host hands
+// the room over.
+func HandOverHost(rw server.ResponseWriter, r *server.Request) {
+	live, id, status, err := getParticipant(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	// Validate host body
+	body, err := validateHostBody(r.R.Body)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusBadRequest, nil)
+		return
+	}
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	status, err = live.checkHost(id)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	if !isPresent(findParticipant(live.state, body.Participant)) {
+		rw.JSON(http.StatusConflict, nil)
+		return
+	}
+
+	err = live.commit(cdb.NewTx(), newEvent(roomModel.EventHost, &body.Participant, nil))
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, copyState(live.state))
+}
+
+// CloseRoom closes the room for everyone. Only the host closes the room.
+func CloseRoom(rw server.ResponseWriter, r *server.Request) {
+	live, id, status, err := getParticipant(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	status, err = live.checkHost(id)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	err = live.close()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	rw.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,182 @@
+package room
+
+import (
+	"time"
+
+	roomModel "github.com/marvindeckmyn/drankspelletjes-server/model/room"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// newState creates the state of a room before any of its events were replayed.
+func newState(room *roomModel.Room) *roomModel.State {
+	return &roomModel.State{
+		Room:         *room.ID,
+		Code:         *room.Code,
+		Session:      room.Session,
+		Status:       roomModel.StatusOpen,
+		Participants: []*roomModel.ParticipantState{},
+		State:        map[string]interface{}{},
+	}
+}
+
+// replay rebuilds the state of a room from its events.
+func replay(room *roomModel.Room, events []*roomModel.Event) *roomModel.State {
+	state := newState(room)
+
+	for _, event := range events {
+		applyEvent(state, event)
+	}
+
+	return state
+}
+
+// findParticipant returns the participant of the room with the given ID, nil when there is none.
+func findParticipant(state *roomModel.State, id uuid.UUID) *roomModel.ParticipantState {
+	for _, participant := range state.Participants {
+		if participant.ID == id {
+			return participant
+		}
+	}
+
+	return nil
+}
+
+// applyEvent changes the state of the room with the event. Events which don't fit the state are
+// ignored, so a replay never fails.
+func applyEvent(state *roomModel.State, event *roomModel.Event) {
+	state.Seq = *event.Seq
+
+	data := map[string]interface{}{}
+	if event.Data != nil {
+		data = *event.Data
+	}
+
+	var participant *roomModel.ParticipantState
+	if event.Participant != nil {
+		participant = findParticipant(state, *event.Participant)
+	}
+
+	switch *event.Kind {
+	case roomModel.EventCreated:
+		state.Status = roomModel.StatusOpen
+
+	case roomModel.EventJoined:
+		if event.Participant == nil || participant != nil {
+			return
+		}
+
+		name, _ := data["name"].(string)
+
+		joinedAt := time.Time{}
+		if event.CreatedAt != nil {
+			joinedAt = *event.CreatedAt
+		}
+
+		state.Participants = append(state.Participants, &roomModel.ParticipantState{
+			ID:       *event.Participant,
+			Name:     name,
+			Online:   true,
+			JoinedAt: joinedAt,
+		})
+
+	case roomModel.EventLeft:
+		if participant == nil {
+			return
+		}
+
+		participant.Left = true
+		participant.Online = false
+
+	case roomModel.EventOnline:
+		if participant == nil || participant.Left {
+			return
+		}
+
+		participant.Online = true
+
+	case roomModel.EventOffline:
+		if participant == nil {
+			return
+		}
+
+		participant.Online = false
+
+	case roomModel.EventHost:
+		if event.Participant != nil && participant == nil {
+			return
+		}
+
+		state.Host = event.Participant
+
+	case roomModel.EventState:
+		game, ok := data["state"].(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		state.State = game
+		state.Version++
+
+	case roomModel.EventClosed:
+		state.Status = roomModel.StatusClosed
+
+		for _, participant := range state.Participants {
+			participant.Online = false
+		}
+	}
+}
+
+// isPresent checks whether the participant is online and didn't leave the room.
+func isPresent(participant *roomModel.ParticipantState) bool {
+	return participant != nil && participant.Online && !participant.Left
+}
+
+// nextHost picks the participant which should host the room: the participant which joined first
+// of the ones which are online. Nil is returned when nobody is online.
+func nextHost(state *roomModel.State) *uuid.UUID {
+	for _, participant := range state.Participants {
+		if isPresent(participant) {
+			id := participant.ID
+			return &id
+		}
+	}
+
+	return nil
+}
+
+// needsHost checks whether the host of the room has to be replaced because there is none or the
+// host isn't online anymore.
+func needsHost(state *roomModel.State) bool {
+	if state.Host == nil {
+		return true
+	}
+
+	return !isPresent(findParticipant(state, *state.Host))
+}
+
+// activeParticipants counts the participants which didn't leave the room.
+func activeParticipants(state *roomModel.State) int {
+	count := 0
+
+	for _, participant := range state.Participants {
+		if !participant.Left {
+			count++
+		}
+	}
+
+	return count
+}
+
+// copyState copies the state so it can be sent while the room keeps changing. The shared game state
+// is replaced as a whole on every change, so it doesn't need to be copied.
+func copyState(state *roomModel.State) *roomModel.State {
+	copied := *state
+	copied.Participants = make([]*roomModel.ParticipantState, len(state.Participants))
+
+	for i, participant := range state.Participants {
+		p := *participant
+		copied.Participants[i] = &p
+	}
+
+	return &copied
+}
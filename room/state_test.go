@@ -0,0 +1,143 @@
+package room
+
+import (
+	"testing"
+	"time"
+
+	roomModel "github.com/marvindeckmyn/drankspelletjes-server/model/room"
+	"github.com/marvindeckmyn/drankspelletjes-server/types"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// testRoom creates a room with the given code.
+func testRoom(code string) *roomModel.Room {
+	return &roomModel.Room{
+		ID:   types.Ptr(uuid.UUIDv4()),
+		Code: types.Ptr(code),
+	}
+}
+
+// numbered numbers the events in order, as they are stored.
+func numbered(events ...*roomModel.Event) []*roomModel.Event {
+	for i, event := range events {
+		event.Seq = types.Ptr(int64(i + 1))
+	}
+
+	return events
+}
+
+func TestReplay(t *testing.T) {
+	anna, bert := uuid.UUIDv4(), uuid.UUIDv4()
+
+	events := numbered(
+		newEvent(roomModel.EventCreated, nil, nil),
+		newEvent(roomModel.EventJoined, &anna, map[string]interface{}{"name": "Anna"}),
+		newEvent(roomModel.EventHost, &anna, nil),
+		newEvent(roomModel.EventJoined, &bert, map[string]interface{}{"name": "Bert"}),
+		newEvent(roomModel.EventState, &anna, map[string]interface{}{
+			"state": map[string]interface{}{"card": "7"},
+		}),
+		newEvent(roomModel.EventOffline, &anna, nil),
+		newEvent(roomModel.EventHost, &bert, nil),
+		newEvent(roomModel.EventLeft, &anna, nil),
+		newEvent(roomModel.EventOnline, &anna, nil),
+	)
+
+	state := replay(testRoom("ABC234"), events)
+
+	if state.Seq != 9 || state.Version != 1 || state.State["card"] != "7" {
+		t.Fatalf("unexpected state %+v", state)
+	}
+
+	if state.Host == nil || *state.Host != bert {
+		t.Fatal("expected the host to have moved to Bert")
+	}
+
+	if len(state.Participants) != 2 || state.Participants[1].Name != "Bert" {
+		t.Fatalf("unexpected participants %+v", state.Participants)
+	}
+
+	// A participant which left can't come back online
+	if !state.Participants[0].Left || state.Participants[0].Online {
+		t.Fatal("expected Anna to have left")
+	}
+
+	closed := replay(testRoom("ABC234"),
+		numbered(append(events, newEvent(roomModel.EventClosed, nil, nil))...))
+
+	if closed.Status != roomModel.StatusClosed || closed.Participants[1].Online {
+		t.Fatal("expected a closed room without online participants")
+	}
+}
+
+func TestNextHost(t *testing.T) {
+	joined := time.Now()
+
+	state := &roomModel.State{
+		Participants: []*roomModel.ParticipantState{
+			{ID: uuid.UUIDv4(), Name: "Anna", Left: true, JoinedAt: joined},
+			{ID: uuid.UUIDv4(), Name: "Bert", JoinedAt: joined},
+			{ID: uuid.UUIDv4(), Name: "Cas", Online: true, JoinedAt: joined},
+			{ID: uuid.UUIDv4(), Name: "Dirk", Online: true, JoinedAt: joined},
+		},
+	}
+
+	next := nextHost(state)
+	if next == nil || *next != state.Participants[2].ID {
+		t.Fatal("expected the first participant which is online to host")
+	}
+
+	state.Host = next
+	if needsHost(state) {
+		t.Fatal("expected the host to stay")
+	}
+
+	state.Participants[2].Online = false
+	if !needsHost(state) {
+		t.Fatal("expected a host which went offline to be replaced")
+	}
+
+	state.Participants[3].Online = false
+	if nextHost(state) != nil {
+		t.Fatal("expected nobody to host when nobody is online")
+	}
+}
+
+func TestGenerateCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := generateCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !isCode(code) {
+			t.Fatalf("unexpected code %s", code)
+		}
+	}
+
+	if !isCode(" abc 234") || normalizeCode(" abc 234") != "ABC234" {
+		t.Fatal("expected typed codes to be normalized")
+	}
+
+	if isCode("ABC10O") || isCode("ABC23") {
+		t.Fatal("expected codes with ambiguous characters or of the wrong length to be rejected")
+	}
+}
+
+func TestSetJoinURL(t *testing.T) {
+	original := joinBaseURL
+
+	t.Cleanup(func() {
+		joinBaseURL = original
+	})
+
+	SetJoinURL("")
+	if joinURL("ABC234") != original+"/ABC234" {
+		t.Fatalf("expected the default link to be kept, got %s", joinURL("ABC234"))
+	}
+
+	SetJoinURL("https://drankspelletjes.be/join/")
+	if joinURL("ABC234") != "https://drankspelletjes.be/join/ABC234" {
+		t.Fatalf("unexpected link %s", joinURL("ABC234"))
+	}
+}
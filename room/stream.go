@@ -0,0 +1,165 @@
+package room
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	roomDao "github.com/marvindeckmyn/drankspelletjes-server/dao/room"
+	"github.com/marvindeckmyn/drankspelletjes-server/log"
+	roomModel "github.com/marvindeckmyn/drankspelletjes-server/model/room"
+	"github.com/marvindeckmyn/drankspelletjes-server/server"
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// keepAlive is how often an idle stream sends a comment, so proxies don't close it.
+var keepAlive = 25 * time.Second
+
+// eventSnapshot is the kind of event which carries the whole state of the room.
+const eventSnapshot = "snapshot"
+
+// catchUp returns the events which a reconnecting stream missed, up to the state it continues
+// from. Nil is returned when a snapshot has to be sent instead.
+func catchUp(r *server.Request, state *roomModel.State) []*roomModel.Event {
+	last, err := strconv.ParseInt(r.R.Header.Get("Last-Event-ID"), 10, 64)
+	if err != nil || last < 0 || last > state.Seq {
+		return nil
+	}
+
+	room := roomModel.Room{
+		ID: &state.Room,
+	}
+
+	events, err := roomDao.GetEvents(&room, last)
+	if err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+
+	missed := []*roomModel.Event{}
+
+	for _, event := range events {
+		if *event.Seq <= state.Seq {
+			missed = append(missed, event)
+		}
+	}
+
+	// Events may be missing when the stream is ahead of the room
+	if int64(len(missed)) != state.Seq-last {
+		return nil
+	}
+
+	return missed
+}
+
+// StreamRoom pushes the events of the room to the participant as server-sent events. The stream
+// starts with a snapshot of the room, a stream which reconnects with the Last-Event-ID header gets
+// the events it missed instead. The participant is online as long as it has a stream.
+func StreamRoom(rw server.ResponseWriter, r *server.Request) {
+	live, id, status, err := getParticipant(r)
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	live.mu.Lock()
+
+	status, err = live.checkParticipant(id)
+	if err != nil {
+		live.mu.Unlock()
+		log.Error(err.Error())
+		rw.JSON(status, nil)
+		return
+	}
+
+	ch := live.subscribe()
+
+	err = live.connect(id)
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	state := copyState(live.state)
+
+	live.mu.Unlock()
+
+	defer func() {
+		live.mu.Lock()
+		defer live.mu.Unlock()
+
+		live.unsubscribe(ch)
+		live.disconnect(id)
+	}()
+
+	stream, err := rw.Stream()
+	if err != nil {
+		log.Error(err.Error())
+		rw.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+
+	sent := state.Seq
+
+	missed := catchUp(r, state)
+	if missed == nil {
+		err = stream.Send(strconv.FormatInt(state.Seq, 10), eventSnapshot, state)
+	}
+
+	for _, event := range missed {
+		if err != nil {
+			break
+		}
+
+		err = stream.Send(strconv.FormatInt(*event.Seq, 10), *event.Kind, event)
+	}
+
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-ch:
+			// The room was closed or the stream fell behind
+			if !ok {
+				return
+			}
+
+			if *event.Seq <= sent {
+				continue
+			}
+
+			err = stream.Send(strconv.FormatInt(*event.Seq, 10), *event.Kind, event)
+			if err != nil {
+				log.Error(err.Error())
+				return
+			}
+
+			sent = *event.Seq
+
+			if isOwnLeave(event, id) {
+				return
+			}
+
+		case <-ticker.C:
+			err = stream.Comment("ping")
+			if err != nil {
+				log.Error(err.Error())
+				return
+			}
+
+		case <-r.R.Context().Done():
+			return
+		}
+	}
+}
+
+// isOwnLeave checks whether the event is the participant leaving the room, which ends its stream.
+func isOwnLeave(event *roomModel.Event, id uuid.UUID) bool {
+	return *event.Kind == roomModel.EventLeft && event.Participant != nil && *event.Participant == id
+}
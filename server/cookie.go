@@ -0,0 +1,24 @@
+package server
+
+import "net/http"
+
+// CookieDomain is the domain on which the cookies of the server are set.
+var CookieDomain = ".drankspelletjes.local"
+
+// SecureCookies marks the cookies of the server as only to be sent over HTTPS.
+var SecureCookies = true
+
+// SetCookie writes a cookie with the default attributes of the server to the response. Cookies are
+// HttpOnly unless readable is set, so scripts can't steal them.
+func (rw *ResponseWriter) SetCookie(name string, value string, maxAge int, readable bool) {
+	http.SetCookie(rw.W, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   CookieDomain,
+		MaxAge:   maxAge,
+		HttpOnly: !readable,
+		Secure:   SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// CSRFCookie is the name of the cookie which holds the CSRF token.
+const CSRFCookie = "drnkngg-csrf"
+
+// CSRFHeader is the header in which clients have to repeat the CSRF token.
+const CSRFHeader = "X-CSRF-Token"
+
+// csrfMaxAge is how long the CSRF cookie is kept by the browser.
+const csrfMaxAge = 3600 * 24 * 7
+
+// isSafeMethod returns true for methods which don't change anything on the server.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
+
+// isBearerRequest returns true when the request carries its credentials in the Authorization
+// header. Browsers never add this header on their own, so these requests can't be forged.
+func isBearerRequest(r *Request) bool {
+	header := r.R.Header.Get("Authorization")
+	return len(header) >= 7 && strings.EqualFold(header[:7], "Bearer ")
+}
+
+// newCSRFToken creates a random CSRF token.
+func newCSRFToken() (string, error) {
+	data := make([]byte, 32)
+
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// CSRF is middleware which protects cookie-authenticated requests against cross-site request
+// forgery with a double-submit token. Safe requests receive the token in a cookie which scripts of
+// our own origin can read, unsafe requests have to repeat it in the X-CSRF-Token header.
+func CSRF(rw ResponseWriter, r *Request) bool {
+	cookie, err := r.Cookie(CSRFCookie)
+
+	if isSafeMethod(r.R.Method) {
+		if err != nil || *cookie == "" {
+			token, err := newCSRFToken()
+			if err != nil {
+				rw.JSON(http.StatusInternalServerError, nil)
+				return false
+			}
+
+			rw.SetCookie(CSRFCookie, token, csrfMaxAge, true)
+		}
+
+		return true
+	}
+
+	if isBearerRequest(r) {
+		return true
+	}
+
+	header := r.R.Header.Get(CSRFHeader)
+	if err != nil || *cookie == "" || subtle.ConstantTimeCompare([]byte(*cookie), []byte(header)) != 1 {
+		rw.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "invalid_csrf_token",
+		})
+		return false
+	}
+
+	return true
+}
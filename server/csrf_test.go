@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newCSRFRouter returns a router with the CSRF middleware and a GET and POST route.
+func newCSRFRouter(t *testing.T) http.Handler {
+	s := New()
+	s.AddMiddleware(CSRF)
+
+	ok := func(rw ResponseWriter, r *Request) {
+		rw.JSON(http.StatusOK, nil)
+	}
+
+	s.Get("/", ok)
+	s.Post("/", ok)
+
+	router, err := s.GetRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return router
+}
+
+func TestCSRF(t *testing.T) {
+	router := newCSRFRouter(t)
+
+	// A safe request receives the token.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var token *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == CSRFCookie {
+			token = cookie
+		}
+	}
+
+	if rec.Code != http.StatusOK || token == nil || token.Value == "" {
+		t.Fatalf("expected a CSRF cookie, got status %d", rec.Code)
+	}
+
+	if token.HttpOnly {
+		t.Fatal("expected the CSRF cookie to be readable by scripts")
+	}
+
+	cases := map[string]struct {
+		header string
+		bearer bool
+		status int
+	}{
+		"missing header": {"", false, http.StatusForbidden},
+		"wrong header":   {"forged", false, http.StatusForbidden},
+		"valid header":   {token.Value, false, http.StatusOK},
+		"bearer":         {"", true, http.StatusOK},
+	}
+
+	for name, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: token.Value})
+
+		if c.header != "" {
+			req.Header.Set(CSRFHeader, c.header)
+		}
+
+		if c.bearer {
+			req.Header.Set("Authorization", "Bearer drnk_test")
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != c.status {
+			t.Fatalf("%s: expected status %d, got %d", name, c.status, rec.Code)
+		}
+	}
+}
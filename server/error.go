@@ -44,3 +44,19 @@ type ErrMarshaling struct{}
 func (e *ErrMarshaling) Error() string {
 	return "Failed to marshal JSON"
 }
+
+// ErrStreaming is thrown when the response can't be streamed.
+type ErrStreaming struct{}
+
+func (e *ErrStreaming) Error() string {
+	return "the response can't be streamed"
+}
+
+// ErrInvalidProxy is thrown when a trusted proxy isn't an IP address or CIDR range.
+type ErrInvalidProxy struct {
+	Proxy string
+}
+
+func (e *ErrInvalidProxy) Error() string {
+	return "invalid trusted proxy '" + e.Proxy + "'"
+}
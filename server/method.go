@@ -7,4 +7,5 @@ const (
 	GET    Method = "GET"
 	PUT    Method = "PUT"
 	DELETE Method = "DELETE"
+	PATCH  Method = "PATCH"
 )
@@ -1,12 +1,17 @@
 package server
 
 import (
+	"net"
 	"net/http"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	accountModel "github.com/marvindeckmyn/drankspelletjes-server/model/account"
 )
 
+// accountParam is the middleware parameter under which the authenticated account is cached.
+const accountParam = "account"
+
 // Request represents a HTTP request.
 type Request struct {
 	// R represents the default http Request.
@@ -85,3 +90,118 @@ func (r *Request) Cookie(name string) (*string, error) {
 
 	return nil, &ErrCookieNotFound{name}
 }
+
+// SetAccount caches the authenticated account on the request so handlers don't have to load it
+// again.
+func (r *Request) SetAccount(acc *accountModel.Account) {
+	r.MiddlewareParams[accountParam] = acc
+}
+
+// Account returns the account which was loaded by the auth middleware. Nil is returned when the
+// request was made anonymously.
+func (r *Request) Account() *accountModel.Account {
+	if r == nil {
+		return nil
+	}
+
+	acc, ok := r.MiddlewareParams[accountParam].(*accountModel.Account)
+	if !ok {
+		return nil
+	}
+
+	return acc
+}
+
+// trustedProxies are the networks of the proxies in front of the server. Only they can pass on the
+// address of the client in the X-Forwarded-For header.
+var trustedProxies = []*net.IPNet{}
+
+// SetTrustedProxies configures the proxies in front of the server as IP addresses or CIDR ranges.
+// Without trusted proxies the X-Forwarded-For header is ignored.
+func SetTrustedProxies(proxies []string) error {
+	networks := []*net.IPNet{}
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return &ErrInvalidProxy{Proxy: proxy}
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return &ErrInvalidProxy{Proxy: proxy}
+		}
+
+		networks = append(networks, network)
+	}
+
+	trustedProxies = networks
+	return nil
+}
+
+// isTrustedProxy checks whether the address belongs to a trusted proxy.
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the IP address of the client. Behind trusted proxies the X-Forwarded-For header
+// is read from the right, the first address which isn't a trusted proxy is the client. Addresses
+// further to the left could have been made up by the client.
+func (r *Request) ClientIP() string {
+	if r == nil {
+		return ""
+	}
+
+	remote, _, err := net.SplitHostPort(r.R.RemoteAddr)
+	if err != nil {
+		remote = r.R.RemoteAddr
+	}
+
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+
+	client := remote
+	forwarded := strings.Split(r.R.Header.Get("X-Forwarded-For"), ",")
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr == "" {
+			continue
+		}
+
+		client = addr
+
+		if !isTrustedProxy(addr) {
+			break
+		}
+	}
+
+	return client
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"github.com/marvindeckmyn/drankspelletjes-server/uuid"
+)
+
+// RequestIDHeader is the header which carries the ID of a request.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDParam is the middleware parameter under which the ID of the request is stored.
+const requestIDParam = "request_id"
+
+// maxRequestIDLength is the longest request ID which is taken over from a client or proxy.
+const maxRequestIDLength = 64
+
+// validRequestID checks that a request ID from a client only contains safe characters, so it can't
+// be used to forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlnum && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RequestID is middleware which gives every request an ID. The ID from a proxy is kept when it
+// sent one. The ID is repeated in the response, so a request can be found back in the logs.
+func RequestID(rw ResponseWriter, r *Request) bool {
+	id := r.R.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = uuid.UUIDv4().String()
+	}
+
+	r.MiddlewareParams[requestIDParam] = id
+	rw.W.Header().Set(RequestIDHeader, id)
+
+	return true
+}
+
+// RequestID returns the ID which was given to the request by the RequestID middleware.
+func (r *Request) RequestID() string {
+	id, _ := r.MiddlewareParams[requestIDParam].(string)
+	return id
+}
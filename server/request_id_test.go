@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	s := New()
+	s.AddMiddleware(RequestID)
+
+	seen := ""
+	s.Get("/", func(rw ResponseWriter, r *Request) {
+		seen = r.RequestID()
+		rw.JSON(http.StatusOK, nil)
+	})
+
+	router, err := s.GetRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		"":                       false,
+		"abc-123_x.y":            true,
+		"forged\nlog line":       false,
+		string(make([]byte, 65)): false,
+	}
+
+	for header, kept := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, header)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if seen == "" || rec.Header().Get(RequestIDHeader) != seen {
+			t.Fatalf("expected the request ID %q to be repeated in the response", seen)
+		}
+
+		if kept != (seen == header) {
+			t.Fatalf("expected %q to be kept: %t, got %q", header, kept, seen)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newForwardedRequest creates a request from the remote address with the X-Forwarded-For header.
+func newForwardedRequest(remote string, forwarded string) *Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remote
+	req.Header.Set("X-Forwarded-For", forwarded)
+
+	return &Request{R: req}
+}
+
+func TestClientIP(t *testing.T) {
+	t.Cleanup(func() {
+		trustedProxies = nil
+	})
+
+	// Without trusted proxies the header is ignored.
+	r := newForwardedRequest("203.0.113.7:4000", "198.51.100.1")
+	if ip := r.ClientIP(); ip != "203.0.113.7" {
+		t.Fatalf("expected the remote address, got %s", ip)
+	}
+
+	err := SetTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Addresses the client made up on the left are skipped.
+	r = newForwardedRequest("10.0.0.2:4000", "198.51.100.1, 203.0.113.9, 192.0.2.1")
+	if ip := r.ClientIP(); ip != "203.0.113.9" {
+		t.Fatalf("expected the first address which isn't a proxy, got %s", ip)
+	}
+
+	// Untrusted remotes can't forward.
+	r = newForwardedRequest("203.0.113.7:4000", "198.51.100.1")
+	if ip := r.ClientIP(); ip != "203.0.113.7" {
+		t.Fatalf("expected the remote address, got %s", ip)
+	}
+
+	if SetTrustedProxies([]string{"proxy.local"}) == nil {
+		t.Fatal("expected an invalid proxy to be rejected")
+	}
+}
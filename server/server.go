@@ -102,6 +102,11 @@ func (s *Server) Put(url string, callback Handler, mWares ...Middleware) {
 	s.rtr.Put(url, s.httpRouterHandle(callback, mWares))
 }
 
+// Patch routes the http PATCH calls for the DPT router
+func (s *Server) Patch(url string, callback Handler, mWares ...Middleware) {
+	s.rtr.Patch(url, s.httpRouterHandle(callback, mWares))
+}
+
 // Post routes the http POST calls for the DPT router
 func (s *Server) Post(url string, callback Handler, mWares ...Middleware) {
 	s.rtr.Post(url, s.httpRouterHandle(callback, mWares))
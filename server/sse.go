@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// EventStream sends server-sent events over a response which is kept open.
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// Stream turns the response in a stream of server-sent events. Nothing else can be written to the
+// response afterwards.
+func (rw *ResponseWriter) Stream() (*EventStream, error) {
+	flusher, ok := rw.W.(http.Flusher)
+	if !ok {
+		return nil, &ErrStreaming{}
+	}
+
+	rw.W.Header().Set("Content-Type", "text/event-stream")
+	rw.W.Header().Set("Cache-Control", "no-cache")
+	rw.W.Header().Set("Connection", "keep-alive")
+
+	// Proxies shouldn't hold back the events
+	rw.W.Header().Set("X-Accel-Buffering", "no")
+
+	rw.W.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &EventStream{rw.W, flusher}, nil
+}
+
+// Send sends an event with the content as JSON data. Clients pass the ID of the last event they
+// received in the Last-Event-ID header when they reconnect.
+func (s *EventStream) Send(id string, event string, content interface{}) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return &ErrMarshaling{}
+	}
+
+	message := ""
+	if id != "" {
+		message += fmt.Sprintf("id: %s\n", id)
+	}
+
+	message += fmt.Sprintf("event: %s\ndata: %s\n\n", event, data)
+
+	_, err = s.w.Write([]byte(message))
+	if err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
+
+// Comment sends a comment, which clients ignore. It keeps idle connections from being closed.
+func (s *EventStream) Comment(text string) error {
+	_, err := s.w.Write([]byte(": " + strings.ReplaceAll(text, "\n", " ") + "\n\n"))
+	if err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventStream(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := newRespWriter(rec)
+
+	stream, err := rw.Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stream.Send("7", "joined", map[string]string{"name": "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stream.Comment("ping\nforged: field")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "id: 7\nevent: joined\ndata: {\"name\":\"Anna\"}\n\n: ping forged: field\n\n"
+
+	if rec.Body.String() != expected || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected stream %q", rec.Body.String())
+	}
+}
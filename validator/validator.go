@@ -219,6 +219,25 @@ func IsUUIDV4(item interface{}) bool {
 	return false
 }
 
+func IsStringSlice(item interface{}) bool {
+	if item == nil {
+		return false
+	}
+
+	values, ok := item.([]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, value := range values {
+		if _, ok := value.(string); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
 func IsMapStrInterface(item interface{}) bool {
 	if item == nil {
 		return false
@@ -325,3 +344,67 @@ func (instance V) ValidateAndMarshalURL(r *server.Request, val interface{}) erro
 
 	return &ErrInvalidContent{Cause: strings.Join(errs, ", ")}
 }
+
+func IsOptUUIDV4(item interface{}) bool {
+	return item == nil || IsUUIDV4(item)
+}
+
+func IsOptBool(item interface{}) bool {
+	return item == nil || IsBool(item)
+}
+
+func IsOptMapStrStr(item interface{}) bool {
+	return item == nil || IsMapStrStr(item)
+}
+
+func IsOptInt(item interface{}) bool {
+	return item == nil || IsInt(item)
+}
+
+func IsOptTimestamp(item interface{}) bool {
+	if item == nil {
+		return true
+	}
+
+	str, ok := item.(string)
+	if !ok {
+		return false
+	}
+
+	_, err := time.Parse(time.RFC3339, str)
+	return err == nil
+}
+
+// ValidateAndMarshalQuery validates the query parameters of the request and marshals them in the
+// given value. Missing parameters are passed as nil to the validation functions and left out, so
+// optional parameters can be pointers. Numbers need the ",string" option in their JSON tag.
+func (instance V) ValidateAndMarshalQuery(r *server.Request, val interface{}) error {
+	errs := []string{}
+	queryParams := map[string]interface{}{}
+
+	for key, validationFunc := range instance {
+		var param interface{}
+		if values := r.QueryParams[key]; len(values) > 0 && values[0] != "" {
+			param = values[0]
+			queryParams[key] = param
+		}
+
+		valid := validationFunc(param)
+		if !valid {
+			errs = append(errs, key)
+		}
+	}
+
+	if len(errs) == 0 {
+		encoded, _ := json.Marshal(queryParams)
+		err := json.Unmarshal(encoded, &val)
+
+		if err != nil {
+			return &ErrInvalidJSON{Cause: err}
+		}
+
+		return nil
+	}
+
+	return &ErrInvalidContent{Cause: strings.Join(errs, ", ")}
+}